@@ -0,0 +1,195 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+const (
+	// DiagnosticsEnv is the environment variable enabling the diagnostics written by [Cleanup]
+	// when a test fails, parsed with [strconv.ParseBool]. Default: enabled.
+	DiagnosticsEnv = "DOCKER_SDK_DIAGNOSTICS"
+
+	// DiagnosticsDirEnv is the environment variable setting the artifacts directory the
+	// diagnostics are written to, in a subdirectory named after the test.
+	// Default: docker-sdk-diagnostics in the temporary directory.
+	DiagnosticsDirEnv = "DOCKER_SDK_DIAGNOSTICS_DIR"
+
+	// diagnosticsTimeout the maximum duration of writing the diagnostics of a container.
+	diagnosticsTimeout = 30 * time.Second
+)
+
+// DiagnosticsWriter is a container writing a diagnostics bundle, see [Container.WriteDiagnostics].
+type DiagnosticsWriter interface {
+	ShortID() string
+	WriteDiagnostics(ctx context.Context, dir string) error
+}
+
+// WriteDiagnostics writes a diagnostics bundle of the container into dir, creating it if needed:
+//   - logs.txt: the full logs of the container.
+//   - inspect.json: the inspect result of the container.
+//   - networks.json: the networks the container is attached to, with its aliases and addresses.
+//   - processes.txt: the processes running in the container, if it's running.
+//   - wait-errors.txt: the errors of its wait strategy, if any.
+//
+// The bundles of its sidecars, see [WithSidecars], are written into "sidecar-<short ID>"
+// subdirectories. A file which can't be written is reported in the returned error,
+// without skipping the other ones.
+func (c *Container) WriteDiagnostics(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create diagnostics dir: %w", err)
+	}
+
+	var errs []error
+
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("inspect: %w", err))
+	} else {
+		errs = append(errs, writeDiagnosticsJSON(filepath.Join(dir, "inspect.json"), inspect.Container))
+
+		if inspect.Container.NetworkSettings != nil {
+			errs = append(errs, writeDiagnosticsJSON(filepath.Join(dir, "networks.json"), inspect.Container.NetworkSettings.Networks))
+		}
+
+		if inspect.Container.State != nil && inspect.Container.State.Running {
+			errs = append(errs, c.writeProcesses(ctx, filepath.Join(dir, "processes.txt")))
+		}
+	}
+
+	errs = append(errs, c.writeLogs(ctx, filepath.Join(dir, "logs.txt")))
+
+	if waitErrs := c.WaitErrors(); len(waitErrs) > 0 {
+		content := strings.Join(waitErrs, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "wait-errors.txt"), []byte(content), 0o644); err != nil {
+			errs = append(errs, fmt.Errorf("write wait errors: %w", err))
+		}
+	}
+
+	for _, sidecar := range c.Sidecars() {
+		if err := sidecar.WriteDiagnostics(ctx, filepath.Join(dir, "sidecar-"+sidecar.ShortID())); err != nil {
+			errs = append(errs, fmt.Errorf("sidecar %s: %w", sidecar.ShortID(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WaitErrors returns the errors of the wait strategy of the container, one per failed start,
+// prefixed with the time they occurred.
+func (c *Container) WaitErrors() []string {
+	c.waitErrsMtx.Lock()
+	defer c.waitErrsMtx.Unlock()
+
+	return append([]string(nil), c.waitErrs...)
+}
+
+// recordWaitError records an error of the wait strategy of the container.
+func (c *Container) recordWaitError(err error) {
+	c.waitErrsMtx.Lock()
+	defer c.waitErrsMtx.Unlock()
+
+	c.waitErrs = append(c.waitErrs, time.Now().Format(time.RFC3339)+" "+err.Error())
+}
+
+// writeLogs writes the full logs of the container into the file at path.
+func (c *Container) writeLogs(ctx context.Context, path string) error {
+	logs, err := c.Logs(ctx)
+	if err != nil {
+		return fmt.Errorf("logs: %w", err)
+	}
+	defer logs.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create logs file: %w", err)
+	}
+
+	_, err = io.Copy(f, logs)
+
+	return errors.Join(err, f.Close())
+}
+
+// writeProcesses writes the processes running in the container into the file at path,
+// as a tab-separated table.
+func (c *Container) writeProcesses(ctx context.Context, path string) error {
+	top, err := c.dockerClient.ContainerTop(ctx, c.ID(), client.ContainerTopOptions{})
+	if err != nil {
+		return fmt.Errorf("container top: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(top.Titles, "\t") + "\n")
+	for _, process := range top.Processes {
+		sb.WriteString(strings.Join(process, "\t") + "\n")
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		return fmt.Errorf("write processes: %w", err)
+	}
+
+	return nil
+}
+
+// writeDiagnosticsJSON writes v, indented, into the file at path.
+func writeDiagnosticsJSON(path string, v any) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.WriteFile(path, append(bs, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
+
+// diagnosticsRoot returns the artifacts directory of the diagnostics, as configured
+// by [DiagnosticsEnv] and [DiagnosticsDirEnv], and false if they are disabled.
+func diagnosticsRoot() (string, bool) {
+	if v := os.Getenv(DiagnosticsEnv); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil && !enabled {
+			return "", false
+		}
+	}
+
+	if dir := os.Getenv(DiagnosticsDirEnv); dir != "" {
+		return dir, true
+	}
+
+	return filepath.Join(os.TempDir(), "docker-sdk-diagnostics"), true
+}
+
+// diagnosticsTestDir returns the directory of the diagnostics of a test, relative to the
+// artifacts directory, from its name. Subtests are nested in the directory of their parent.
+func diagnosticsTestDir(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segment = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			default:
+				return '_'
+			}
+		}, segment)
+
+		if segment == "" || segment == "." || segment == ".." {
+			segment = "_"
+		}
+		segments[i] = segment
+	}
+
+	return filepath.Join(segments...)
+}
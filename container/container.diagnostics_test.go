@@ -0,0 +1,64 @@
+package container_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+func TestContainer_WriteDiagnostics(t *testing.T) {
+	t.Run("running", func(t *testing.T) {
+		ctx := context.Background()
+
+		ctr, err := container.Run(ctx, container.WithImage(nginxAlpineImage))
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		require.NoError(t, ctr.WriteDiagnostics(ctx, dir))
+
+		for _, name := range []string{"logs.txt", "inspect.json", "networks.json", "processes.txt"} {
+			require.FileExists(t, filepath.Join(dir, name))
+		}
+		require.NoFileExists(t, filepath.Join(dir, "wait-errors.txt"))
+
+		processes, err := os.ReadFile(filepath.Join(dir, "processes.txt"))
+		require.NoError(t, err)
+		require.Contains(t, string(processes), "nginx")
+
+		networks, err := os.ReadFile(filepath.Join(dir, "networks.json"))
+		require.NoError(t, err)
+		require.Contains(t, string(networks), `"bridge"`)
+	})
+
+	t.Run("wait-error", func(t *testing.T) {
+		ctx := context.Background()
+
+		ctr, err := container.Run(ctx,
+			container.WithImage(alpineLatest),
+			container.WithCmd("sh", "-c", "echo starting; sleep 300"),
+			container.WithWaitStrategy(wait.ForLog("never printed").WithTimeout(2*time.Second)),
+		)
+		container.Cleanup(t, ctr)
+		require.Error(t, err)
+		require.NotNil(t, ctr)
+
+		dir := t.TempDir()
+		require.NoError(t, ctr.WriteDiagnostics(ctx, dir))
+
+		waitErrs, err := os.ReadFile(filepath.Join(dir, "wait-errors.txt"))
+		require.NoError(t, err)
+		require.Contains(t, string(waitErrs), "deadline exceeded")
+
+		logs, err := os.ReadFile(filepath.Join(dir, "logs.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "starting\n", string(logs))
+	})
+}
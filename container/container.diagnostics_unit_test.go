@@ -0,0 +1,177 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container/wait"
+)
+
+// diagnosticsTB is a test which failed, recording its cleanups and logs.
+type diagnosticsTB struct {
+	testing.TB
+
+	name     string
+	cleanups []func()
+	logs     []string
+}
+
+func (tb *diagnosticsTB) Name() string                 { return tb.name }
+func (tb *diagnosticsTB) Failed() bool                 { return true }
+func (tb *diagnosticsTB) Helper()                      {}
+func (tb *diagnosticsTB) Cleanup(f func())             { tb.cleanups = append(tb.cleanups, f) }
+func (tb *diagnosticsTB) Logf(format string, _ ...any) { tb.logs = append(tb.logs, format) }
+
+// diagnosticsContainer is a container recording its diagnostics and termination.
+type diagnosticsContainer struct {
+	err        error
+	dir        string
+	terminated bool
+}
+
+func (c *diagnosticsContainer) ShortID() string {
+	return "1234567890ab"
+}
+
+func (c *diagnosticsContainer) WriteDiagnostics(_ context.Context, dir string) error {
+	c.dir = dir
+	return c.err
+}
+
+func (c *diagnosticsContainer) Terminate(_ context.Context, _ ...TerminateOption) error {
+	if c.dir == "" {
+		return errors.New("terminated before writing the diagnostics")
+	}
+	c.terminated = true
+	return nil
+}
+
+func TestCleanup_diagnostics(t *testing.T) {
+	t.Run("failed-test", func(t *testing.T) {
+		root := t.TempDir()
+		t.Setenv(DiagnosticsEnv, "")
+		t.Setenv(DiagnosticsDirEnv, root)
+
+		tb := &diagnosticsTB{TB: t, name: "TestPostgres/seed data"}
+		ctr := &diagnosticsContainer{}
+		Cleanup(tb, ctr)
+
+		require.Len(t, tb.cleanups, 1)
+		tb.cleanups[0]()
+
+		require.True(t, ctr.terminated)
+		require.Equal(t, filepath.Join(root, "TestPostgres", "seed_data", "1234567890ab"), ctr.dir)
+		require.Equal(t, []string{"diagnostics of container %s written to %s"}, tb.logs)
+	})
+
+	t.Run("write-error", func(t *testing.T) {
+		t.Setenv(DiagnosticsDirEnv, t.TempDir())
+
+		tb := &diagnosticsTB{TB: t, name: "TestPostgres"}
+		ctr := &diagnosticsContainer{err: errors.New("inspect: no such container")}
+		Cleanup(tb, ctr)
+		tb.cleanups[0]()
+
+		require.True(t, ctr.terminated)
+		require.Equal(t, []string{"diagnostics of container %s partially written to %s: %v"}, tb.logs)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv(DiagnosticsEnv, "false")
+
+		tb := &diagnosticsTB{TB: t, name: "TestPostgres"}
+		ctr := &diagnosticsContainer{dir: "unset"}
+		Cleanup(tb, ctr)
+		tb.cleanups[0]()
+
+		require.True(t, ctr.terminated)
+		require.Equal(t, "unset", ctr.dir)
+		require.Empty(t, tb.logs)
+	})
+
+	t.Run("nil-container", func(t *testing.T) {
+		tb := &diagnosticsTB{TB: t, name: "TestPostgres"}
+		var ctr *Container
+		Cleanup(tb, ctr)
+		tb.cleanups[0]()
+
+		require.Empty(t, tb.logs)
+	})
+}
+
+func TestDiagnosticsRoot(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		t.Setenv(DiagnosticsEnv, "")
+		t.Setenv(DiagnosticsDirEnv, "")
+
+		root, ok := diagnosticsRoot()
+		require.True(t, ok)
+		require.Equal(t, filepath.Join(os.TempDir(), "docker-sdk-diagnostics"), root)
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		t.Setenv(DiagnosticsEnv, "true")
+		t.Setenv(DiagnosticsDirEnv, "artifacts")
+
+		root, ok := diagnosticsRoot()
+		require.True(t, ok)
+		require.Equal(t, "artifacts", root)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Setenv(DiagnosticsEnv, "0")
+		t.Setenv(DiagnosticsDirEnv, "artifacts")
+
+		_, ok := diagnosticsRoot()
+		require.False(t, ok)
+	})
+}
+
+func TestDiagnosticsTestDir(t *testing.T) {
+	for name, want := range map[string]string{
+		"TestRun":             "TestRun",
+		"TestRun/with-env":    filepath.Join("TestRun", "with-env"),
+		"TestRun/port 80:tcp": filepath.Join("TestRun", "port_80_tcp"),
+		"TestRun/../etc":      filepath.Join("TestRun", "_", "etc"),
+		"TestRun//empty":      filepath.Join("TestRun", "_", "empty"),
+		"TestRun/émoji-🐳":     filepath.Join("TestRun", "_moji-_"),
+	} {
+		require.Equal(t, want, diagnosticsTestDir(name), name)
+	}
+}
+
+func TestContainer_WaitErrors(t *testing.T) {
+	ctr, _ := newLifecycleTestContainer(StateRunning)
+	require.Empty(t, ctr.WaitErrors())
+
+	ctr.recordWaitError(errors.New("context deadline exceeded"))
+	errs := ctr.WaitErrors()
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0], " context deadline exceeded")
+}
+
+// failingStrategy is a wait strategy which always fails.
+type failingStrategy struct{}
+
+func (failingStrategy) WaitUntilReady(_ context.Context, _ wait.StrategyTarget) error {
+	return errors.New("context deadline exceeded")
+}
+
+func TestDefaultReadinessHook_recordsWaitErrors(t *testing.T) {
+	ctr, _ := newLifecycleTestContainer(StateRunning)
+	ctr.waitingFor = failingStrategy{}
+
+	// the errors are recorded by any container info able to, e.g. a wrapped container
+	wrapped := struct{ *Container }{ctr}
+	err := defaultReadinessHook().PostStarts[0](context.Background(), wrapped)
+	require.ErrorContains(t, err, "wait until ready")
+
+	errs := ctr.WaitErrors()
+	require.Len(t, errs, 1)
+	require.Contains(t, errs[0], " context deadline exceeded")
+}
@@ -0,0 +1,86 @@
+package container
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// ChangeKind is the kind of a change of the filesystem of a container.
+type ChangeKind string
+
+const (
+	// ChangeAdded the path was added.
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeModified the path was modified.
+	ChangeModified ChangeKind = "modified"
+
+	// ChangeDeleted the path was deleted.
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// Change is a change of the filesystem of a container, compared to its image.
+type Change struct {
+	// Kind the kind of the change.
+	Kind ChangeKind
+
+	// Path the absolute path of the changed file or directory in the container.
+	Path string
+}
+
+// String returns the change in the format of the docker diff command, e.g. "A /tmp/file".
+func (c Change) String() string {
+	var prefix string
+	switch c.Kind {
+	case ChangeAdded:
+		prefix = "A"
+	case ChangeModified:
+		prefix = "C"
+	case ChangeDeleted:
+		prefix = "D"
+	}
+
+	return prefix + " " + c.Path
+}
+
+// Diff returns the changes of the filesystem of the container compared to its image, sorted by path.
+// The parent directories of changed paths are reported as modified. Changes in volumes are not reported.
+func (c *Container) Diff(ctx context.Context) ([]Change, error) {
+	resp, err := c.dockerClient.ContainerDiff(ctx, c.ID(), client.ContainerDiffOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("container diff: %w", err)
+	}
+
+	return changesOf(resp.Changes)
+}
+
+// changesOf converts the changes reported by the daemon into typed changes, sorted by path.
+func changesOf(fsChanges []container.FilesystemChange) ([]Change, error) {
+	changes := make([]Change, 0, len(fsChanges))
+	for _, fsChange := range fsChanges {
+		var kind ChangeKind
+		switch fsChange.Kind {
+		case container.ChangeAdd:
+			kind = ChangeAdded
+		case container.ChangeModify:
+			kind = ChangeModified
+		case container.ChangeDelete:
+			kind = ChangeDeleted
+		default:
+			return nil, fmt.Errorf("unknown change kind %d of %s", fsChange.Kind, fsChange.Path)
+		}
+
+		changes = append(changes, Change{Kind: kind, Path: fsChange.Path})
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+
+	return changes, nil
+}
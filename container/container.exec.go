@@ -1,11 +1,19 @@
 package container
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/moby/moby/api/pkg/stdcopy"
 	"github.com/moby/moby/client"
 
 	"github.com/docker/go-sdk/container/exec"
@@ -45,20 +53,357 @@ func (c *Container) Exec(ctx context.Context, cmd []string, options ...exec.Proc
 		o.Apply(processOptions)
 	}
 
-	var exitCode int
+	exitCode, err := c.waitExec(ctx, response.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return exitCode, processOptions.Reader, nil
+}
+
+// waitExec polls the exec process until it's not running anymore, returning its exit code.
+func (c *Container) waitExec(ctx context.Context, execID string) (int, error) {
 	for {
-		execResp, err := c.dockerClient.ExecInspect(ctx, response.ID, client.ExecInspectOptions{})
+		execResp, err := c.dockerClient.ExecInspect(ctx, execID, client.ExecInspectOptions{})
 		if err != nil {
-			return 0, nil, fmt.Errorf("container exec inspect: %w", err)
+			return 0, fmt.Errorf("container exec inspect: %w", err)
 		}
 
 		if !execResp.Running {
-			exitCode = execResp.ExitCode
-			break
+			return execResp.ExitCode, nil
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
+}
 
-	return exitCode, processOptions.Reader, nil
+// ExecResult is the result of a command executed with [Container.ExecWithResult].
+type ExecResult struct {
+	// ExitCode the exit code of the command.
+	ExitCode int
+
+	// Stdout the standard output of the command. When the command uses a TTY,
+	// it includes the standard error too.
+	Stdout []byte
+
+	// Stderr the standard error of the command.
+	Stderr []byte
+
+	// Duration the time between the start of the command and its exit.
+	Duration time.Duration
+}
+
+// ExecWithResult executes a command in the current container, waiting for it to exit.
+// It returns the exit code of the command, and its standard output and error, kept separate.
+// A non-zero exit code is not an error. The [exec.Multiplexed] option has no effect,
+// as the output is always demultiplexed.
+func (c *Container) ExecWithResult(ctx context.Context, cmd []string, options ...exec.ProcessOption) (*ExecResult, error) {
+	var stdout, stderr bytes.Buffer
+
+	proc, err := c.startExec(ctx, cmd, false, &stdout, &stderr, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	exitCode, err := proc.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		ExitCode: exitCode,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: proc.duration,
+	}, nil
+}
+
+// ExecProcess is a command running in a container, started with [Container.ExecStart].
+type ExecProcess struct {
+	ctr *Container
+
+	// ctx the context the process was started with, bounding its lifetime.
+	ctx context.Context
+
+	// id the ID of the exec instance.
+	id string
+
+	// marker the value of [execMarkerEnv] in the environment of the process, identifying it.
+	marker string
+
+	// tty whether the process runs in a TTY.
+	tty bool
+
+	// hijack the connection attached to the process streams.
+	hijack client.HijackedResponse
+
+	// stdout and stderr the readers of the process output, nil when the output is written to buffers.
+	stdout io.Reader
+	stderr io.Reader
+
+	// startedAt the time the process was started.
+	startedAt time.Time
+
+	// outputDone is closed once the process output has been fully copied.
+	outputDone chan struct{}
+
+	waitOnce sync.Once
+	exitCode int
+	duration time.Duration
+	waitErr  error
+}
+
+// ExecStart starts a command in the current container, without waiting for it to exit,
+// returning a handle to write to its standard input, read its output, resize its TTY,
+// signal it, and wait for it to exit. The environment of the command holds [execMarkerEnv],
+// identifying the process to signal it.
+//
+// As with the pipes of [os/exec.Cmd], the standard output and error must be read while the
+// process runs, or it may block writing to them: [ExecProcess.Wait] returns once both have been
+// read until EOF. The lifetime of the process is bounded by ctx.
+func (c *Container) ExecStart(ctx context.Context, cmd []string, options ...exec.ProcessOption) (*ExecProcess, error) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	marker := rand.Text()
+	options = append(slices.Clone(options), exec.ProcessOptionFunc(func(opts *exec.ProcessOptions) {
+		opts.ExecConfig.Env = append(slices.Clone(opts.ExecConfig.Env), execMarkerEnv+"="+marker)
+	}))
+
+	proc, err := c.startExec(ctx, cmd, true, stdoutWriter, stderrWriter, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	proc.marker = marker
+
+	proc.stdout = stdoutReader
+	proc.stderr = stderrReader
+
+	return proc, nil
+}
+
+// startExec creates and starts an exec process, copying its output to stdout and stderr
+// in the background. If the writers are pipes, they are closed once the output ends.
+func (c *Container) startExec(ctx context.Context, cmd []string, attachStdin bool, stdout io.Writer, stderr io.Writer, options ...exec.ProcessOption) (*ExecProcess, error) {
+	processOptions := exec.NewProcessOptions(cmd)
+	for _, o := range options {
+		o.Apply(processOptions)
+	}
+	processOptions.ExecConfig.AttachStdin = attachStdin
+
+	response, err := c.dockerClient.ExecCreate(ctx, c.ID(), processOptions.ExecConfig)
+	if err != nil {
+		return nil, fmt.Errorf("container exec create: %w", err)
+	}
+
+	// attaching starts the process
+	hijack, err := c.dockerClient.ExecAttach(ctx, response.ID, client.ExecAttachOptions{
+		TTY: processOptions.ExecConfig.TTY,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("container exec attach: %w", err)
+	}
+
+	proc := &ExecProcess{
+		ctr:        c,
+		ctx:        ctx,
+		id:         response.ID,
+		tty:        processOptions.ExecConfig.TTY,
+		hijack:     hijack.HijackedResponse,
+		startedAt:  time.Now(),
+		outputDone: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(proc.outputDone)
+
+		var err error
+		if proc.tty {
+			// a TTY merges the output in a single stream, without multiplexing headers
+			_, err = io.Copy(stdout, hijack.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(stdout, stderr, hijack.Reader)
+		}
+
+		for _, w := range []io.Writer{stdout, stderr} {
+			if pw, ok := w.(*io.PipeWriter); ok {
+				pw.CloseWithError(err)
+			}
+		}
+	}()
+
+	return proc, nil
+}
+
+// ID returns the ID of the exec instance.
+func (p *ExecProcess) ID() string {
+	return p.id
+}
+
+// Stdin returns a writer to the standard input of the process.
+// Closing it closes the standard input, signaling the end of the input to the process.
+func (p *ExecProcess) Stdin() io.WriteCloser {
+	return execStdin{hijack: &p.hijack}
+}
+
+// Stdout returns a reader of the standard output of the process.
+// When the process uses a TTY, it includes the standard error too.
+func (p *ExecProcess) Stdout() io.Reader {
+	return p.stdout
+}
+
+// Stderr returns a reader of the standard error of the process.
+// It's empty when the process uses a TTY.
+func (p *ExecProcess) Stderr() io.Reader {
+	return p.stderr
+}
+
+// Wait waits for the process to exit, returning its exit code, and releases the attached streams.
+// A non-zero exit code is not an error. It's safe to call Wait multiple times.
+func (p *ExecProcess) Wait() (int, error) {
+	p.waitOnce.Do(func() {
+		defer p.hijack.Close()
+
+		select {
+		case <-p.outputDone:
+		case <-p.ctx.Done():
+			p.waitErr = p.ctx.Err()
+			return
+		}
+
+		p.exitCode, p.waitErr = p.ctr.waitExec(p.ctx, p.id)
+		p.duration = time.Since(p.startedAt)
+	})
+
+	return p.exitCode, p.waitErr
+}
+
+// Resize resizes the TTY of the process. It returns an error if the process doesn't use a TTY.
+func (p *ExecProcess) Resize(ctx context.Context, height uint, width uint) error {
+	if !p.tty {
+		return errors.New("exec process has no TTY")
+	}
+
+	if _, err := p.ctr.dockerClient.ExecResize(ctx, p.id, client.ExecResizeOptions{Height: height, Width: width}); err != nil {
+		return fmt.Errorf("container exec resize: %w", err)
+	}
+
+	return nil
+}
+
+// Kill sends a signal to the process, e.g. "TERM" or "SIGKILL". If signal is empty, "KILL" is sent.
+//
+// The Docker API can't signal an exec process, so the process is located in the container's
+// PID namespace by the [execMarkerEnv] variable of its environment, and the signal is sent with
+// the kill command. It requires the container to provide a POSIX shell, tr and grep. If the
+// process can't be located, e.g. because it replaced its environment, an error is returned
+// and no signal is sent. Killing a process which has already exited is a no-op.
+func (p *ExecProcess) Kill(ctx context.Context, signal string) error {
+	inspect, err := p.ctr.dockerClient.ExecInspect(ctx, p.id, client.ExecInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("container exec inspect: %w", err)
+	}
+
+	if !inspect.Running {
+		return nil
+	}
+
+	pid, err := p.ctr.execPID(ctx, p.marker)
+	if err != nil {
+		return fmt.Errorf("locate exec process: %w", err)
+	}
+
+	signal = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if signal == "" {
+		signal = "KILL"
+	}
+
+	exitCode, output, err := p.ctr.Exec(ctx, []string{"kill", "-s", signal, strconv.Itoa(pid)}, exec.Multiplexed())
+	if err != nil {
+		return fmt.Errorf("kill: %w", err)
+	}
+
+	if exitCode != 0 {
+		msg, _ := io.ReadAll(output)
+		return fmt.Errorf("kill exited with code %d: %s", exitCode, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// execStdin writes to the standard input of an exec process.
+type execStdin struct {
+	hijack *client.HijackedResponse
+}
+
+// Write implements [io.Writer].
+func (s execStdin) Write(p []byte) (int, error) {
+	return s.hijack.Conn.Write(p)
+}
+
+// Close implements [io.Closer], closing the standard input only.
+func (s execStdin) Close() error {
+	return s.hijack.CloseWrite()
+}
+
+// execMarkerEnv is the environment variable holding the unique marker of a process
+// started with [Container.ExecStart].
+const execMarkerEnv = "DOCKER_SDK_EXEC"
+
+// execRootScript lists the processes of the container started by the daemon outside the init
+// process tree, which have no parent in the container's PID namespace, with the marker passed
+// as first argument in their environment. Their children inherit the marker, but not the lack
+// of parent.
+const execRootScript = `for s in /proc/[0-9]*/status; do
+  p=${s#/proc/}; p=${p%/status}
+  [ "$p" = 1 ] || [ "$p" = "$$" ] && continue
+  while read -r k v; do [ "$k" = "PPid:" ] && { [ "$v" = 0 ] && tr '\0' '\n' < "/proc/$p/environ" 2>/dev/null | grep -qxF "$1" && echo "$p"; break; }; done < "$s" 2>/dev/null
+done`
+
+// execPID returns the PID, in the container's PID namespace, of the exec process with
+// the given marker. The daemon reports host PIDs, which can't be mapped to the ones of
+// the container, so the process is located by its environment.
+func (c *Container) execPID(ctx context.Context, marker string) (int, error) {
+	if marker == "" {
+		return 0, errors.New("exec process has no marker")
+	}
+
+	exitCode, output, err := c.Exec(ctx, []string{"sh", "-c", execRootScript, "sh", execMarkerEnv + "=" + marker}, exec.Multiplexed())
+	if err != nil {
+		return 0, fmt.Errorf("list processes: %w", err)
+	}
+	if exitCode != 0 {
+		return 0, fmt.Errorf("list processes: exit code %d", exitCode)
+	}
+
+	out, err := io.ReadAll(output)
+	if err != nil {
+		return 0, fmt.Errorf("read processes: %w", err)
+	}
+
+	return parseExecPID(string(out))
+}
+
+// parseExecPID parses the PIDs listed by [execRootScript], which must be exactly one.
+func parseExecPID(out string) (int, error) {
+	fields := strings.Fields(out)
+	switch len(fields) {
+	case 0:
+		return 0, errors.New("exec process not found")
+	case 1:
+	default:
+		return 0, fmt.Errorf("several exec processes found: %v", fields)
+	}
+
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, fmt.Errorf("parse pid %q: %w", fields[0], err)
+	}
+
+	return pid, nil
 }
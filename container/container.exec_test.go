@@ -102,3 +102,114 @@ func TestContainer_Exec(t *testing.T) {
 		})
 	})
 }
+
+func TestContainer_ExecWithResult(t *testing.T) {
+	ctr, err := container.Run(context.Background(),
+		container.WithImage(nginxAlpineImage),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	t.Run("separate-streams", func(t *testing.T) {
+		result, err := ctr.ExecWithResult(context.Background(), []string{"sh", "-c", "echo out; echo err >&2; exit 2"})
+		require.NoError(t, err)
+		require.Equal(t, 2, result.ExitCode)
+		require.Equal(t, "out\n", string(result.Stdout))
+		require.Equal(t, "err\n", string(result.Stderr))
+		require.Positive(t, result.Duration)
+	})
+
+	t.Run("with-tty", func(t *testing.T) {
+		result, err := ctr.ExecWithResult(context.Background(), []string{"sh", "-c", "echo out; echo err >&2"}, exec.WithTTY(true))
+		require.NoError(t, err)
+		require.Zero(t, result.ExitCode)
+		require.Contains(t, string(result.Stdout), "err")
+		require.Empty(t, result.Stderr)
+	})
+}
+
+func TestContainer_ExecStart(t *testing.T) {
+	ctr, err := container.Run(context.Background(),
+		container.WithImage(nginxAlpineImage),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	t.Run("stdin", func(t *testing.T) {
+		proc, err := ctr.ExecStart(context.Background(), []string{"cat"})
+		require.NoError(t, err)
+
+		_, err = io.WriteString(proc.Stdin(), "hello exec")
+		require.NoError(t, err)
+		require.NoError(t, proc.Stdin().Close())
+
+		out, err := io.ReadAll(proc.Stdout())
+		require.NoError(t, err)
+		require.Equal(t, "hello exec", string(out))
+
+		_, err = io.ReadAll(proc.Stderr())
+		require.NoError(t, err)
+
+		code, err := proc.Wait()
+		require.NoError(t, err)
+		require.Zero(t, code)
+	})
+
+	t.Run("kill", func(t *testing.T) {
+		// a second process running the same command must not be killed
+		other, err := ctr.ExecStart(context.Background(), []string{"sleep", "300"})
+		require.NoError(t, err)
+
+		proc, err := ctr.ExecStart(context.Background(), []string{"sleep", "300"})
+		require.NoError(t, err)
+
+		go func() { _, _ = io.Copy(io.Discard, proc.Stdout()) }()
+		go func() { _, _ = io.Copy(io.Discard, proc.Stderr()) }()
+
+		require.NoError(t, proc.Kill(context.Background(), "TERM"))
+
+		code, err := proc.Wait()
+		require.NoError(t, err)
+		require.Equal(t, 143, code)
+
+		// killing an exited process is a no-op
+		require.NoError(t, proc.Kill(context.Background(), "KILL"))
+
+		result, err := ctr.ExecWithResult(context.Background(), []string{"pgrep", "sleep"})
+		require.NoError(t, err)
+		require.Zero(t, result.ExitCode, "the other process must still be running")
+
+		require.NoError(t, other.Kill(context.Background(), ""))
+	})
+
+	t.Run("kill-unlocated", func(t *testing.T) {
+		// the marker is dropped from the environment of the process
+		proc, err := ctr.ExecStart(context.Background(), []string{"env", "-i", "sleep", "300"})
+		require.NoError(t, err)
+
+		go func() { _, _ = io.Copy(io.Discard, proc.Stdout()) }()
+		go func() { _, _ = io.Copy(io.Discard, proc.Stderr()) }()
+
+		require.ErrorContains(t, proc.Kill(context.Background(), "KILL"), "exec process not found")
+
+		result, err := ctr.ExecWithResult(context.Background(), []string{"pgrep", "sleep"})
+		require.NoError(t, err)
+		require.Zero(t, result.ExitCode, "the process must still be running")
+
+		_, err = ctr.ExecWithResult(context.Background(), []string{"pkill", "sleep"})
+		require.NoError(t, err)
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		proc, err := ctr.ExecStart(context.Background(), []string{"sleep", "1"}, exec.WithTTY(true))
+		require.NoError(t, err)
+
+		go func() { _, _ = io.Copy(io.Discard, proc.Stdout()) }()
+		go func() { _, _ = io.Copy(io.Discard, proc.Stderr()) }()
+
+		require.NoError(t, proc.Resize(context.Background(), 40, 120))
+
+		_, err = proc.Wait()
+		require.NoError(t, err)
+	})
+}
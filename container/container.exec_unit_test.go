@@ -0,0 +1,22 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExecPID(t *testing.T) {
+	pid, err := parseExecPID("42\n")
+	require.NoError(t, err)
+	require.Equal(t, 42, pid)
+
+	_, err = parseExecPID("")
+	require.ErrorContains(t, err, "not found")
+
+	_, err = parseExecPID("42\n17\n")
+	require.ErrorContains(t, err, "several exec processes")
+
+	_, err = parseExecPID("pid\n")
+	require.Error(t, err)
+}
@@ -0,0 +1,194 @@
+package container
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/moby/moby/client"
+)
+
+// ExportOption is an option to select the paths exported with [Container.Export] and [Container.ExportTo].
+type ExportOption func(*exportOptions)
+
+// exportOptions the options to export the filesystem of a container.
+type exportOptions struct {
+	paths   []string
+	include []string
+	exclude []string
+}
+
+// ExportPaths only exports the given absolute paths of the container, with their content.
+func ExportPaths(paths ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.paths = append(o.paths, paths...)
+	}
+}
+
+// ExportInclude only exports the files matching at least one of the glob patterns, in the
+// [path.Match] syntax. Patterns are matched against the path relative to the root of the container,
+// without the leading slash, and patterns without a slash are matched against the base name too.
+// Directories are always exported, unless excluded.
+func ExportInclude(patterns ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// ExportExclude skips the files and directories, with their content, matching at least one
+// of the glob patterns. Patterns are matched as in [ExportInclude], and take precedence over them.
+func ExportExclude(patterns ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// filtered returns true if the options select only part of the filesystem.
+func (o exportOptions) filtered() bool {
+	return len(o.paths) > 0 || len(o.include) > 0 || len(o.exclude) > 0
+}
+
+// newExportOptions returns the export options, validating the glob patterns
+// and cleaning the paths.
+func newExportOptions(opts ...ExportOption) (exportOptions, error) {
+	var options exportOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	for _, pattern := range slices.Concat(options.include, options.exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return exportOptions{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	for i, p := range options.paths {
+		if !path.IsAbs(p) {
+			return exportOptions{}, fmt.Errorf("export path %q must be absolute", p)
+		}
+		options.paths[i] = strings.TrimPrefix(path.Clean(p), "/")
+		if options.paths[i] == "" {
+			options.paths[i] = "."
+		}
+	}
+
+	return options, nil
+}
+
+// Export writes the root filesystem of the container to w as a tar archive, whose entries are named
+// relative to the root of the container. Use the [ExportOption] functions to only export some paths.
+// Volumes are not part of the exported filesystem.
+func (c *Container) Export(ctx context.Context, w io.Writer, opts ...ExportOption) error {
+	options, err := newExportOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	r, err := c.dockerClient.ContainerExport(ctx, c.ID(), client.ContainerExportOptions{})
+	if err != nil {
+		return fmt.Errorf("container export: %w", err)
+	}
+	defer r.Close()
+
+	if !options.filtered() {
+		if _, err := io.Copy(w, r); err != nil {
+			return fmt.Errorf("copy export: %w", err)
+		}
+		return nil
+	}
+
+	if err := filterArchive(tar.NewReader(r), tar.NewWriter(w), options); err != nil {
+		return fmt.Errorf("filter export: %w", err)
+	}
+
+	return nil
+}
+
+// ExportTo extracts the root filesystem of the container into a directory of the host, which is created
+// if it doesn't exist. Absolute symbolic links are rewritten relative to the host directory, and symbolic
+// links are resolved through the other links of the filesystem. Entries pointing outside of the directory,
+// written through symbolic links, or hard links to anything but regular files, are rejected with an error
+// wrapping [ErrUnsafeArchivePath]. Devices and other special files are skipped. Use the [ExportOption]
+// functions to only export some paths.
+func (c *Container) ExportTo(ctx context.Context, hostDir string, opts ...ExportOption) error {
+	if err := os.MkdirAll(hostDir, 0o755); err != nil {
+		return fmt.Errorf("create host dir: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.Export(ctx, pw, opts...))
+	}()
+
+	err := extractArchive(c.logger, tar.NewReader(pr), hostDir, true)
+	// unblock the export on extraction errors
+	pr.CloseWithError(errors.New("extraction stopped"))
+	if err != nil {
+		return fmt.Errorf("extract export: %w", err)
+	}
+
+	return nil
+}
+
+// filterArchive copies the entries of a tar archive selected by the options.
+func filterArchive(tr *tar.Reader, tw *tar.Writer, options exportOptions) error {
+	var excludedDirs []string
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("next entry: %w", err)
+		}
+
+		rel := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if rel == "" || !exportSelected(rel, header.Typeflag == tar.TypeDir, options, &excludedDirs) {
+			continue
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return fmt.Errorf("copy %s: %w", rel, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+// exportSelected returns true if the options select the path, relative to the root of the container.
+// Excluded directories are added to excludedDirs, to skip their content.
+func exportSelected(rel string, dir bool, options exportOptions, excludedDirs *[]string) bool {
+	if len(options.paths) > 0 && !slices.ContainsFunc(options.paths, func(p string) bool {
+		return p == "." || rel == p || strings.HasPrefix(rel, p+"/")
+	}) {
+		return false
+	}
+
+	if slices.ContainsFunc(*excludedDirs, func(p string) bool {
+		return strings.HasPrefix(rel, p+"/")
+	}) {
+		return false
+	}
+
+	if matchAny(options.exclude, rel) {
+		if dir {
+			*excludedDirs = append(*excludedDirs, rel)
+		}
+		return false
+	}
+
+	if !dir && len(options.include) > 0 && !matchAny(options.include, rel) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package container_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+)
+
+func TestContainer_Diff(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx, container.WithImage(nginxAlpineImage))
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	code, _, err := ctr.Exec(ctx, []string{"sh", "-c", "echo hello > /tmp/added.txt && rm /etc/motd"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	changes, err := ctr.Diff(ctx)
+	require.NoError(t, err)
+	require.Contains(t, changes, container.Change{Kind: container.ChangeAdded, Path: "/tmp/added.txt"})
+	require.Contains(t, changes, container.Change{Kind: container.ChangeModified, Path: "/tmp"})
+	require.Contains(t, changes, container.Change{Kind: container.ChangeDeleted, Path: "/etc/motd"})
+}
+
+func TestContainer_Export(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx, container.WithImage(nginxAlpineImage))
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	code, _, err := ctr.Exec(ctx, []string{"sh", "-c", "mkdir -p /data/logs && echo hello > /data/hello.txt && echo log > /data/logs/app.log"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	t.Run("export", func(t *testing.T) {
+		var buff bytes.Buffer
+		require.NoError(t, ctr.Export(ctx, &buff, container.ExportPaths("/data"), container.ExportExclude("logs")))
+
+		var names []string
+		tr := tar.NewReader(&buff)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			names = append(names, header.Name)
+		}
+		require.ElementsMatch(t, []string{"data/", "data/hello.txt"}, names)
+	})
+
+	t.Run("export-to", func(t *testing.T) {
+		dst := t.TempDir()
+		require.NoError(t, ctr.ExportTo(ctx, dst, container.ExportPaths("/data", "/bin")))
+
+		content, err := os.ReadFile(filepath.Join(dst, "data", "hello.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", string(content))
+
+		_, err = os.Lstat(filepath.Join(dst, "bin", "sh"))
+		require.NoError(t, err)
+
+		_, err = os.Stat(filepath.Join(dst, "etc"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestContainer_ExportTo_chainedLinks(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx, container.WithImage(nginxAlpineImage))
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	// each link is lexically local, but /links/l2 resolves to the parent of the root through /links/d/l1
+	code, _, err := ctr.Exec(ctx, []string{"sh", "-c", "mkdir -p /links/d && ln -s .. /links/d/l1 && ln -s d/l1/../.. /links/l2"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	parent := t.TempDir()
+	dst := filepath.Join(parent, "rootfs")
+
+	err = ctr.ExportTo(ctx, dst, container.ExportPaths("/links"))
+	require.ErrorIs(t, err, container.ErrUnsafeArchivePath)
+
+	_, err = os.Lstat(filepath.Join(dst, "links", "l2"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
@@ -0,0 +1,240 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangesOf(t *testing.T) {
+	changes, err := changesOf([]container.FilesystemChange{
+		{Kind: container.ChangeModify, Path: "/tmp"},
+		{Kind: container.ChangeAdd, Path: "/tmp/file"},
+		{Kind: container.ChangeDelete, Path: "/etc/motd"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Kind: ChangeDeleted, Path: "/etc/motd"},
+		{Kind: ChangeModified, Path: "/tmp"},
+		{Kind: ChangeAdded, Path: "/tmp/file"},
+	}, changes)
+	require.Equal(t, "A /tmp/file", changes[2].String())
+
+	_, err = changesOf([]container.FilesystemChange{{Kind: 42, Path: "/tmp"}})
+	require.Error(t, err)
+}
+
+// rootfsArchive returns a tar archive of a small root filesystem.
+func rootfsArchive(t *testing.T) []byte {
+	t.Helper()
+
+	var buff bytes.Buffer
+	tw := tar.NewWriter(&buff)
+
+	entries := []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0o755, Size: 4},
+		{Name: "bin/sh", Typeflag: tar.TypeSymlink, Linkname: "/bin/busybox"},
+		{Name: "bin/ls", Typeflag: tar.TypeLink, Linkname: "bin/busybox"},
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0o644, Size: 5},
+		{Name: "etc/ssl/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "etc/ssl/cert.pem", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+		{Name: "dev/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0o666, Devmajor: 1, Devminor: 3},
+	}
+	contents := map[string]string{"bin/busybox": "elf!", "etc/hosts": "hosts", "etc/ssl/cert.pem": "cert"}
+
+	for _, header := range entries {
+		require.NoError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(contents[header.Name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buff.Bytes()
+}
+
+func TestFilterArchive(t *testing.T) {
+	namesOf := func(t *testing.T, opts ...ExportOption) []string {
+		t.Helper()
+
+		options, err := newExportOptions(opts...)
+		require.NoError(t, err)
+
+		var buff bytes.Buffer
+		require.NoError(t, filterArchive(tar.NewReader(bytes.NewReader(rootfsArchive(t))), tar.NewWriter(&buff), options))
+
+		var names []string
+		tr := tar.NewReader(&buff)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				return names
+			}
+			require.NoError(t, err)
+			names = append(names, header.Name)
+		}
+	}
+
+	require.Equal(t, []string{"etc/", "etc/hosts", "etc/ssl/", "etc/ssl/cert.pem"}, namesOf(t, ExportPaths("/etc")))
+	require.Equal(t, []string{"etc/", "etc/hosts"}, namesOf(t, ExportPaths("/etc"), ExportExclude("ssl")))
+	require.Equal(t, []string{"bin/", "etc/", "etc/ssl/", "etc/ssl/cert.pem", "dev/"}, namesOf(t, ExportInclude("*.pem")))
+	require.Len(t, namesOf(t, ExportPaths("/")), 10)
+
+	_, err := newExportOptions(ExportPaths("etc"))
+	require.Error(t, err)
+
+	_, err = newExportOptions(ExportExclude("[a-"))
+	require.Error(t, err)
+}
+
+func TestExtractArchive_rootfs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// archiveOf returns a tar archive of the headers, regular files holding "pwned".
+	archiveOf := func(t *testing.T, headers ...*tar.Header) *tar.Reader {
+		t.Helper()
+
+		var buff bytes.Buffer
+		tw := tar.NewWriter(&buff)
+		for _, header := range headers {
+			var content []byte
+			if header.Typeflag == tar.TypeReg {
+				content = []byte("pwned")
+			}
+			header.Mode = 0o644
+			header.Size = int64(len(content))
+			require.NoError(t, tw.WriteHeader(header))
+			_, err := tw.Write(content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+
+		return tar.NewReader(&buff)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		dst := t.TempDir()
+		require.NoError(t, extractArchive(logger, tar.NewReader(bytes.NewReader(rootfsArchive(t))), dst, true))
+
+		target, err := os.Readlink(filepath.Join(dst, "bin", "sh"))
+		require.NoError(t, err)
+		require.Equal(t, "busybox", target)
+
+		content, err := os.ReadFile(filepath.Join(dst, "bin", "ls"))
+		require.NoError(t, err)
+		require.Equal(t, "elf!", string(content))
+
+		content, err = os.ReadFile(filepath.Join(dst, "etc", "ssl", "cert.pem"))
+		require.NoError(t, err)
+		require.Equal(t, "cert", string(content))
+
+		_, err = os.Lstat(filepath.Join(dst, "dev", "null"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("unsafe", func(t *testing.T) {
+		var buff bytes.Buffer
+		tw := tar.NewWriter(&buff)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/link", Typeflag: tar.TypeSymlink, Linkname: "../../../etc/passwd"}))
+		require.NoError(t, tw.Close())
+
+		err := extractArchive(logger, tar.NewReader(&buff), t.TempDir(), true)
+		require.ErrorIs(t, err, ErrUnsafeArchivePath)
+	})
+
+	t.Run("unsafe/chained-symlinks", func(t *testing.T) {
+		parent := t.TempDir()
+		dst := filepath.Join(parent, "rootfs")
+		require.NoError(t, os.Mkdir(dst, 0o755))
+		victim := filepath.Join(parent, "victim")
+		require.NoError(t, os.WriteFile(victim, []byte("safe"), 0o600))
+
+		// each link is lexically local, but the hard link resolves through both of them
+		err := extractArchive(logger, archiveOf(t,
+			&tar.Header{Name: "d/", Typeflag: tar.TypeDir},
+			&tar.Header{Name: "d/l", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			&tar.Header{Name: "d/l/l2", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			&tar.Header{Name: "h", Typeflag: tar.TypeLink, Linkname: "d/l/l2/victim"},
+			&tar.Header{Name: "h", Typeflag: tar.TypeReg},
+		), dst, true)
+		require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+		content, err := os.ReadFile(victim)
+		require.NoError(t, err)
+		require.Equal(t, "safe", string(content))
+
+		entries, err := os.ReadDir(parent)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+	})
+
+	t.Run("absolute-symlink-through-symlink", func(t *testing.T) {
+		dst := t.TempDir()
+
+		err := extractArchive(logger, archiveOf(t,
+			&tar.Header{Name: "d/", Typeflag: tar.TypeDir},
+			&tar.Header{Name: "e/f", Typeflag: tar.TypeReg},
+			&tar.Header{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: "/e"},
+			&tar.Header{Name: "l2", Typeflag: tar.TypeSymlink, Linkname: "/d/l1/f"},
+		), dst, true)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dst, "l2"))
+		require.NoError(t, err)
+		require.Equal(t, "pwned", string(content))
+	})
+
+	for name, headers := range map[string][]*tar.Header{
+		"relative": {
+			{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "l2", Typeflag: tar.TypeSymlink, Linkname: "d/l1/.."},
+		},
+		"absolute": {
+			{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+			{Name: "l2", Typeflag: tar.TypeSymlink, Linkname: "/d/l1"},
+		},
+		"absolute-nested": {
+			{Name: "d/l1", Typeflag: tar.TypeSymlink, Linkname: "/"},
+			{Name: "e/f/l2", Typeflag: tar.TypeSymlink, Linkname: "/d/l1"},
+			{Name: "l3", Typeflag: tar.TypeSymlink, Linkname: "e/f/l2/.."},
+		},
+	} {
+		t.Run("unsafe/chained-links-"+name, func(t *testing.T) {
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "rootfs")
+			require.NoError(t, os.Mkdir(dst, 0o755))
+
+			err := extractArchive(logger, archiveOf(t, append([]*tar.Header{{Name: "d/", Typeflag: tar.TypeDir}}, headers...)...), dst, true)
+			require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+			entries, err := os.ReadDir(dst)
+			require.NoError(t, err)
+			for _, entry := range entries {
+				require.True(t, entry.IsDir(), "link %s created", entry.Name())
+			}
+		})
+	}
+
+	t.Run("unsafe/absolute-symlink-parent", func(t *testing.T) {
+		dst := t.TempDir()
+
+		// absolute links are rewritten inside the host directory, but entries are not written through them
+		err := extractArchive(logger, archiveOf(t,
+			&tar.Header{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: "/"},
+			&tar.Header{Name: "lib/passwd", Typeflag: tar.TypeReg},
+		), dst, true)
+		require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+		_, err = os.Lstat(filepath.Join(dst, "passwd"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/netip"
+	"sync"
 
 	"github.com/moby/moby/api/types/container"
 
@@ -11,14 +13,18 @@ import (
 	"github.com/docker/go-sdk/container/wait"
 )
 
-// Container represents a container
+// Container represents a container.
+//
+// Its lifecycle methods ([Container.Start], [Container.Stop] and [Container.Terminate])
+// are safe to call concurrently: they are serialized, and the lifecycle state is
+// guarded by a mutex. They must not be called from the container's lifecycle hooks.
 type Container struct {
 	dockerClient client.SDKClient
 
-	// containerID the Container ID
+	// containerID the Container ID, guarded by stateMtx as it changes when the container is recreated.
 	containerID string
 
-	// shortID the short Container ID, using the first 12 characters of the ID
+	// shortID the short Container ID, using the first 12 characters of the ID, guarded by stateMtx.
 	shortID string
 
 	// WaitingFor the waiting strategy to use for the container.
@@ -36,8 +42,69 @@ type Container struct {
 	// lifecycleHooks the lifecycle hooks to use for the container.
 	lifecycleHooks []LifecycleHooks
 
-	// isRunning the flag to check if the container is running.
-	isRunning bool
+	// lifecycleMtx serializes the lifecycle operations of the container.
+	lifecycleMtx sync.Mutex
+
+	// stateMtx guards the lifecycle state and its observers, and the IDs of the container.
+	stateMtx sync.RWMutex
+
+	// state the lifecycle state of the container.
+	state LifecycleState
+
+	// stateObservers the functions called after the lifecycle state changes.
+	stateObservers []StateObserver
+
+	// volumes the volumes to remove when the container is terminated.
+	volumes []string
+
+	// inspectCache the last inspect result of the container.
+	inspectCache inspectCache
+
+	// watchMtx guards the crash handlers and the watcher.
+	watchMtx sync.Mutex
+
+	// crashHandlers the functions called when the watched container crashes.
+	crashHandlers []CrashHandler
+
+	// watcher the running monitor of the container, nil if it's not watched.
+	watcher *watcher
+
+	// syncMtx guards the syncers.
+	syncMtx sync.Mutex
+
+	// syncers the running syncs of host directories into the container.
+	syncers []*Syncer
+
+	// snapshotMtx guards the snapshots.
+	snapshotMtx sync.Mutex
+
+	// snapshots the snapshots taken, by snapshot name.
+	snapshots map[string]snapshot
+
+	// snapshotImages the IDs of the snapshot images to remove when the container is terminated.
+	snapshotImages []string
+
+	// snapshotVolumes the names of the snapshot volumes to remove when the container is terminated.
+	snapshotVolumes []string
+
+	// restoredVolumes the names of the volumes mounted in the container by the last restore,
+	// removed when the container is restored again or terminated.
+	restoredVolumes []string
+
+	// sidecars the containers sharing the network namespace of the container, terminated with it.
+	sidecars []*Container
+
+	// hostAccess the forwarder relaying connections to the ports of the host, nil if not used.
+	hostAccess *hostAccessForwarderContainer
+
+	// hostPorts the allocator of the host ports pinned in the port bindings, nil if not used.
+	hostPorts *hostPortAllocator
+
+	// waitErrsMtx guards the wait errors.
+	waitErrsMtx sync.Mutex
+
+	// waitErrs the errors of the wait strategy, reported by the diagnostics.
+	waitErrs []string
 }
 
 // Client returns the client used by the container.
@@ -47,6 +114,9 @@ func (c *Container) Client() client.SDKClient {
 
 // ID returns the container ID
 func (c *Container) ID() string {
+	c.stateMtx.RLock()
+	defer c.stateMtx.RUnlock()
+
 	return c.containerID
 }
 
@@ -57,17 +127,10 @@ func (c *Container) Image() string {
 
 // ShortID returns the short container ID, using the first 12 characters of the ID
 func (c *Container) ShortID() string {
-	return c.shortID
-}
-
-// IsRunning returns the running state of the container.
-func (c *Container) IsRunning() bool {
-	return c.isRunning
-}
+	c.stateMtx.RLock()
+	defer c.stateMtx.RUnlock()
 
-// Running sets the running state of the container.
-func (c *Container) Running(b bool) {
-	c.isRunning = b
+	return c.shortID
 }
 
 // WaitingFor returns the waiting strategy used by the container.
@@ -121,7 +184,7 @@ func FromResponse(ctx context.Context, dockerClient client.SDKClient, response c
 	for _, port := range response.Ports {
 		// Only include ports that are published to the host (PublicPort != 0)
 		if port.PublicPort != 0 {
-			exposedPorts = append(exposedPorts, fmt.Sprintf("%d/%s", port.PublicPort, port.Type))
+			exposedPorts = append(exposedPorts, portSpecOf(port))
 		}
 	}
 
@@ -135,7 +198,7 @@ func FromResponse(ctx context.Context, dockerClient client.SDKClient, response c
 		containerID:  response.ID,
 		shortID:      shortID,
 		image:        response.Image,
-		isRunning:    response.State == "running",
+		state:        stateFromDocker(string(response.State)),
 		exposedPorts: exposedPorts,
 		logger:       dockerClient.Logger(),
 		lifecycleHooks: []LifecycleHooks{
@@ -145,3 +208,24 @@ func FromResponse(ctx context.Context, dockerClient client.SDKClient, response c
 
 	return ctr, nil
 }
+
+// portSpecOf returns the port spec of a published port, in the "[hostIP:]hostPort:containerPort/proto"
+// format, keeping the protocol and the host IP of the binding. If the container port is unknown,
+// it returns "hostPort/proto".
+func portSpecOf(port container.PortSummary) string {
+	proto := port.Type
+	if proto == "" {
+		proto = "tcp"
+	}
+
+	if port.PrivatePort == 0 {
+		return fmt.Sprintf("%d/%s", port.PublicPort, proto)
+	}
+
+	if !port.IP.IsValid() {
+		return fmt.Sprintf("%d:%d/%s", port.PublicPort, port.PrivatePort, proto)
+	}
+
+	// IPv6 addresses are enclosed in brackets, as in "[::]:8080:80/tcp"
+	return fmt.Sprintf("%s:%d/%s", netip.AddrPortFrom(port.IP, port.PublicPort), port.PrivatePort, proto)
+}
@@ -0,0 +1,401 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/system"
+	dockerclient "github.com/moby/moby/client"
+
+	"github.com/docker/go-sdk/container/wait"
+)
+
+// HostInternal is the hostname the ports of the host are reachable at from a container
+// using [WithHostPortAccess].
+const HostInternal = "host.internal"
+
+const (
+	// hostAccessImage the image of the container forwarding the connections to the host,
+	// providing socat and a POSIX shell.
+	hostAccessImage = "alpine/socat:1.8.0.1"
+
+	// hostAccessProbeTimeout the time to wait for a host port to accept a connection,
+	// when checking whether it's reachable through the host-gateway.
+	hostAccessProbeTimeout = time.Second
+
+	// dockerDesktopOS the operating system reported by the Docker Desktop daemons.
+	dockerDesktopOS = "Docker Desktop"
+
+	// hostAccessDir the directory of the forwarder holding its control and connection FIFOs.
+	hostAccessDir = "/run/host-access"
+
+	// hostAccessReady the log line the forwarder prints once it listens.
+	hostAccessReady = "host access ready"
+)
+
+// hostAccessScript is the entrypoint of the forwarder, listening on the ports passed as arguments.
+// Each connection is handed to relay.sh, which announces a pair of FIFOs on the control FIFO
+// and copies the connection through them. The SDK reads the control FIFO and, for each
+// announced connection, dials the host port and attaches an exec to the FIFOs, so all the
+// traffic goes through the Docker API, whatever the host of the daemon.
+//
+// relay.sh exits once the host side is done, closing the connection. Its standard input
+// is duplicated to fd 3 because asynchronous commands get /dev/null as standard input.
+const hostAccessScript = `set -e
+D=` + hostAccessDir + `
+mkdir -p "$D"
+mkfifo "$D/control"
+cat > "$D/relay.sh" <<'EOF'
+#!/bin/sh
+d=` + hostAccessDir + `/$$
+mkfifo "$d.in" "$d.out"
+echo "$1 $d" > ` + hostAccessDir + `/control
+exec 3<&0
+cat <&3 > "$d.out" &
+cat "$d.in"
+kill $! 2>/dev/null || true
+rm -f "$d.in" "$d.out"
+EOF
+chmod +x "$D/relay.sh"
+for port in "$@"; do
+	socat "TCP-LISTEN:$port,fork,reuseaddr" "EXEC:$D/relay.sh $port" &
+done
+echo "` + hostAccessReady + `"
+wait
+`
+
+// hostAccessMode is how the ports of the host are made reachable from a container.
+type hostAccessMode int
+
+const (
+	// hostAccessGateway maps [HostInternal] to the host-gateway of the daemon.
+	hostAccessGateway hostAccessMode = iota
+
+	// hostAccessForwarder maps [HostInternal] to a forwarder container.
+	hostAccessForwarder
+)
+
+// WithHostPortAccess makes the given ports of the host, e.g. the one of a webhook receiver
+// started by the test, reachable from the container at [HostInternal], on the same ports.
+//
+// When the daemon runs on the same host and supports it (Docker 20.10 or later, not rootless),
+// [HostInternal] is mapped to the host-gateway of the daemon. Docker Desktop forwards those
+// connections to the loopback interface of the host, but other daemons only reach the services
+// listening on an address of the Docker bridge, e.g. ":8080" rather than "127.0.0.1:8080",
+// so the host-gateway is only used with them when all the ports accept connections on
+// a non-loopback address of the host.
+//
+// Otherwise, e.g. with a remote or rootless daemon, or when the SDK runs in a container,
+// [HostInternal] is mapped to a forwarder container, attached to the first network of the
+// container, which relays each connection to "localhost" on the host through the Docker API.
+// The forwarder is terminated with the container.
+//
+// Host port access is not supported with a container network mode.
+func WithHostPortAccess(ports ...uint16) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if slices.Contains(ports, 0) {
+			return errors.New("host port access: invalid port 0")
+		}
+
+		def.hostAccessPorts = ports
+		return nil
+	}
+}
+
+// selectHostAccessMode returns how the ports of the host are made reachable, from the host
+// of the daemon, as returned by [client.SDKClient.DaemonHostWithContext], and its information.
+// The host-gateway reaches the host of the daemon, so it's only used when it's the current host.
+func selectHostAccessMode(daemonHost string, info system.Info) hostAccessMode {
+	if !isLocalDaemonHost(daemonHost) {
+		return hostAccessForwarder
+	}
+
+	for _, opt := range info.SecurityOptions {
+		if opt == "name=rootless" {
+			return hostAccessForwarder
+		}
+	}
+
+	// host-gateway was introduced in Docker 20.10
+	major, minor, ok := parseDaemonVersion(info.ServerVersion)
+	if !ok || major < 20 || (major == 20 && minor < 10) {
+		return hostAccessForwarder
+	}
+
+	return hostAccessGateway
+}
+
+// isLocalDaemonHost returns whether the host of the daemon, as returned by
+// [client.SDKClient.DaemonHostWithContext], is the current host.
+func isLocalDaemonHost(daemonHost string) bool {
+	if daemonHost == "localhost" {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(daemonHost)
+	return err == nil && addr.IsLoopback()
+}
+
+// hostPortsReachable returns whether all the ports of the host accept connections on a non-loopback
+// address of the host, preferring the one of the default Docker bridge, so they are reachable
+// through the host-gateway. Services listening on the loopback interface only are not.
+func hostPortsReachable(ctx context.Context, ports []uint16) bool {
+	addr, ok := hostBridgeAddr()
+	if !ok {
+		return false
+	}
+
+	dialer := net.Dialer{Timeout: hostAccessProbeTimeout}
+	for _, port := range ports {
+		conn, err := dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(addr, port).String())
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+	}
+
+	return true
+}
+
+// hostBridgeAddr returns an IPv4 address of the host which is not a loopback one,
+// preferring the one of the default Docker bridge.
+func hostBridgeAddr() (netip.Addr, bool) {
+	var candidates []net.Interface
+	if iface, err := net.InterfaceByName("docker0"); err == nil {
+		candidates = append(candidates, *iface)
+	}
+	if ifaces, err := net.Interfaces(); err == nil {
+		candidates = append(candidates, ifaces...)
+	}
+
+	for _, iface := range candidates {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			prefix, err := netip.ParsePrefix(a.String())
+			if err != nil {
+				continue
+			}
+			if addr := prefix.Addr(); addr.Is4() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast() {
+				return addr, true
+			}
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// parseDaemonVersion parses the major and minor numbers of a daemon version, e.g. "28.3.1".
+func parseDaemonVersion(version string) (int, int, bool) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
+// setupHostAccess maps [HostInternal] in the host config of the container, starting a forwarder
+// if needed, which is returned. It must be called once the create hooks ran, as it depends
+// on the network of the container.
+func (d *Definition) setupHostAccess(ctx context.Context, hostConfig *container.HostConfig) (*hostAccessForwarderContainer, error) {
+	if len(d.hostAccessPorts) == 0 {
+		return nil, nil
+	}
+
+	if hostConfig.NetworkMode.IsContainer() {
+		return nil, errors.New("host port access is not supported with a container network mode")
+	}
+
+	daemonHost, err := d.dockerClient.DaemonHostWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("daemon host: %w", err)
+	}
+
+	info, err := d.dockerClient.Info(ctx, dockerclient.InfoOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("docker info: %w", err)
+	}
+
+	if selectHostAccessMode(daemonHost, info.Info) == hostAccessGateway {
+		// only Docker Desktop reaches the services listening on the loopback interface
+		if info.Info.OperatingSystem == dockerDesktopOS || hostPortsReachable(ctx, d.hostAccessPorts) {
+			hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, HostInternal+":host-gateway")
+			return nil, nil
+		}
+
+		d.dockerClient.Logger().Debug("host ports not reachable through the host-gateway, using a forwarder", "ports", d.hostAccessPorts)
+	}
+
+	nw := "bridge"
+	switch {
+	case len(d.networks) > 0:
+		nw = d.networks[0]
+	case hostConfig.NetworkMode.IsUserDefined():
+		nw = hostConfig.NetworkMode.NetworkName()
+	}
+
+	forwarder, err := startHostAccessForwarder(ctx, d, nw)
+	if err != nil {
+		return nil, fmt.Errorf("host access forwarder: %w", err)
+	}
+
+	hostConfig.ExtraHosts = append(hostConfig.ExtraHosts, HostInternal+":"+forwarder.ip.String())
+
+	return forwarder, nil
+}
+
+// hostAccessForwarderContainer is a running forwarder, relaying the connections
+// to the host ports until it's stopped.
+type hostAccessForwarderContainer struct {
+	ctr    *Container
+	ip     netip.Addr
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startHostAccessForwarder starts a forwarder container attached to the network nw,
+// relaying the connections to the host ports of the definition.
+func startHostAccessForwarder(ctx context.Context, def *Definition, nw string) (*hostAccessForwarderContainer, error) {
+	ports := make([]string, 0, len(def.hostAccessPorts))
+	for _, port := range def.hostAccessPorts {
+		ports = append(ports, strconv.Itoa(int(port)))
+	}
+
+	opts := []ContainerCustomizer{
+		WithClient(def.dockerClient),
+		WithImage(hostAccessImage),
+		WithImageSubstitutors(def.imageSubstitutors...),
+		WithEntrypoint(append([]string{"sh", "-c", hostAccessScript, "sh"}, ports...)...),
+		WithWaitStrategy(wait.ForLog(hostAccessReady)),
+	}
+	if nw != "bridge" {
+		opts = append(opts, WithNetworkName(nil, nw))
+	}
+
+	ctr, err := Run(ctx, opts...)
+	if err != nil {
+		return nil, errors.Join(err, Terminate(ctr))
+	}
+
+	inspect, err := ctr.Inspect(ctx)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("inspect: %w", err), Terminate(ctr))
+	}
+
+	var ip netip.Addr
+	if inspect.Container.NetworkSettings != nil {
+		if settings, ok := inspect.Container.NetworkSettings.Networks[nw]; ok && settings != nil {
+			ip = settings.IPAddress
+		}
+	}
+	if !ip.IsValid() {
+		return nil, errors.Join(fmt.Errorf("no IP address on network %s", nw), Terminate(ctr))
+	}
+
+	// the relays outlive the context of Run, until the forwarder is stopped
+	relayCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	control, err := ctr.ExecStart(relayCtx, []string{"sh", "-c", "exec cat <>" + hostAccessDir + "/control"})
+	if err != nil {
+		cancel()
+		return nil, errors.Join(fmt.Errorf("control exec: %w", err), Terminate(ctr))
+	}
+	go func() { _, _ = io.Copy(io.Discard, control.Stderr()) }()
+
+	f := &hostAccessForwarderContainer{
+		ctr:    ctr,
+		ip:     ip,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(f.done)
+
+		scanner := bufio.NewScanner(control.Stdout())
+		for scanner.Scan() {
+			port, dir, ok := strings.Cut(scanner.Text(), " ")
+			if !ok {
+				continue
+			}
+			go f.relay(relayCtx, port, dir)
+		}
+	}()
+
+	ctr.logger.Info("Host access forwarder started", "containerID", ctr.ShortID(), "ports", ports, "ip", ip)
+
+	return f, nil
+}
+
+// relay relays a connection announced by the forwarder, through the FIFOs in dir,
+// to the port of the host.
+func (f *hostAccessForwarderContainer) relay(ctx context.Context, port string, dir string) {
+	proc, err := f.ctr.ExecStart(ctx, []string{"sh", "-c", `cat "$1.out" & cat > "$1.in"; wait`, "sh", dir})
+	if err != nil {
+		f.ctr.logger.Debug("host access relay failed", "port", port, "error", err)
+		return
+	}
+	go func() { _, _ = io.Copy(io.Discard, proc.Stderr()) }()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("localhost", port))
+	if err != nil {
+		f.ctr.logger.Debug("host access dial failed", "port", port, "error", err)
+		// closing the standard input closes the connection in the container
+		_ = proc.Stdin().Close()
+		_, _ = io.Copy(io.Discard, proc.Stdout())
+		_, _ = proc.Wait()
+		return
+	}
+	defer conn.Close()
+
+	hostDone := make(chan struct{})
+	go func() {
+		defer close(hostDone)
+		_, _ = io.Copy(proc.Stdin(), conn)
+		_ = proc.Stdin().Close()
+	}()
+
+	_, _ = io.Copy(conn, proc.Stdout())
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.CloseWrite()
+	}
+
+	<-hostDone
+	_, _ = proc.Wait()
+}
+
+// stop stops relaying the connections and terminates the forwarder container.
+func (f *hostAccessForwarderContainer) stop(ctx context.Context) error {
+	f.cancel()
+	err := f.ctr.Terminate(ctx)
+	<-f.done
+
+	return err
+}
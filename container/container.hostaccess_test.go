@@ -0,0 +1,67 @@
+package container_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/exec"
+)
+
+func TestRun_withHostPortAccess(t *testing.T) {
+	// listening on all the interfaces, the host is reachable through the host-gateway too
+	t.Run("all-interfaces", func(t *testing.T) {
+		testHostPortAccess(t, ":0")
+	})
+
+	// listening on the loopback interface, the host is only reachable through the forwarder,
+	// except with Docker Desktop
+	t.Run("loopback", func(t *testing.T) {
+		testHostPortAccess(t, "127.0.0.1:0")
+	})
+}
+
+// testHostPortAccess checks that a server of the host listening on the address is reachable
+// from a container using [container.WithHostPortAccess].
+func testHostPortAccess(t *testing.T, address string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "hello from the host")
+		}),
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	ctx := context.Background()
+	ctr, err := container.Run(ctx,
+		container.WithImage(alpineLatest),
+		container.WithEntrypoint("tail", "-f", "/dev/null"),
+		container.WithHostPortAccess(uint16(port)),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("http://%s:%d", container.HostInternal, port)
+	for range 3 {
+		code, r, err := ctr.Exec(ctx, []string{"wget", "-q", "-O", "-", url}, exec.Multiplexed())
+		require.NoError(t, err)
+		require.Zero(t, code)
+
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "hello from the host", string(out))
+	}
+}
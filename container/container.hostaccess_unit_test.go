@@ -0,0 +1,185 @@
+package container
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/system"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+)
+
+// hostAccessFakeClient is a fake SDK client returning a fixed daemon host and information.
+type hostAccessFakeClient struct {
+	hostFakeClient
+
+	info system.Info
+}
+
+func (f *hostAccessFakeClient) Info(_ context.Context, _ dockerclient.InfoOptions) (dockerclient.SystemInfoResult, error) {
+	return dockerclient.SystemInfoResult{Info: f.info}, nil
+}
+
+func TestSelectHostAccessMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		daemonHost string
+		info       system.Info
+		want       hostAccessMode
+	}{
+		{
+			name:       "local",
+			daemonHost: "localhost",
+			info:       system.Info{ServerVersion: "28.3.1"},
+			want:       hostAccessGateway,
+		},
+		{
+			name:       "local-loopback-ip",
+			daemonHost: "127.0.0.1",
+			info:       system.Info{ServerVersion: "20.10.0"},
+			want:       hostAccessGateway,
+		},
+		{
+			name:       "remote",
+			daemonHost: "docker.example.com",
+			info:       system.Info{ServerVersion: "28.3.1"},
+			want:       hostAccessForwarder,
+		},
+		{
+			name:       "in-a-container",
+			daemonHost: "172.17.0.1",
+			info:       system.Info{ServerVersion: "28.3.1"},
+			want:       hostAccessForwarder,
+		},
+		{
+			name:       "rootless",
+			daemonHost: "localhost",
+			info:       system.Info{ServerVersion: "28.3.1", SecurityOptions: []string{"name=seccomp,profile=builtin", "name=rootless"}},
+			want:       hostAccessForwarder,
+		},
+		{
+			name:       "without-host-gateway",
+			daemonHost: "localhost",
+			info:       system.Info{ServerVersion: "19.03.15"},
+			want:       hostAccessForwarder,
+		},
+		{
+			name:       "unknown-version",
+			daemonHost: "localhost",
+			info:       system.Info{ServerVersion: "dev"},
+			want:       hostAccessForwarder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, selectHostAccessMode(tt.daemonHost, tt.info))
+		})
+	}
+}
+
+func TestDefinition_setupHostAccess(t *testing.T) {
+	newDefinition := func(t *testing.T, opts ...CustomizeDefinitionOption) *Definition {
+		t.Helper()
+
+		def := &Definition{
+			dockerClient: &hostAccessFakeClient{
+				hostFakeClient: hostFakeClient{host: "localhost"},
+				info:           system.Info{ServerVersion: "28.3.1", OperatingSystem: "Docker Desktop"},
+			},
+		}
+		for _, opt := range opts {
+			require.NoError(t, opt(def))
+		}
+
+		return def
+	}
+
+	t.Run("host-gateway", func(t *testing.T) {
+		def := newDefinition(t, WithHostPortAccess(8080, 9090))
+		hostConfig := &container.HostConfig{ExtraHosts: []string{"db:10.0.0.2"}}
+
+		forwarder, err := def.setupHostAccess(context.Background(), hostConfig)
+		require.NoError(t, err)
+		require.Nil(t, forwarder)
+		require.Equal(t, []string{"db:10.0.0.2", "host.internal:host-gateway"}, hostConfig.ExtraHosts)
+	})
+
+	t.Run("host-gateway-reachable", func(t *testing.T) {
+		if _, ok := hostBridgeAddr(); !ok {
+			t.Skip("no non-loopback address")
+		}
+
+		port := listenHostPort(t, ":0")
+		def := newDefinition(t, WithHostPortAccess(port))
+		def.dockerClient.(*hostAccessFakeClient).info.OperatingSystem = "Ubuntu 24.04.3 LTS"
+		hostConfig := &container.HostConfig{}
+
+		forwarder, err := def.setupHostAccess(context.Background(), hostConfig)
+		require.NoError(t, err)
+		require.Nil(t, forwarder)
+		require.Equal(t, []string{"host.internal:host-gateway"}, hostConfig.ExtraHosts)
+	})
+
+	t.Run("no-ports", func(t *testing.T) {
+		def := newDefinition(t)
+		hostConfig := &container.HostConfig{}
+
+		forwarder, err := def.setupHostAccess(context.Background(), hostConfig)
+		require.NoError(t, err)
+		require.Nil(t, forwarder)
+		require.Empty(t, hostConfig.ExtraHosts)
+	})
+
+	t.Run("container-network-mode", func(t *testing.T) {
+		def := newDefinition(t, WithHostPortAccess(8080))
+		hostConfig := &container.HostConfig{NetworkMode: "container:1234"}
+
+		_, err := def.setupHostAccess(context.Background(), hostConfig)
+		require.ErrorContains(t, err, "not supported with a container network mode")
+	})
+
+	t.Run("invalid-port", func(t *testing.T) {
+		def := &Definition{}
+		require.Error(t, WithHostPortAccess(8080, 0)(def))
+		require.Empty(t, def.hostAccessPorts)
+	})
+}
+
+// listenHostPort listens on the address until the end of the test, returning the port.
+func listenHostPort(t *testing.T, address string) uint16 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	return uint16(listener.Addr().(*net.TCPAddr).Port)
+}
+
+func TestHostPortsReachable(t *testing.T) {
+	if _, ok := hostBridgeAddr(); !ok {
+		t.Skip("no non-loopback address")
+	}
+
+	t.Run("all-interfaces", func(t *testing.T) {
+		require.True(t, hostPortsReachable(context.Background(), []uint16{listenHostPort(t, ":0"), listenHostPort(t, ":0")}))
+	})
+
+	t.Run("loopback", func(t *testing.T) {
+		require.False(t, hostPortsReachable(context.Background(), []uint16{listenHostPort(t, ":0"), listenHostPort(t, "127.0.0.1:0")}))
+	})
+}
+
+func TestHostAccessScript_parses(t *testing.T) {
+	sh, err := exec.LookPath("/bin/sh")
+	if err != nil {
+		t.Skipf("/bin/sh unavailable: %v", err)
+	}
+
+	out, err := exec.Command(sh, "-n", "-c", hostAccessScript).CombinedOutput()
+	require.NoError(t, err, "output:\n%s", out)
+}
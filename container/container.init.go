@@ -0,0 +1,71 @@
+package container
+
+import (
+	"context"
+	"fmt"
+)
+
+// initContainerStderrTail is the number of trailing bytes of the standard error
+// of a failed init container kept in its [InitContainerError].
+const initContainerStderrTail = 1024
+
+// InitContainerError is returned by [Run] when an init container exits with a non-zero code.
+type InitContainerError struct {
+	// Index the index of the init container, in the order passed to [WithInitContainers].
+	Index int
+
+	// ExitCode the exit code of the init container.
+	ExitCode int
+
+	// Stderr the last bytes of the standard error of the init container.
+	Stderr string
+}
+
+// Error returns the error message, including the end of the standard error of the init container.
+func (e *InitContainerError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("init container %d exited with code %d", e.Index, e.ExitCode)
+	}
+	return fmt.Sprintf("init container %d exited with code %d: %s", e.Index, e.ExitCode, e.Stderr)
+}
+
+// WithInitContainers appends the containers run to completion before the container is created,
+// e.g. to seed a volume or wait for a dependency. Each init container is defined by the customizers
+// passed to [RunJob], using the Docker client of the container unless it sets its own one.
+//
+// Init containers run one at a time, in order, and are removed once they exit.
+// If one of them fails to run, or exits with a non-zero code, the container is not created
+// and [Run] returns an error, which is an [*InitContainerError] for a non-zero exit code.
+func WithInitContainers(defs ...[]ContainerCustomizer) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.initContainers = append(def.initContainers, defs...)
+		return nil
+	}
+}
+
+// runInitContainers runs the init containers of the definition, in order, stopping at the first failure.
+func (d *Definition) runInitContainers(ctx context.Context) error {
+	for i, opts := range d.initContainers {
+		jobOpts := make([]ContainerCustomizer, 0, len(opts)+2)
+		jobOpts = append(jobOpts, WithClient(d.dockerClient))
+		jobOpts = append(jobOpts, opts...)
+		jobOpts = append(jobOpts, WithAutoRemove())
+
+		d.dockerClient.Logger().Info("Running init container", "index", i)
+
+		result, err := RunJob(ctx, jobOpts...)
+		if err != nil {
+			return fmt.Errorf("init container %d: %w", i, err)
+		}
+
+		if result.ExitCode != 0 {
+			stderr := result.Stderr
+			if len(stderr) > initContainerStderrTail {
+				stderr = stderr[len(stderr)-initContainerStderrTail:]
+			}
+			return &InitContainerError{Index: i, ExitCode: result.ExitCode, Stderr: string(stderr)}
+		}
+	}
+
+	return nil
+}
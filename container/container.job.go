@@ -0,0 +1,222 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// JobResult is the result of a container run to completion with [RunJob].
+type JobResult struct {
+	// ExitCode the exit code of the container.
+	ExitCode int
+
+	// Stdout the standard output of the container. When the container uses a TTY,
+	// it includes the standard error too.
+	Stdout []byte
+
+	// Stderr the standard error of the container.
+	Stderr []byte
+
+	// Duration the time between the start of the container and its exit.
+	Duration time.Duration
+
+	// OOMKilled whether the container was killed because it ran out of memory.
+	OOMKilled bool
+
+	// Container the exited container, which the caller must terminate.
+	// It's nil when the container was removed with [WithAutoRemove].
+	Container *Container
+}
+
+// WithStdin sets the input written to the standard input of a job run with [RunJob].
+// The standard input is closed once the input is fully written.
+// It's only supported by [RunJob], [Run] returns an error if it's set.
+func WithStdin(stdin io.Reader) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.stdin = stdin
+		return nil
+	}
+}
+
+// WithAutoRemove removes the container of a job run with [RunJob] once its result is collected,
+// or once it's killed because the context is done.
+// It's only supported by [RunJob], [Run] returns an error if it's set.
+func WithAutoRemove() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.autoRemove = true
+		return nil
+	}
+}
+
+// validateJob validates that the options of jobs, the standard input and the auto removal,
+// are only set for jobs.
+func (d *Definition) validateJob() error {
+	if d.job {
+		return nil
+	}
+
+	if d.stdin != nil {
+		return errors.New("stdin is only supported by RunJob")
+	}
+	if d.autoRemove {
+		return errors.New("auto remove is only supported by RunJob")
+	}
+
+	return nil
+}
+
+// RunJob creates a container from the given customizers, the same as [Run], starts it and waits
+// for it to exit, returning its exit code and its standard output and error, kept separate.
+// A non-zero exit code is not an error.
+//
+// Use [WithStdin] to write to the standard input of the container, and [WithAutoRemove]
+// to remove the container once it exits. If ctx is done before the container exits,
+// the container is killed, and ctx's error is returned.
+//
+// A job may exit as soon as it's started, so its wait strategy, if any, is not used.
+//
+// On error, the result is returned, if any, to allow the caller to clean up the container.
+func RunJob(ctx context.Context, opts ...ContainerCustomizer) (*JobResult, error) {
+	var (
+		stdin      io.Reader
+		autoRemove bool
+	)
+
+	jobOpts := make([]ContainerCustomizer, 0, len(opts)+2)
+	jobOpts = append(jobOpts, CustomizeDefinitionOption(func(def *Definition) error {
+		def.job = true
+		return nil
+	}))
+	jobOpts = append(jobOpts, opts...)
+	jobOpts = append(jobOpts, CustomizeDefinitionOption(func(def *Definition) error {
+		// the container is started once its streams are attached
+		def.started = false
+		stdin = def.stdin
+		autoRemove = def.autoRemove
+		return nil
+	}))
+
+	ctr, err := Run(ctx, jobOpts...)
+	if err != nil {
+		if ctr != nil {
+			err = errors.Join(err, ctr.Terminate(context.WithoutCancel(ctx)))
+		}
+		return nil, err
+	}
+
+	result, err := ctr.runJob(ctx, stdin)
+	if autoRemove {
+		if errTerminate := ctr.Terminate(context.WithoutCancel(ctx)); errTerminate != nil {
+			err = errors.Join(err, fmt.Errorf("terminate: %w", errTerminate))
+		}
+	} else {
+		result.Container = ctr
+	}
+
+	return result, err
+}
+
+// runJob starts the created container, writing stdin to its standard input,
+// and waits for it to exit. The container is killed if ctx is done before.
+func (c *Container) runJob(ctx context.Context, stdin io.Reader) (*JobResult, error) {
+	result := &JobResult{}
+
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return result, fmt.Errorf("inspect: %w", err)
+	}
+	tty := inspect.Container.Config != nil && inspect.Container.Config.Tty
+
+	attach, err := c.dockerClient.ContainerAttach(ctx, c.ID(), client.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  stdin != nil,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return result, fmt.Errorf("container attach: %w", err)
+	}
+	defer attach.Close()
+
+	var stdout, stderr bytes.Buffer
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if tty {
+			_, err = io.Copy(&stdout, attach.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(&stdout, &stderr, attach.Reader)
+		}
+		outputDone <- err
+	}()
+
+	if stdin != nil {
+		go func() {
+			if _, err := io.Copy(attach.Conn, stdin); err != nil {
+				c.logger.Debug("failed to write job stdin", "containerID", c.ShortID(), "error", err)
+			}
+			if err := attach.CloseWrite(); err != nil {
+				c.logger.Debug("failed to close job stdin", "containerID", c.ShortID(), "error", err)
+			}
+		}()
+	}
+
+	// waiting for the next exit must be requested before starting the container
+	wait := c.dockerClient.ContainerWait(ctx, c.ID(), client.ContainerWaitOptions{
+		Condition: container.WaitConditionNextExit,
+	})
+
+	startedAt := time.Now()
+	if err := c.Start(ctx); err != nil {
+		return result, fmt.Errorf("start container: %w", err)
+	}
+
+	var statusCode int64
+	select {
+	case resp := <-wait.Result:
+		statusCode = resp.StatusCode
+	case err = <-wait.Error:
+	case <-ctx.Done():
+	}
+	result.Duration = time.Since(startedAt)
+
+	if ctx.Err() != nil {
+		if _, errKill := c.dockerClient.ContainerKill(context.WithoutCancel(ctx), c.ID(), client.ContainerKillOptions{}); errKill != nil {
+			return result, errors.Join(ctx.Err(), fmt.Errorf("container kill: %w", errKill))
+		}
+
+		c.Running(false)
+		return result, ctx.Err()
+	}
+
+	if err != nil {
+		return result, fmt.Errorf("container wait: %w", err)
+	}
+
+	// the output streams end once the container exits
+	if err := <-outputDone; err != nil {
+		c.logger.Debug("failed to read job output", "containerID", c.ShortID(), "error", err)
+	}
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	result.ExitCode = int(statusCode)
+
+	c.Running(false)
+
+	state, err := c.State(ctx)
+	if err != nil {
+		return result, fmt.Errorf("state: %w", err)
+	}
+	result.ExitCode = state.ExitCode
+	result.OOMKilled = state.OOMKilled
+
+	return result, nil
+}
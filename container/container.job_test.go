@@ -0,0 +1,103 @@
+package container_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+func TestRunJob(t *testing.T) {
+	t.Run("separate-streams", func(t *testing.T) {
+		result, err := container.RunJob(context.Background(),
+			container.WithImage(bashImage),
+			container.WithCmd("bash", "-c", "echo out; echo err >&2; exit 3"),
+			container.WithAutoRemove(),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 3, result.ExitCode)
+		require.Equal(t, "out\n", string(result.Stdout))
+		require.Equal(t, "err\n", string(result.Stderr))
+		require.False(t, result.OOMKilled)
+		require.Positive(t, result.Duration)
+		require.Nil(t, result.Container)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		result, err := container.RunJob(context.Background(),
+			container.WithImage(bashImage),
+			container.WithCmd("bash", "-c", "tr a-z A-Z"),
+			container.WithStdin(strings.NewReader("hello job")),
+		)
+		if result != nil {
+			container.Cleanup(t, result.Container)
+		}
+		require.NoError(t, err)
+		require.Zero(t, result.ExitCode)
+		require.Equal(t, "HELLO JOB", string(result.Stdout))
+
+		require.NotNil(t, result.Container)
+		require.Equal(t, container.StateStopped, result.Container.LifecycleState())
+	})
+
+	t.Run("context-cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		result, err := container.RunJob(ctx,
+			container.WithImage(bashImage),
+			container.WithCmd("sleep", "300"),
+		)
+		if result != nil {
+			container.Cleanup(t, result.Container)
+		}
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		state, err := result.Container.State(context.Background())
+		require.NoError(t, err)
+		require.False(t, state.Running)
+	})
+
+	t.Run("stdin-requires-job", func(t *testing.T) {
+		ctr, err := container.Run(context.Background(),
+			container.WithImage(bashImage),
+			container.WithStdin(strings.NewReader("input")),
+		)
+		require.Error(t, err)
+		require.Nil(t, ctr)
+	})
+
+	t.Run("auto-remove-requires-job", func(t *testing.T) {
+		ctr, err := container.Run(context.Background(),
+			container.WithImage(bashImage),
+			container.WithAutoRemove(),
+		)
+		require.ErrorContains(t, err, "auto remove is only supported by RunJob")
+		require.Nil(t, ctr)
+	})
+
+	t.Run("wait-strategy-not-used", func(t *testing.T) {
+		// the job exits before the log is ever written
+		result, err := container.RunJob(context.Background(),
+			container.WithImage(bashImage),
+			container.WithCmd("true"),
+			container.WithWaitStrategy(wait.ForLog("never written").WithTimeout(time.Second)),
+			container.WithAutoRemove(),
+		)
+		require.NoError(t, err)
+		require.Zero(t, result.ExitCode)
+	})
+
+	t.Run("invalid-definition", func(t *testing.T) {
+		result, err := container.RunJob(context.Background(),
+			container.WithCmd("true"),
+		)
+		require.Error(t, err)
+		require.Nil(t, result)
+	})
+}
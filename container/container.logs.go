@@ -31,7 +31,7 @@ func (c *Container) Logs(ctx context.Context) (io.ReadCloser, error) {
 	}
 
 	// Check if the container has TTY enabled, to determine the log format
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		rc.Close()
 		return nil, fmt.Errorf("inspect container: %w", err)
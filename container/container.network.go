@@ -2,13 +2,30 @@ package container
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moby/moby/api/types/network"
 )
 
+// ErrNoSharedNetwork is returned when a container can't be reached by its peers through
+// a user-defined network: it's not attached to the requested network, the network is one
+// of the default networks, or two containers are not attached to a common network.
+var ErrNoSharedNetwork = errors.New("no shared user-defined network")
+
+// defaultNetworks are the networks created by the Docker daemon, where containers
+// can't be reached by their aliases.
+var defaultNetworks = []string{"bridge", "host", "none"}
+
 // ContainerIP gets the IP address of the primary network within the container.
 // If there are multiple networks, it returns an empty string.
 func (c *Container) ContainerIP(ctx context.Context) (netip.Addr, error) {
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return netip.Addr{}, err
 	}
@@ -26,7 +43,7 @@ func (c *Container) ContainerIP(ctx context.Context) (netip.Addr, error) {
 
 // ContainerIPs gets the IP addresses of all the networks within the container.
 func (c *Container) ContainerIPs(ctx context.Context) ([]netip.Addr, error) {
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +58,7 @@ func (c *Container) ContainerIPs(ctx context.Context) ([]netip.Addr, error) {
 
 // NetworkAliases gets the aliases of the container for the networks it is attached to.
 func (c *Container) NetworkAliases(ctx context.Context) (map[string][]string, error) {
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return map[string][]string{}, err
 	}
@@ -59,7 +76,7 @@ func (c *Container) NetworkAliases(ctx context.Context) (map[string][]string, er
 
 // Networks gets the names of the networks the container is attached to.
 func (c *Container) Networks(ctx context.Context) ([]string, error) {
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return []string{}, err
 	}
@@ -74,3 +91,132 @@ func (c *Container) Networks(ctx context.Context) ([]string, error) {
 
 	return n, nil
 }
+
+// InternalEndpoint gets the proto://host:port string to reach a container port from the peers
+// attached to the given user-defined network, identified by its name or ID. The host is the first
+// network alias of the container, its name if it has no alias, or its IP address on the network.
+// It returns just host:port if proto is blank.
+//
+// If networkName is empty, the only user-defined network of the container is used, which is
+// convenient for networks created with [WithNewNetwork]. It returns an error wrapping
+// [ErrNoSharedNetwork] if the container is not attached to a user-defined network matching it.
+func (c *Container) InternalEndpoint(ctx context.Context, networkName string, port network.Port, proto string) (string, error) {
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
+	if err != nil {
+		return "", fmt.Errorf("inspect: %w", err)
+	}
+
+	var networks map[string]*network.EndpointSettings
+	if inspect.Container.NetworkSettings != nil {
+		networks = inspect.Container.NetworkSettings.Networks
+	}
+
+	name, settings, err := userDefinedNetwork(networks, networkName)
+	if err != nil {
+		return "", fmt.Errorf("container %s: %w", c.ShortID(), err)
+	}
+
+	host := internalHost(settings, strings.TrimPrefix(inspect.Container.Name, "/"), c.ShortID())
+	if host == "" {
+		return "", fmt.Errorf("container %s has no alias, name or IP address on network %q", c.ShortID(), name)
+	}
+
+	hostPort := net.JoinHostPort(host, strconv.Itoa(int(port.Num())))
+	if proto == "" {
+		return hostPort, nil
+	}
+
+	return proto + "://" + hostPort, nil
+}
+
+// PeerEndpoint gets the proto://host:port string to reach a container port from the peer container,
+// using the first user-defined network, by name, both containers are attached to.
+// It returns an error wrapping [ErrNoSharedNetwork] if they share no user-defined network.
+func (c *Container) PeerEndpoint(ctx context.Context, peer *Container, port network.Port, proto string) (string, error) {
+	networks, err := c.Networks(ctx)
+	if err != nil {
+		return "", fmt.Errorf("networks: %w", err)
+	}
+
+	peerNetworks, err := peer.Networks(ctx)
+	if err != nil {
+		return "", fmt.Errorf("peer networks: %w", err)
+	}
+
+	slices.Sort(networks)
+	for _, name := range networks {
+		if slices.Contains(defaultNetworks, name) || !slices.Contains(peerNetworks, name) {
+			continue
+		}
+
+		return c.InternalEndpoint(ctx, name, port, proto)
+	}
+
+	return "", fmt.Errorf("%w: between containers %s and %s", ErrNoSharedNetwork, c.ShortID(), peer.ShortID())
+}
+
+// userDefinedNetwork returns the user-defined network matching the given name or ID.
+// If networkName is empty, it returns the only user-defined network.
+func userDefinedNetwork(networks map[string]*network.EndpointSettings, networkName string) (string, *network.EndpointSettings, error) {
+	if networkName != "" {
+		for name, settings := range networks {
+			if name != networkName && (settings == nil || settings.NetworkID != networkName) {
+				continue
+			}
+
+			if slices.Contains(defaultNetworks, name) {
+				return "", nil, fmt.Errorf("%w: %q is a default network, aliases are not resolved on it", ErrNoSharedNetwork, name)
+			}
+
+			if settings == nil {
+				settings = &network.EndpointSettings{}
+			}
+
+			return name, settings, nil
+		}
+
+		return "", nil, fmt.Errorf("%w: not attached to network %q", ErrNoSharedNetwork, networkName)
+	}
+
+	var candidates []string
+	for name := range networks {
+		if !slices.Contains(defaultNetworks, name) {
+			candidates = append(candidates, name)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", nil, fmt.Errorf("%w: not attached to any user-defined network", ErrNoSharedNetwork)
+	case 1:
+		settings := networks[candidates[0]]
+		if settings == nil {
+			settings = &network.EndpointSettings{}
+		}
+
+		return candidates[0], settings, nil
+	default:
+		slices.Sort(candidates)
+		return "", nil, fmt.Errorf("attached to multiple user-defined networks %v, a network name is required", candidates)
+	}
+}
+
+// internalHost returns the host to reach a container on a network: its first alias other than
+// its short ID, which the daemon adds automatically, its name, or its IP address.
+func internalHost(settings *network.EndpointSettings, name string, shortID string) string {
+	for _, alias := range settings.Aliases {
+		if alias != "" && alias != shortID {
+			return alias
+		}
+	}
+
+	if name != "" {
+		return name
+	}
+
+	if settings.IPAddress.IsValid() {
+		return settings.IPAddress.String()
+	}
+
+	return ""
+}
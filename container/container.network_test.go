@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"testing"
 
+	apinetwork "github.com/moby/moby/api/types/network"
 	"github.com/stretchr/testify/require"
 
 	"github.com/docker/go-sdk/client"
@@ -126,3 +127,76 @@ func TestContainer_NetworkAliases(t *testing.T) {
 		nw.Name(): {"ctr1-a", "ctr1-b", "ctr1-c"},
 	}, aliases)
 }
+
+func TestContainer_InternalEndpoint(t *testing.T) {
+	t.Run("new-network", func(t *testing.T) {
+		ctr, err := container.Run(
+			context.Background(),
+			container.WithImage(nginxAlpineImage),
+			container.WithNewNetwork(context.Background(), []string{"web"}),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		endpoint, err := ctr.InternalEndpoint(context.Background(), "", apinetwork.MustParsePort("80/tcp"), "http")
+		require.NoError(t, err)
+		require.Equal(t, "http://web:80", endpoint)
+
+		_, err = ctr.InternalEndpoint(context.Background(), "bridge", apinetwork.MustParsePort("80/tcp"), "http")
+		require.ErrorIs(t, err, container.ErrNoSharedNetwork)
+	})
+
+	t.Run("peers", func(t *testing.T) {
+		nw, err := network.New(context.Background())
+		network.Cleanup(t, nw)
+		require.NoError(t, err)
+
+		server, err := container.Run(
+			context.Background(),
+			container.WithImage(nginxAlpineImage),
+			container.WithNetwork([]string{"server"}, nw),
+		)
+		container.Cleanup(t, server)
+		require.NoError(t, err)
+
+		peer, err := container.Run(
+			context.Background(),
+			container.WithImage(nginxAlpineImage),
+			container.WithNetwork([]string{"peer"}, nw),
+		)
+		container.Cleanup(t, peer)
+		require.NoError(t, err)
+
+		endpoint, err := server.PeerEndpoint(context.Background(), peer, apinetwork.MustParsePort("80/tcp"), "")
+		require.NoError(t, err)
+		require.Equal(t, "server:80", endpoint)
+
+		endpoint, err = server.InternalEndpoint(context.Background(), nw.ID(), apinetwork.MustParsePort("80/tcp"), "")
+		require.NoError(t, err)
+		require.Equal(t, "server:80", endpoint)
+
+		code, _, err := peer.Exec(context.Background(), []string{"wget", "-q", "-O", "/dev/null", "http://" + endpoint})
+		require.NoError(t, err)
+		require.Zero(t, code)
+	})
+
+	t.Run("no-shared-network", func(t *testing.T) {
+		server, err := container.Run(
+			context.Background(),
+			container.WithImage(nginxAlpineImage),
+			container.WithNewNetwork(context.Background(), []string{"server"}),
+		)
+		container.Cleanup(t, server)
+		require.NoError(t, err)
+
+		peer, err := container.Run(
+			context.Background(),
+			container.WithImage(nginxAlpineImage),
+		)
+		container.Cleanup(t, peer)
+		require.NoError(t, err)
+
+		_, err = server.PeerEndpoint(context.Background(), peer, apinetwork.MustParsePort("80/tcp"), "")
+		require.ErrorIs(t, err, container.ErrNoSharedNetwork)
+	})
+}
@@ -30,6 +30,8 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 			return nil
 		},
 		def.validateMounts,
+		def.validateJob,
+		def.validateSidecars,
 	}
 
 	for _, opt := range opts {
@@ -42,6 +44,11 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 		return nil, fmt.Errorf("validate: %w", err)
 	}
 
+	// a health check without an explicit wait strategy waits for the container to be healthy
+	if def.healthCheck != nil && def.healthCheck.Test[0] != "NONE" && def.waitingFor == nil {
+		def.waitingFor = healthCheckWaitStrategy(def.healthCheck)
+	}
+
 	if def.dockerClient == nil {
 		sdk, err := client.New(ctx)
 		if err != nil {
@@ -82,17 +89,20 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 	defaultHooks = append(defaultHooks,
 		defaultPreCreateHook(def.dockerClient, dockerInput, hostConfig, networkingConfig),
 		defaultCopyFileToContainerHook(def.files),
-		defaultReadinessHook(),
 	)
 
+	// a job may have exited once started, so it's not waited for
+	if !def.job {
+		defaultHooks = append(defaultHooks, defaultReadinessHook())
+	}
+
 	// Combine with the original LifecycleHooks to avoid duplicate logging hooks.
 	origLifecycleHooks := def.lifecycleHooks
 	def.lifecycleHooks = []LifecycleHooks{
 		combineContainerHooks(defaultHooks, origLifecycleHooks),
 	}
 
-	err := def.creatingHook(ctx)
-	if err != nil {
+	if err := def.creatingHook(ctx); err != nil {
 		return nil, err
 	}
 
@@ -114,6 +124,24 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 	// as it could have been overridden in there.
 	dockerInput.Image = def.image
 
+	// the image is pulled once the creating hook has been called, so its entrypoint can be inspected.
+	if err := def.wrapDurableEntrypoint(ctx, dockerInput); err != nil {
+		return nil, err
+	}
+
+	if err := def.runInitContainers(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := def.pinHostPorts(ctx, dockerInput, hostConfig); err != nil {
+		return nil, err
+	}
+
+	hostAccess, err := def.setupHostAccess(ctx, hostConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := def.dockerClient.ContainerCreate(ctx, dockerclient.ContainerCreateOptions{
 		Config:           dockerInput,
 		HostConfig:       hostConfig,
@@ -122,7 +150,11 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 		Name:             def.name,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("container create: %w", err)
+		err = fmt.Errorf("container create: %w", err)
+		if hostAccess != nil {
+			err = errors.Join(err, hostAccess.stop(context.WithoutCancel(ctx)))
+		}
+		return nil, err
 	}
 
 	// This should match the fields set in ContainerFromDockerResponse.
@@ -130,11 +162,20 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 		dockerClient:   def.dockerClient,
 		containerID:    resp.ID,
 		shortID:        resp.ID[:12],
+		state:          StateCreated,
 		waitingFor:     def.waitingFor,
 		image:          def.image,
 		exposedPorts:   def.exposedPorts,
 		logger:         def.dockerClient.Logger(),
 		lifecycleHooks: def.lifecycleHooks,
+		volumes:        volumesToRemove(def.mounts),
+		crashHandlers:  def.crashHandlers,
+		hostAccess:     hostAccess,
+		hostPorts:      def.hostPorts,
+	}
+
+	for _, fn := range def.onCreated {
+		fn(ctr)
 	}
 
 	// Note: `ctr.dockerClient` is the same instance as `def.dockerClient`.
@@ -161,6 +202,8 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 				return ctr, fmt.Errorf("network connect: %w", err)
 			}
 		}
+
+		ctr.invalidateInspect()
 	}
 
 	if err = ctr.createdHook(ctx); err != nil {
@@ -172,6 +215,10 @@ func Run(ctx context.Context, opts ...ContainerCustomizer) (*Container, error) {
 		if err := ctr.Start(ctx); err != nil {
 			return ctr, fmt.Errorf("start container: %w", err)
 		}
+
+		if err := ctr.runSidecars(ctx, def.sidecars); err != nil {
+			return ctr, err
+		}
 	}
 
 	return ctr, nil
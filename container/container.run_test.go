@@ -3,7 +3,8 @@ package container_test
 import (
 	"bytes"
 	"context"
-	_ "embed"
+	"embed"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -428,6 +429,99 @@ echo "done"
 		require.Equal(t, "nobody|hello world|/tmp\n", string(out))
 	})
 
+	t.Run("with-durable-startup-entrypoint", func(t *testing.T) {
+		// The wrapper runs the dispatcher on every start, including a
+		// restart, and then execs the image's command (nginx), which
+		// keeps PID 1.
+		ctx := context.Background()
+
+		c, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithDurableStartupCommand(exec.NewRawCommand(
+				[]string{"sh", "-c", `echo started >> /tmp/starts`},
+			)),
+			container.WithDurableStartupEntrypoint(),
+			container.WithWaitStrategy(wait.ForListeningPort(apinetwork.MustParsePort("80/tcp"))),
+		)
+		container.Cleanup(t, c)
+		require.NoError(t, err)
+
+		inspect, err := c.Inspect(ctx)
+		require.NoError(t, err)
+		require.Equal(t, container.DurableStartupEntrypointPath, inspect.Container.Config.Entrypoint[0])
+
+		readStarts := func() string {
+			_, r, err := c.Exec(ctx, []string{"cat", "/tmp/starts"}, exec.Multiplexed())
+			require.NoError(t, err)
+			out, err := io.ReadAll(r)
+			require.NoError(t, err)
+			return string(out)
+		}
+		require.Equal(t, "started\n", readStarts())
+
+		require.NoError(t, c.Stop(ctx))
+		require.NoError(t, c.Start(ctx))
+		require.Equal(t, "started\nstarted\n", readStarts())
+
+		_, r, err := c.Exec(ctx, []string{"cat", "/proc/1/comm"}, exec.Multiplexed())
+		require.NoError(t, err)
+		comm, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "nginx\n", string(comm))
+	})
+
+	t.Run("with-durable-startup-report-and-run-once", func(t *testing.T) {
+		ctx := context.Background()
+
+		c, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithDurableStartupCommandsFromDir("migrations", exec.NewRawCommand(
+				[]string{"sh", "-c", `echo migrated >> /tmp/migrations; echo applied >&2`},
+			)),
+			container.WithDurableStartupRunOnce("migrations"),
+			container.WithDurableStartupEntrypoint(),
+			container.WithWaitStrategy(wait.ForListeningPort(apinetwork.MustParsePort("80/tcp"))),
+		)
+		container.Cleanup(t, c)
+		require.NoError(t, err)
+
+		report, err := c.DurableStartupReport(ctx)
+		require.NoError(t, err)
+		require.True(t, report.Finished())
+		require.Zero(t, report.ExitCode)
+		require.Len(t, report.Scripts, 1)
+		require.Equal(t, "migrations", report.Scripts[0].Namespace)
+		require.Equal(t, "applied\n", report.Scripts[0].StderrTail)
+
+		require.NoError(t, c.Stop(ctx))
+		require.NoError(t, c.Start(ctx))
+
+		report, err = c.DurableStartupReport(ctx)
+		require.NoError(t, err)
+		require.Empty(t, report.Scripts)
+		require.Equal(t, []string{"migrations"}, report.Skipped)
+
+		_, r, err := c.Exec(ctx, []string{"cat", "/tmp/migrations"}, exec.Multiplexed())
+		require.NoError(t, err)
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "migrated\n", string(out))
+	})
+
+	t.Run("without-durable-startup-report", func(t *testing.T) {
+		ctx := context.Background()
+
+		c, err := container.Run(ctx,
+			container.WithImage(alpineLatest),
+			container.WithEntrypoint("tail", "-f", "/dev/null"),
+		)
+		container.Cleanup(t, c)
+		require.NoError(t, err)
+
+		_, err = c.DurableStartupReport(ctx)
+		require.ErrorIs(t, err, container.ErrDurableStartupReportNotFound)
+	})
+
 	t.Run("with-durable-startup-command-missing-user-fails-clearly", func(t *testing.T) {
 		// Failure-propagation contract: if `su` can't switch to the
 		// requested user, the dispatcher's set -e causes a non-zero
@@ -710,6 +804,9 @@ func TestRun_addSDKLabels(t *testing.T) {
 //go:embed testdata/hello.sh
 var helloBytes []byte
 
+//go:embed testdata
+var testdataFS embed.FS
+
 func TestRun_withFiles(t *testing.T) {
 	t.Run("created-container/file", func(t *testing.T) {
 		ctx, cnl := context.WithTimeout(context.Background(), 30*time.Second)
@@ -763,6 +860,66 @@ func TestRun_withFiles(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("created-container/fs", func(t *testing.T) {
+		ctx, cnl := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cnl()
+
+		ctr, err := container.Run(ctx,
+			container.WithImage(bashImage),
+			container.WithFiles(container.File{
+				FS:            testdataFS,
+				FSPath:        "testdata",
+				ContainerPath: "/scripts",
+				Mode:          0o700,
+				UID:           1000,
+				GID:           1000,
+			}),
+			container.WithCmd("bash", "/scripts/hello.sh"),
+			container.WithWaitStrategy(wait.ForLog("done")),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		rc, err := ctr.CopyFromContainer(ctx, "/scripts/hello.sh")
+		require.NoError(t, err)
+		defer rc.Close()
+
+		bs, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.Equal(t, helloBytes, bs)
+	})
+
+	t.Run("started-container/template", func(t *testing.T) {
+		ctx, cnl := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cnl()
+
+		ctr, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithExposedPorts("80/tcp"),
+			container.WithEnv(map[string]string{"MODE": "test"}),
+			container.WithFiles(container.File{
+				Template:      `{{ .Name }} {{ .Env.MODE }} {{ index .Ports "80/tcp" }}`,
+				ContainerPath: "/app.conf",
+				Mode:          0o644,
+				AfterStart:    true,
+			}),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		data, err := ctr.TemplateContext(ctx)
+		require.NoError(t, err)
+		require.NotZero(t, data.Ports["80/tcp"])
+
+		rc, err := ctr.CopyFromContainer(ctx, "/app.conf")
+		require.NoError(t, err)
+		defer rc.Close()
+
+		bs, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s test %d", data.Name, data.Ports["80/tcp"]), string(bs))
+	})
+
 	t.Run("running-container/file", func(t *testing.T) {
 		ctx, cnl := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cnl()
@@ -820,6 +977,53 @@ func TestRun_withFiles(t *testing.T) {
 		err = ctr.CopyDirToContainer(ctx, dataDirectory, "/scripts/", 0o700)
 		require.NoError(t, err)
 	})
+
+	t.Run("running-container/directory-round-trip", func(t *testing.T) {
+		ctx, cnl := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cnl()
+
+		src := filepath.Join(t.TempDir(), "data")
+		require.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "sub", "hello.txt"), []byte("hello"), 0o640))
+		require.NoError(t, os.WriteFile(filepath.Join(src, "skipped.log"), []byte("log"), 0o644))
+		require.NoError(t, os.Symlink("sub/hello.txt", filepath.Join(src, "link")))
+
+		ctr, err := container.Run(ctx,
+			container.WithImage(bashImage),
+			container.WithCmd("sleep", "infinity"),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		err = ctr.CopyDirToContainerWithOptions(ctx, src, "/data", 0o700,
+			container.CopyPreserveModes(),
+			container.CopyOwner(1000, 1000),
+			container.CopyPreserveSymlinks(),
+			container.CopyExclude("*.log"),
+		)
+		require.NoError(t, err)
+
+		code, r, err := ctr.Exec(ctx, []string{"stat", "-c", "%a %u:%g", "/data/sub/hello.txt"}, exec.Multiplexed())
+		require.NoError(t, err)
+		require.Zero(t, code)
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "640 1000:1000\n", string(out))
+
+		dst := t.TempDir()
+		require.NoError(t, ctr.CopyDirFromContainer(ctx, "/data", dst))
+
+		content, err := os.ReadFile(filepath.Join(dst, "sub", "hello.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(content))
+
+		target, err := os.Readlink(filepath.Join(dst, "link"))
+		require.NoError(t, err)
+		require.Equal(t, "sub/hello.txt", target)
+
+		_, err = os.Stat(filepath.Join(dst, "skipped.log"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
 }
 
 func TestRunWithLifecycleHooks(t *testing.T) {
@@ -0,0 +1,278 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultRunAllConcurrency is the number of definitions [RunAll] runs at the same time by default.
+const defaultRunAllConcurrency = 4
+
+// RunPhase is the phase of [Run] a definition failed in, see [RunAllError].
+type RunPhase string
+
+const (
+	// RunPhaseSetup is the phase customizing and validating the definition, and connecting to the daemon.
+	RunPhaseSetup RunPhase = "setup"
+
+	// RunPhaseCreate is the phase pulling the image and creating the container, including its create hooks.
+	RunPhaseCreate RunPhase = "create"
+
+	// RunPhaseStart is the phase starting the container and waiting for it to be ready, including its start hooks.
+	RunPhaseStart RunPhase = "start"
+)
+
+// RunAllError is the error of a definition run by [RunAll].
+type RunAllError struct {
+	// Index the index of the definition, in the order passed to [RunAll].
+	Index int
+
+	// Phase the phase the definition failed in.
+	Phase RunPhase
+
+	// Err the error returned by [Run].
+	Err error
+}
+
+// Error returns the error message, identifying the definition and the phase.
+func (e *RunAllError) Error() string {
+	return fmt.Sprintf("definition %d failed in %s phase: %v", e.Index, e.Phase, e.Err)
+}
+
+// Unwrap returns the error returned by [Run].
+func (e *RunAllError) Unwrap() error {
+	return e.Err
+}
+
+// RunAllOption is a type that represents an option for [RunAll].
+type RunAllOption func(*runAllOptions)
+
+// runAllOptions holds the options for [RunAll].
+type runAllOptions struct {
+	concurrency int
+}
+
+// RunAllConcurrency returns a RunAllOption that sets the maximum number of definitions run at the same time.
+// A value lower than 1 runs all of them at the same time.
+// Default: 4.
+func RunAllConcurrency(n int) RunAllOption {
+	return func(o *runAllOptions) {
+		o.concurrency = n
+	}
+}
+
+// RunAll runs independent containers concurrently, each one defined by the customizers passed to [Run],
+// and returns them in the order of the definitions. Definitions using the same image, platform,
+// daemon and [WithAlwaysPull] setting share a single pull of the image, even with different clients,
+// unless they set pull options, e.g. [WithPullHandler].
+//
+// On the first failure, the definitions not started yet are skipped, the ones running are cancelled,
+// and all the containers already created are terminated. The returned error joins a [*RunAllError]
+// for each failed definition, identifying the phase it failed in, and the errors of the rollback.
+// Definitions cancelled because of another failure are not reported.
+func RunAll(ctx context.Context, defs [][]ContainerCustomizer, opts ...RunAllOption) ([]*Container, error) {
+	options := runAllOptions{concurrency: defaultRunAllConcurrency}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	concurrency := options.concurrency
+	if concurrency < 1 || concurrency > len(defs) {
+		concurrency = len(defs)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		pulls = &pullGroup{}
+		sem   = make(chan struct{}, concurrency)
+		wg    sync.WaitGroup
+		mtx   sync.Mutex
+
+		containers = make([]*Container, len(defs))
+		errs       []error
+		failed     bool
+	)
+
+	for i, def := range defs {
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			phase := RunPhaseSetup
+			runOpts := make([]ContainerCustomizer, 0, len(def)+1)
+			runOpts = append(runOpts, def...)
+			// applied last, so the hooks are kept if the definition sets its own ones
+			runOpts = append(runOpts, CustomizeDefinitionOption(func(d *Definition) error {
+				d.pulls = pulls
+				d.lifecycleHooks = append(d.lifecycleHooks, runAllPhaseHooks(&mtx, &phase))
+				return nil
+			}))
+
+			ctr, err := Run(runCtx, runOpts...)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			containers[i] = ctr
+			if err == nil {
+				return
+			}
+
+			// the definitions cancelled by the rollback are not reported
+			if !failed || !errors.Is(err, context.Canceled) {
+				errs = append(errs, &RunAllError{Index: i, Phase: phase, Err: err})
+			}
+			failed = true
+			cancel()
+		}()
+	}
+
+	wg.Wait()
+
+	if !failed && ctx.Err() == nil {
+		return containers, nil
+	}
+
+	if !failed {
+		errs = append(errs, ctx.Err())
+	}
+
+	// roll back, terminating the containers already created
+	for i, ctr := range containers {
+		if ctr == nil {
+			continue
+		}
+		if err := ctr.Terminate(context.WithoutCancel(ctx)); err != nil {
+			errs = append(errs, fmt.Errorf("terminate container of definition %d: %w", i, err))
+		}
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// runAllPhaseHooks returns the lifecycle hooks recording the phase of a definition run by [RunAll].
+// The pre-create hook runs before the image is pulled, and the pre-start hook before the container
+// is started.
+func runAllPhaseHooks(mtx *sync.Mutex, phase *RunPhase) LifecycleHooks {
+	setPhase := func(p RunPhase) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		*phase = p
+	}
+
+	return LifecycleHooks{
+		PreCreates: []DefinitionHook{
+			func(_ context.Context, _ *Definition) error {
+				setPhase(RunPhaseCreate)
+				return nil
+			},
+		},
+		PreStarts: []ContainerHook{
+			func(_ context.Context, _ ContainerInfo) error {
+				setPhase(RunPhaseStart)
+				return nil
+			},
+		},
+	}
+}
+
+// pullGroup de-duplicates the image pulls of the definitions run together:
+// a pull is done once per key, and its result is shared by all the definitions.
+// A nil pullGroup runs every pull.
+type pullGroup struct {
+	mtx   sync.Mutex
+	pulls map[pullKey]*pullCall
+}
+
+// pullKey identifies the pulls which can be shared: the ones of the same image and platform,
+// done on the same daemon with the same always pull setting.
+type pullKey struct {
+	// daemon the address of the daemon pulling the image. The definitions without their own
+	// client get a new one each, so the clients are not compared.
+	daemon string
+
+	// image the image to pull.
+	image string
+
+	// platform the platform of the image to pull.
+	platform string
+
+	// always whether the image is pulled even if it's present.
+	always bool
+}
+
+// pullCall is a pull in progress or done.
+type pullCall struct {
+	done chan struct{}
+	err  error
+}
+
+// pullKey returns the key of the pull of the definition, and whether it can be shared. Pulls
+// with pull options are not shared, as the handlers and credentials of each definition must be used.
+func (d *Definition) pullKey() (pullKey, bool) {
+	if len(d.pullOptions) > 0 {
+		return pullKey{}, false
+	}
+
+	return pullKey{
+		daemon:   d.dockerClient.DaemonHost(),
+		image:    d.image,
+		platform: d.imagePlatform,
+		always:   d.alwaysPullImage,
+	}, true
+}
+
+// do calls pull once for the given key, waiting for the pull in progress if any,
+// and returns its error. Waiting stops when ctx is done, and a pull interrupted by
+// the context of another caller is done again with the context of the waiting one.
+func (g *pullGroup) do(ctx context.Context, key pullKey, pull func() error) error {
+	if g == nil {
+		return pull()
+	}
+
+	g.mtx.Lock()
+	if g.pulls == nil {
+		g.pulls = map[pullKey]*pullCall{}
+	}
+	if call, ok := g.pulls[key]; ok {
+		g.mtx.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if isContextError(call.err) && ctx.Err() == nil {
+			return pull()
+		}
+		return call.err
+	}
+
+	call := &pullCall{done: make(chan struct{})}
+	g.pulls[key] = call
+	g.mtx.Unlock()
+
+	call.err = pull()
+	close(call.done)
+
+	return call.err
+}
+
+// isContextError returns whether err is caused by a cancelled or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,113 @@
+package container_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/events"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/client"
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+func TestRunAll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		ctrs, err := container.RunAll(context.Background(), [][]container.ContainerCustomizer{
+			{container.WithImage(nginxAlpineImage)},
+			{container.WithImage(alpineLatest), container.WithCmd("sleep", "300")},
+			{container.WithImage(alpineLatest), container.WithCmd("sleep", "300")},
+		}, container.RunAllConcurrency(2))
+		for _, ctr := range ctrs {
+			container.Cleanup(t, ctr)
+		}
+		require.NoError(t, err)
+		require.Len(t, ctrs, 3)
+
+		for _, ctr := range ctrs {
+			require.True(t, ctr.IsRunning())
+		}
+
+		inspect, err := ctrs[0].Inspect(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, nginxAlpineImage, inspect.Container.Config.Image)
+	})
+
+	t.Run("shared-pull", func(t *testing.T) {
+		cli, err := client.New(context.Background())
+		require.NoError(t, err)
+		defer cli.Close()
+
+		since := time.Now()
+
+		// each definition without its own client gets a new one
+		def := []container.ContainerCustomizer{
+			container.WithImage(alpineLatest),
+			container.WithCmd("sleep", "300"),
+			container.WithAlwaysPull(),
+		}
+		ctrs, err := container.RunAll(context.Background(), [][]container.ContainerCustomizer{def, def, def}, container.RunAllConcurrency(0))
+		for _, ctr := range ctrs {
+			container.Cleanup(t, ctr)
+		}
+		require.NoError(t, err)
+
+		result := cli.Events(context.Background(), dockerclient.EventsListOptions{
+			Since: strconv.FormatInt(since.Unix(), 10),
+			Until: strconv.FormatInt(time.Now().Add(time.Second).Unix(), 10),
+			Filters: make(dockerclient.Filters).
+				Add("type", string(events.ImageEventType)).
+				Add("event", string(events.ActionPull)).
+				Add("image", alpineLatest),
+		})
+
+		var pulls int
+		for done := false; !done; {
+			select {
+			case <-result.Messages:
+				pulls++
+			case err := <-result.Err:
+				require.ErrorIs(t, err, io.EOF)
+				done = true
+			}
+		}
+		require.Equal(t, 1, pulls)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		var created []*container.Container
+		recordCreated := container.WithAdditionalLifecycleHooks(container.LifecycleHooks{
+			PostCreates: []container.ContainerHook{
+				func(_ context.Context, info container.ContainerInfo) error {
+					created = append(created, info.(*container.Container))
+					return nil
+				},
+			},
+		})
+
+		ctrs, err := container.RunAll(context.Background(), [][]container.ContainerCustomizer{
+			{container.WithImage(nginxAlpineImage), recordCreated},
+			{
+				container.WithImage(alpineLatest),
+				container.WithCmd("sleep", "300"),
+				container.WithWaitStrategy(wait.ForLog("never printed").WithTimeout(2 * time.Second)),
+			},
+		}, container.RunAllConcurrency(1))
+		require.Nil(t, ctrs)
+
+		var runErr *container.RunAllError
+		require.ErrorAs(t, err, &runErr)
+		require.Equal(t, 1, runErr.Index)
+		require.Equal(t, container.RunPhaseStart, runErr.Phase)
+		require.False(t, errors.Is(err, context.Canceled))
+
+		require.Len(t, created, 1)
+		require.Equal(t, container.StateRemoved, created[0].LifecycleState())
+	})
+}
@@ -0,0 +1,224 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdkclient "github.com/docker/go-sdk/client"
+)
+
+func TestRunAllError(t *testing.T) {
+	cause := errors.New("image is required")
+	err := &RunAllError{Index: 2, Phase: RunPhaseSetup, Err: cause}
+
+	require.EqualError(t, err, "definition 2 failed in setup phase: image is required")
+	require.ErrorIs(t, err, cause)
+}
+
+func TestRunAll_failures(t *testing.T) {
+	failing := CustomizeDefinitionOption(func(_ *Definition) error {
+		return errors.New("customize failed")
+	})
+
+	t.Run("no-definitions", func(t *testing.T) {
+		ctrs, err := RunAll(context.Background(), nil)
+		require.NoError(t, err)
+		require.Empty(t, ctrs)
+	})
+
+	t.Run("setup-errors", func(t *testing.T) {
+		ctrs, err := RunAll(context.Background(), [][]ContainerCustomizer{
+			{},
+			{WithImage("alpine:latest"), failing},
+		}, RunAllConcurrency(0))
+		require.Error(t, err)
+		require.Nil(t, ctrs)
+
+		var indexes []int
+		for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+			var runErr *RunAllError
+			require.ErrorAs(t, e, &runErr)
+			require.Equal(t, RunPhaseSetup, runErr.Phase)
+			indexes = append(indexes, runErr.Index)
+		}
+		require.ElementsMatch(t, []int{0, 1}, indexes)
+		require.ErrorContains(t, err, "image is required")
+		require.ErrorContains(t, err, "customize failed")
+	})
+
+	t.Run("skips-after-failure", func(t *testing.T) {
+		var customized atomic.Bool
+		_, err := RunAll(context.Background(), [][]ContainerCustomizer{
+			{failing},
+			{CustomizeDefinitionOption(func(_ *Definition) error {
+				customized.Store(true)
+				return nil
+			})},
+		}, RunAllConcurrency(1))
+
+		var runErr *RunAllError
+		require.ErrorAs(t, err, &runErr)
+		require.Equal(t, 0, runErr.Index)
+		require.False(t, customized.Load())
+	})
+
+	t.Run("cancelled-context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := RunAll(ctx, [][]ContainerCustomizer{{WithImage("alpine:latest")}})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestPullGroup(t *testing.T) {
+	alpine := pullKey{image: "alpine:latest"}
+
+	t.Run("deduplicates", func(t *testing.T) {
+		var (
+			g      pullGroup
+			pulls  atomic.Int32
+			wg     sync.WaitGroup
+			errs   = make([]error, 8)
+			cause  = errors.New("pull failed")
+			unlock = make(chan struct{})
+		)
+
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = g.do(context.Background(), alpine, func() error {
+					pulls.Add(1)
+					<-unlock
+					return cause
+				})
+			}()
+		}
+		close(unlock)
+		wg.Wait()
+
+		require.Equal(t, int32(1), pulls.Load())
+		for _, err := range errs {
+			require.ErrorIs(t, err, cause)
+		}
+
+		require.NoError(t, g.do(context.Background(), pullKey{image: "nginx:alpine"}, func() error { return nil }))
+		require.Equal(t, int32(1), pulls.Load())
+	})
+
+	t.Run("different-keys", func(t *testing.T) {
+		var (
+			g     pullGroup
+			pulls int
+		)
+		for _, key := range []pullKey{
+			alpine,
+			{image: "alpine:latest", always: true},
+			{image: "alpine:latest", platform: "linux/arm64"},
+			{image: "alpine:latest", daemon: "tcp://127.0.0.1:2375"},
+		} {
+			require.NoError(t, g.do(context.Background(), key, func() error {
+				pulls++
+				return nil
+			}))
+		}
+		require.Equal(t, 4, pulls)
+	})
+
+	t.Run("waiter-cancelled", func(t *testing.T) {
+		var g pullGroup
+		unlock := make(chan struct{})
+		defer close(unlock)
+
+		started := make(chan struct{})
+		go func() {
+			_ = g.do(context.Background(), alpine, func() error {
+				close(started)
+				<-unlock
+				return nil
+			})
+		}()
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.ErrorIs(t, g.do(ctx, alpine, func() error { return nil }), context.Canceled)
+	})
+
+	t.Run("pulling-cancelled", func(t *testing.T) {
+		var g pullGroup
+		require.ErrorIs(t, g.do(context.Background(), alpine, func() error { return context.Canceled }), context.Canceled)
+
+		// the pull interrupted by another caller is done again
+		var pulled bool
+		require.NoError(t, g.do(context.Background(), alpine, func() error {
+			pulled = true
+			return nil
+		}))
+		require.True(t, pulled)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var g *pullGroup
+		var pulls int
+		for range 2 {
+			require.NoError(t, g.do(context.Background(), alpine, func() error {
+				pulls++
+				return nil
+			}))
+		}
+		require.Equal(t, 2, pulls)
+	})
+}
+
+func TestDefinition_pullKey(t *testing.T) {
+	fake := &lifecycleFakeClient{}
+
+	def := Definition{dockerClient: fake, image: "alpine:latest", imagePlatform: "linux/amd64"}
+	require.NoError(t, WithAlwaysPull()(&def))
+
+	key, shared := def.pullKey()
+	require.True(t, shared)
+	require.Equal(t, pullKey{daemon: "unix:///var/run/docker.sock", image: "alpine:latest", platform: "linux/amd64", always: true}, key)
+
+	require.NoError(t, WithPullHandler(func(io.ReadCloser) error { return nil })(&def))
+	_, shared = def.pullKey()
+	require.False(t, shared)
+}
+
+// uncomparableFakeClient is a fake SDK client which can't be compared, nor used as a map key.
+type uncomparableFakeClient struct {
+	*lifecycleFakeClient
+
+	hosts []string
+}
+
+func TestPullGroup_clients(t *testing.T) {
+	// the definitions without their own client get a new one each
+	var (
+		g     pullGroup
+		pulls int
+	)
+	for _, cli := range []sdkclient.SDKClient{
+		&lifecycleFakeClient{},
+		&lifecycleFakeClient{},
+		uncomparableFakeClient{lifecycleFakeClient: &lifecycleFakeClient{}},
+	} {
+		def := Definition{dockerClient: cli, image: "alpine:latest"}
+		key, shared := def.pullKey()
+		require.True(t, shared)
+
+		require.NoError(t, g.do(context.Background(), key, func() error {
+			pulls++
+			return nil
+		}))
+	}
+	require.Equal(t, 1, pulls)
+}
@@ -0,0 +1,105 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// WithSidecars appends the containers started once the container is started, sharing its network
+// namespace, e.g. a proxy or a log shipper. Each sidecar is defined by the customizers passed
+// to [Run], using the Docker client of the container unless it sets its own one; add
+// [WithSharedVolumes] to also mount the volumes of the container.
+//
+// As they share its network namespace, sidecars reach the container on localhost, and they
+// must not define networks or exposed ports: ports are exposed by the container. Wait strategies
+// of sidecars can't rely on mapped ports either.
+//
+// Sidecars are started in order, after the container is ready, and are terminated with it,
+// see [Container.Sidecars]. They are not restarted when the container is. Sidecars require
+// the container to be started by [Run], so they are not supported with [WithNoStart] or [RunJob].
+func WithSidecars(defs ...[]ContainerCustomizer) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.sidecars = append(def.sidecars, defs...)
+		return nil
+	}
+}
+
+// WithSharedVolumes mounts the volumes of the container a sidecar is attached to in the sidecar,
+// at the same paths. It's only valid in the customizers of a sidecar, see [WithSidecars].
+func WithSharedVolumes() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.sharedVolumes = true
+		return nil
+	}
+}
+
+// validateSidecars validates that the sidecars can be started.
+func (d *Definition) validateSidecars() error {
+	if len(d.sidecars) > 0 && !d.started {
+		return errors.New("sidecars require the container to be started")
+	}
+
+	if d.sharedVolumes && d.sidecarOf == "" {
+		return errors.New("shared volumes are only supported by sidecars")
+	}
+
+	return nil
+}
+
+// Sidecars returns the sidecars of the container, in the order they were started.
+func (c *Container) Sidecars() []*Container {
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	return slices.Clone(c.sidecars)
+}
+
+// runSidecars starts the sidecars of the container, in order. The started sidecars are kept
+// in the container, even on error, so they are terminated with it.
+func (c *Container) runSidecars(ctx context.Context, defs [][]ContainerCustomizer) error {
+	for i, opts := range defs {
+		sidecarOpts := make([]ContainerCustomizer, 0, len(opts)+2)
+		sidecarOpts = append(sidecarOpts, WithClient(c.dockerClient))
+		sidecarOpts = append(sidecarOpts, opts...)
+		sidecarOpts = append(sidecarOpts, CustomizeDefinitionOption(func(def *Definition) error {
+			def.sidecarOf = c.ID()
+			return WithAdditionalHostConfigModifier(func(hostConfig *container.HostConfig) {
+				hostConfig.NetworkMode = container.NetworkMode("container:" + c.ID())
+				if def.sharedVolumes {
+					hostConfig.VolumesFrom = append(hostConfig.VolumesFrom, c.ID())
+				}
+			})(def)
+		}))
+
+		sidecar, err := Run(ctx, sidecarOpts...)
+		if sidecar != nil {
+			c.sidecars = append(c.sidecars, sidecar)
+		}
+		if err != nil {
+			return fmt.Errorf("sidecar %d: %w", i, err)
+		}
+
+		c.logger.Info("Sidecar started", "containerID", c.ShortID(), "sidecarID", sidecar.ShortID())
+	}
+
+	return nil
+}
+
+// terminateSidecars terminates the sidecars of the container, in reverse order.
+func (c *Container) terminateSidecars(ctx context.Context) error {
+	sidecars := c.sidecars
+	c.sidecars = nil
+
+	var errs []error
+	for _, sidecar := range slices.Backward(sidecars) {
+		if err := sidecar.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminate sidecar %s: %w", sidecar.ShortID(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
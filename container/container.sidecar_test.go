@@ -0,0 +1,104 @@
+package container_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/exec"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+func TestRun_initContainers(t *testing.T) {
+	t.Run("seed-volume", func(t *testing.T) {
+		ctx := context.Background()
+		volumeName := fmt.Sprintf("init-containers-%d", time.Now().UnixNano())
+
+		seed := func(line string) []container.ContainerCustomizer {
+			return []container.ContainerCustomizer{
+				container.WithImage(alpineLatest),
+				container.WithCmd("sh", "-c", "echo "+line+" >> /data/seed"),
+				container.WithMounts(container.VolumeMount{Name: volumeName, ContainerPath: "/data"}),
+			}
+		}
+
+		ctr, err := container.Run(ctx,
+			container.WithImage(alpineLatest),
+			container.WithEntrypoint("tail", "-f", "/dev/null"),
+			container.WithMounts(container.VolumeMount{Name: volumeName, ContainerPath: "/data", RemoveOnTerminate: true}),
+			container.WithInitContainers(seed("first"), seed("second")),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		_, r, err := ctr.Exec(ctx, []string{"cat", "/data/seed"}, exec.Multiplexed())
+		require.NoError(t, err)
+		out, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "first\nsecond\n", string(out))
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		ctr, err := container.Run(context.Background(),
+			container.WithImage(alpineLatest),
+			container.WithEntrypoint("tail", "-f", "/dev/null"),
+			container.WithInitContainers(
+				[]container.ContainerCustomizer{
+					container.WithImage(alpineLatest),
+					container.WithCmd("sh", "-c", "echo migration failed >&2; exit 4"),
+				},
+			),
+		)
+		require.Nil(t, ctr)
+
+		var initErr *container.InitContainerError
+		require.ErrorAs(t, err, &initErr)
+		require.Zero(t, initErr.Index)
+		require.Equal(t, 4, initErr.ExitCode)
+		require.Equal(t, "migration failed\n", initErr.Stderr)
+	})
+}
+
+func TestRun_sidecars(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx,
+		container.WithImage(nginxAlpineImage),
+		container.WithWaitStrategy(wait.ForLog("start worker processes")),
+		container.WithSidecars(
+			[]container.ContainerCustomizer{
+				container.WithImage(alpineLatest),
+				container.WithEntrypoint("tail", "-f", "/dev/null"),
+				container.WithSharedVolumes(),
+			},
+		),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	sidecars := ctr.Sidecars()
+	require.Len(t, sidecars, 1)
+	sidecar := sidecars[0]
+
+	// the sidecar reaches the container on localhost
+	code, r, err := sidecar.Exec(ctx, []string{"wget", "-q", "-O", "-", "http://localhost:80"}, exec.Multiplexed())
+	require.NoError(t, err)
+	require.Zero(t, code)
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "Welcome to nginx")
+
+	inspect, err := sidecar.Inspect(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "container:"+ctr.ID(), string(inspect.Container.HostConfig.NetworkMode))
+	require.Equal(t, []string{ctr.ID()}, inspect.Container.HostConfig.VolumesFrom)
+
+	require.NoError(t, ctr.Terminate(ctx))
+	require.Equal(t, container.StateRemoved, sidecar.LifecycleState())
+	require.Empty(t, ctr.Sidecars())
+}
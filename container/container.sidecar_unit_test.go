@@ -0,0 +1,71 @@
+package container
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainer_terminateSidecars(t *testing.T) {
+	ctr, _ := newLifecycleTestContainer(StateRunning)
+	first, _ := newLifecycleTestContainer(StateRunning)
+	second, _ := newLifecycleTestContainer(StateRunning)
+	ctr.sidecars = []*Container{first, second}
+
+	var order []string
+	for name, c := range map[string]*Container{"main": ctr, "first": first, "second": second} {
+		c.OnStateChange(func(_ *Container, _ LifecycleState, to LifecycleState) {
+			if to == StateRemoved {
+				order = append(order, name)
+			}
+		})
+	}
+
+	require.Len(t, ctr.Sidecars(), 2)
+	require.NoError(t, ctr.Terminate(context.Background()))
+
+	require.Equal(t, []string{"second", "first", "main"}, order)
+	require.Empty(t, ctr.Sidecars())
+}
+
+func TestRun_validateSidecars(t *testing.T) {
+	sidecar := []ContainerCustomizer{WithImage("nginx:alpine")}
+
+	t.Run("not-started", func(t *testing.T) {
+		_, err := Run(context.Background(), WithImage("alpine:latest"), WithSidecars(sidecar), WithNoStart())
+		require.ErrorContains(t, err, "sidecars require the container to be started")
+	})
+
+	t.Run("job", func(t *testing.T) {
+		_, err := RunJob(context.Background(), WithImage("alpine:latest"), WithSidecars(sidecar))
+		require.ErrorContains(t, err, "sidecars require the container to be started")
+	})
+
+	t.Run("shared-volumes-without-sidecar", func(t *testing.T) {
+		_, err := Run(context.Background(), WithImage("alpine:latest"), WithSharedVolumes())
+		require.ErrorContains(t, err, "shared volumes are only supported by sidecars")
+	})
+}
+
+func TestInitContainerError(t *testing.T) {
+	err := &InitContainerError{Index: 1, ExitCode: 2}
+	require.EqualError(t, err, "init container 1 exited with code 2")
+
+	err.Stderr = "no such table"
+	require.EqualError(t, err, "init container 1 exited with code 2: no such table")
+}
+
+func TestWithSidecars_append(t *testing.T) {
+	proxy := []ContainerCustomizer{WithImage("nginx:alpine")}
+	shipper := []ContainerCustomizer{WithImage("alpine:latest")}
+
+	def := Definition{}
+	require.NoError(t, WithSidecars(proxy)(&def))
+	require.NoError(t, WithSidecars(shipper)(&def))
+	require.Len(t, def.sidecars, 2)
+
+	require.NoError(t, WithInitContainers(proxy)(&def))
+	require.NoError(t, WithInitContainers(shipper, proxy)(&def))
+	require.Len(t, def.initContainers, 3)
+}
@@ -0,0 +1,527 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/api/types/network"
+	dockerclient "github.com/moby/moby/client"
+
+	"github.com/docker/go-sdk/client"
+)
+
+const (
+	// snapshotRepository the repository of the snapshot images.
+	snapshotRepository = "docker-go-sdk-snapshot"
+
+	// LabelSnapshot is the label of snapshot images, holding the name of the snapshot.
+	LabelSnapshot = client.LabelBase + ".snapshot"
+
+	// LabelSnapshotContainer is the label of snapshot images, holding the ID of the snapshotted container.
+	LabelSnapshotContainer = client.LabelBase + ".snapshot.container"
+)
+
+// ErrSnapshotNotFound is returned when restoring a snapshot which was not taken.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// errSnapshotSidecars is returned when snapshotting or restoring a container with sidecars,
+// which would be left sharing the network namespace of the removed container.
+var errSnapshotSidecars = errors.New("containers with sidecars can't be snapshotted")
+
+// snapshotNameRegexp is the format of snapshot names, which are used in image tags.
+var snapshotNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,63}$`)
+
+// snapshotHelperEntrypoint the entrypoint of the helper containers used to copy the content of volumes.
+// They are never started, it's only set so they can be created from images without a command.
+var snapshotHelperEntrypoint = []string{"true"}
+
+// snapshot is a snapshot of the container, taken with [Container.Snapshot].
+type snapshot struct {
+	// imageID the ID of the image the filesystem of the container was committed to.
+	imageID string
+
+	// volumes the names of the volumes holding the content of the volumes of the container, by mount destination.
+	volumes map[string]string
+}
+
+// Snapshot commits the filesystem of the container to an image labelled with [LabelSnapshot]
+// and [LabelSnapshotContainer], so the container can be restored later to that state with [Container.Restore],
+// e.g. to reset a seeded database between tests. The content of the anonymous volumes mounted in the container,
+// including the ones declared by the image, is copied to volumes with the same labels. Named volumes,
+// e.g. mounted with [WithMounts], may be shared with other containers, so they are not part of the snapshot.
+// The container is paused while it's snapshotted. Taking a snapshot with the name of an existing one replaces it.
+//
+// Containers with sidecars, see [WithSidecars], can't be snapshotted. Snapshot images and volumes
+// are removed when the container is terminated.
+func (c *Container) Snapshot(ctx context.Context, name string) (err error) {
+	if !snapshotNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q: it must match %s", name, snapshotNameRegexp)
+	}
+
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	if len(c.sidecars) > 0 {
+		return errSnapshotSidecars
+	}
+
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+
+	destinations := volumeDestinations(inspect.Container, c.restoredVolumes)
+
+	// the volumes are copied after the commit, so the container stays paused in between.
+	state := inspect.Container.State
+	if len(destinations) > 0 && state != nil && state.Running && !state.Paused {
+		if _, err := c.dockerClient.ContainerPause(ctx, c.ID(), dockerclient.ContainerPauseOptions{}); err != nil {
+			return fmt.Errorf("container pause: %w", err)
+		}
+		defer func() {
+			if _, errUnpause := c.dockerClient.ContainerUnpause(ctx, c.ID(), dockerclient.ContainerUnpauseOptions{}); errUnpause != nil {
+				err = errors.Join(err, fmt.Errorf("container unpause: %w", errUnpause))
+			}
+		}()
+	}
+
+	resp, err := c.dockerClient.ContainerCommit(ctx, c.ID(), dockerclient.ContainerCommitOptions{
+		Reference: fmt.Sprintf("%s:%s-%s", snapshotRepository, c.ShortID(), name),
+		Comment:   "snapshot " + name + " of container " + c.ID(),
+		Changes: []string{
+			fmt.Sprintf("LABEL %q=%q %q=%q", LabelSnapshot, name, LabelSnapshotContainer, c.ID()),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("container commit: %w", err)
+	}
+
+	c.snapshotMtx.Lock()
+	if !slices.Contains(c.snapshotImages, resp.ID) {
+		c.snapshotImages = append(c.snapshotImages, resp.ID)
+	}
+	c.snapshotMtx.Unlock()
+
+	snap := snapshot{imageID: resp.ID, volumes: map[string]string{}}
+	for _, destination := range destinations {
+		volume, err := c.createSnapshotVolume(ctx, name)
+		if err != nil {
+			return err
+		}
+		snap.volumes[destination] = volume
+
+		c.snapshotMtx.Lock()
+		c.snapshotVolumes = append(c.snapshotVolumes, volume)
+		c.snapshotMtx.Unlock()
+
+		if err := c.copyToVolume(ctx, c.ID(), destination, resp.ID, volume, name); err != nil {
+			return fmt.Errorf("snapshot volume %s: %w", destination, err)
+		}
+	}
+
+	c.snapshotMtx.Lock()
+	if c.snapshots == nil {
+		c.snapshots = map[string]snapshot{}
+	}
+	c.snapshots[name] = snap
+	c.snapshotMtx.Unlock()
+
+	c.logger.Info("Container snapshot taken", "containerID", c.ShortID(), "snapshot", name, "imageID", resp.ID, "volumes", len(snap.volumes))
+
+	return nil
+}
+
+// Restore recreates the container from a snapshot taken with [Container.Snapshot].
+// The current container is removed, including its anonymous volumes, and replaced with a new one,
+// created from the snapshot image with the same name, configuration, network aliases and mounts,
+// so the ID of the container changes. The volumes of the snapshot are not mounted: new volumes
+// are mounted instead of the anonymous volumes of the container, with a copy of their content,
+// so the snapshot can be restored again. Named volumes stay mounted as they are, keeping their
+// current content, so the container keeps sharing them with other containers. Ports are bound to the same host ports when they are still available,
+// or to the originally requested ones otherwise.
+//
+// If the container was running, the new container is started, calling the start hooks
+// and waiting for it to be ready. Restoring a snapshot which was not taken returns
+// an error wrapping [ErrSnapshotNotFound].
+func (c *Container) Restore(ctx context.Context, name string) error {
+	c.snapshotMtx.Lock()
+	snap, ok := c.snapshots[name]
+	c.snapshotMtx.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	if c.LifecycleState() == StateRemoved {
+		return &StateTransitionError{From: StateRemoved, To: StateCreated}
+	}
+
+	if len(c.sidecars) > 0 {
+		return errSnapshotSidecars
+	}
+
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+
+	// the content of the snapshot volumes is copied to new volumes,
+	// mounted in place of the volumes of the container.
+	volumes := make(map[string]string, len(snap.volumes))
+	for destination := range snap.volumes {
+		volume, err := c.createSnapshotVolume(ctx, name)
+		if err != nil {
+			return errors.Join(err, c.removeVolumes(ctx, slices.Collect(maps.Values(volumes))))
+		}
+		volumes[destination] = volume
+	}
+
+	wasRunning := c.IsRunning()
+	if wasRunning {
+		if err := c.stop(ctx); err != nil {
+			return errors.Join(fmt.Errorf("stop: %w", err), c.removeVolumes(ctx, slices.Collect(maps.Values(volumes))))
+		}
+	}
+
+	options := restoreCreateOptions(inspect.Container, snap.imageID, c.ShortID())
+	restoreVolumeMounts(options.HostConfig, volumes)
+
+	_, err = c.dockerClient.ContainerRemove(ctx, c.ID(), dockerclient.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return errors.Join(fmt.Errorf("container remove: %w", err), c.removeVolumes(ctx, slices.Collect(maps.Values(volumes))))
+	}
+	c.invalidateInspect()
+
+	// the volumes of the previous restore were only used by the removed container.
+	errVolumes := c.removeVolumes(ctx, c.restoredVolumes)
+	c.restoredVolumes = slices.Collect(maps.Values(volumes))
+
+	resp, err := c.dockerClient.ContainerCreate(ctx, options)
+	if err != nil && inspect.Container.HostConfig != nil {
+		// the host ports could have been taken in the meantime
+		c.logger.Debug("failed to restore the host ports, using the requested ones", "containerID", c.ShortID(), "error", err)
+		options.HostConfig.PortBindings = inspect.Container.HostConfig.PortBindings
+		resp, err = c.dockerClient.ContainerCreate(ctx, options)
+	}
+	if err != nil {
+		// the container is gone
+		return errors.Join(fmt.Errorf("container create: %w", err), errVolumes, c.transition(StateRemoved))
+	}
+
+	c.replaceContainer(resp.ID)
+
+	// the new container is not started yet, so its volumes can be filled in.
+	for _, destination := range slices.Sorted(maps.Keys(snap.volumes)) {
+		if err := c.copyFromVolume(ctx, snap.imageID, snap.volumes[destination], destination, c.ID(), name); err != nil {
+			return errors.Join(fmt.Errorf("restore volume %s: %w", destination, err), errVolumes)
+		}
+	}
+
+	c.logger.Info("Container restored", "containerID", c.ShortID(), "snapshot", name)
+
+	if wasRunning {
+		if err := c.start(ctx); err != nil {
+			return errors.Join(fmt.Errorf("start: %w", err), errVolumes)
+		}
+	}
+
+	return errVolumes
+}
+
+// volumeDestinations returns the sorted destinations of the volumes of the inspected container
+// which are snapshotted: the anonymous ones, and the ones mounted by the last restore.
+func volumeDestinations(inspect container.InspectResponse, restored []string) []string {
+	named := namedVolumes(inspect.HostConfig)
+
+	var destinations []string
+	for _, mp := range inspect.Mounts {
+		if mp.Type != mount.TypeVolume {
+			continue
+		}
+		if named[mp.Name] && !slices.Contains(restored, mp.Name) {
+			continue
+		}
+		destinations = append(destinations, mp.Destination)
+	}
+	slices.Sort(destinations)
+
+	return destinations
+}
+
+// namedVolumes returns the names of the volumes mounted by name in the host config.
+// The other volumes of a container are anonymous.
+func namedVolumes(hostConfig *container.HostConfig) map[string]bool {
+	named := map[string]bool{}
+	if hostConfig == nil {
+		return named
+	}
+
+	for _, bind := range hostConfig.Binds {
+		source, _, ok := strings.Cut(bind, ":")
+		// sources which are paths of the host are bind mounts
+		if ok && source != "" && !strings.ContainsAny(source, `/\`) && !isDriveLetter(source) {
+			named[source] = true
+		}
+	}
+	for _, m := range hostConfig.Mounts {
+		if m.Type == mount.TypeVolume && m.Source != "" {
+			named[m.Source] = true
+		}
+	}
+
+	return named
+}
+
+// restoreVolumeMounts replaces the mounts of the host config at the destinations of the volumes
+// with the volumes, by mount destination. The binds and mounts of the host config are not modified in place.
+func restoreVolumeMounts(hostConfig *container.HostConfig, volumes map[string]string) {
+	if len(volumes) == 0 {
+		return
+	}
+
+	hostConfig.Binds = slices.DeleteFunc(slices.Clone(hostConfig.Binds), func(bind string) bool {
+		target, err := parseBindTarget(bind)
+		_, ok := volumes[target]
+		return err == nil && ok
+	})
+	hostConfig.Mounts = slices.DeleteFunc(slices.Clone(hostConfig.Mounts), func(m mount.Mount) bool {
+		_, ok := volumes[m.Target]
+		return ok
+	})
+
+	for _, destination := range slices.Sorted(maps.Keys(volumes)) {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:          mount.TypeVolume,
+			Source:        volumes[destination],
+			Target:        destination,
+			VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+		})
+	}
+}
+
+// createSnapshotVolume creates a volume labelled with the snapshot name.
+func (c *Container) createSnapshotVolume(ctx context.Context, name string) (string, error) {
+	resp, err := c.dockerClient.VolumeCreate(ctx, dockerclient.VolumeCreateOptions{
+		Labels: map[string]string{
+			LabelSnapshot:          name,
+			LabelSnapshotContainer: c.ID(),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("volume create: %w", err)
+	}
+
+	return resp.Volume.Name, nil
+}
+
+// copyToVolume copies the content mounted at the destination in a container to a volume,
+// mounted at the same destination in a helper container created from the image.
+func (c *Container) copyToVolume(ctx context.Context, containerID string, destination string, imageID string, volume string, name string) error {
+	helperID, err := c.createVolumeHelper(ctx, imageID, volume, destination, name)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(copyMountContent(ctx, c.dockerClient, containerID, helperID, destination), c.removeVolumeHelper(ctx, helperID))
+}
+
+// copyFromVolume copies the content of a volume, mounted at the destination in a helper container
+// created from the image, to the same destination in a container.
+func (c *Container) copyFromVolume(ctx context.Context, imageID string, volume string, destination string, containerID string, name string) error {
+	helperID, err := c.createVolumeHelper(ctx, imageID, volume, destination, name)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(copyMountContent(ctx, c.dockerClient, helperID, containerID, destination), c.removeVolumeHelper(ctx, helperID))
+}
+
+// createVolumeHelper creates a container from the image, mounting the volume at the destination,
+// so the content of the volume can be copied with the archive API. The container is never started.
+func (c *Container) createVolumeHelper(ctx context.Context, imageID string, volume string, destination string, name string) (string, error) {
+	resp, err := c.dockerClient.ContainerCreate(ctx, dockerclient.ContainerCreateOptions{
+		Config: &container.Config{
+			Image:      imageID,
+			Entrypoint: snapshotHelperEntrypoint,
+			Labels: map[string]string{
+				LabelSnapshot:          name,
+				LabelSnapshotContainer: c.ID(),
+			},
+		},
+		HostConfig: &container.HostConfig{
+			Mounts: []mount.Mount{{
+				Type:          mount.TypeVolume,
+				Source:        volume,
+				Target:        destination,
+				VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+			}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create volume helper: %w", err)
+	}
+
+	return resp.ID, nil
+}
+
+// removeVolumeHelper removes a helper container created by createVolumeHelper, including the anonymous
+// volumes declared by its image, but not the volume it mounts.
+func (c *Container) removeVolumeHelper(ctx context.Context, id string) error {
+	_, err := c.dockerClient.ContainerRemove(ctx, id, dockerclient.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("remove volume helper: %w", err)
+	}
+
+	return nil
+}
+
+// copyMountContent copies the content mounted at the destination from a container to another one,
+// extracting the archive in the parent directory so the content ends up in the mount.
+func copyMountContent(ctx context.Context, cli client.SDKClient, fromID string, toID string, destination string) error {
+	from, err := cli.CopyFromContainer(ctx, fromID, dockerclient.CopyFromContainerOptions{SourcePath: destination})
+	if err != nil {
+		return fmt.Errorf("copy from container: %w", err)
+	}
+	defer from.Content.Close()
+
+	_, err = cli.CopyToContainer(ctx, toID, dockerclient.CopyToContainerOptions{
+		DestinationPath: path.Dir(destination),
+		Content:         from.Content,
+	})
+	if err != nil {
+		return fmt.Errorf("copy to container: %w", err)
+	}
+
+	return nil
+}
+
+// restoreCreateOptions returns the options to recreate a container from a snapshot image,
+// with the configuration of the inspected container. The ports are bound to the host ports
+// of the inspected container, and the network aliases are kept, except the short ID of the container.
+func restoreCreateOptions(inspect container.InspectResponse, imageID string, shortID string) dockerclient.ContainerCreateOptions {
+	cfg := &container.Config{}
+	if inspect.Config != nil {
+		*cfg = *inspect.Config
+	}
+	cfg.Image = imageID
+
+	hostConfig := &container.HostConfig{}
+	if inspect.HostConfig != nil {
+		*hostConfig = *inspect.HostConfig
+	}
+
+	networkingConfig := &network.NetworkingConfig{}
+	if inspect.NetworkSettings != nil {
+		if len(inspect.NetworkSettings.Ports) > 0 {
+			bindings := network.PortMap{}
+			for port, pbs := range inspect.NetworkSettings.Ports {
+				if len(pbs) > 0 {
+					bindings[port] = slices.Clone(pbs)
+				}
+			}
+			if len(bindings) > 0 {
+				hostConfig.PortBindings = bindings
+			}
+		}
+
+		for _, nwName := range slices.Sorted(maps.Keys(inspect.NetworkSettings.Networks)) {
+			nw := inspect.NetworkSettings.Networks[nwName]
+			if nw == nil || slices.Contains(defaultNetworks, nwName) {
+				continue
+			}
+
+			if networkingConfig.EndpointsConfig == nil {
+				networkingConfig.EndpointsConfig = map[string]*network.EndpointSettings{}
+			}
+			networkingConfig.EndpointsConfig[nwName] = &network.EndpointSettings{
+				Aliases: slices.DeleteFunc(slices.Clone(nw.Aliases), func(alias string) bool {
+					return alias == shortID
+				}),
+				Links: nw.Links,
+			}
+		}
+	}
+
+	return dockerclient.ContainerCreateOptions{
+		Config:           cfg,
+		HostConfig:       hostConfig,
+		NetworkingConfig: networkingConfig,
+		Name:             strings.TrimPrefix(inspect.Name, "/"),
+	}
+}
+
+// replaceContainer replaces the Docker container the container refers to with a newly created one.
+// The IDs are replaced with the state, so they are consistent for concurrent readers.
+func (c *Container) replaceContainer(id string) {
+	c.stateMtx.Lock()
+	c.containerID = id
+	c.shortID = id[:12]
+	from := c.state
+	c.state = StateCreated
+	observers := slices.Clone(c.stateObservers)
+	c.stateMtx.Unlock()
+
+	c.invalidateInspect()
+
+	for _, observer := range observers {
+		observer(c, from, StateCreated)
+	}
+}
+
+// removeSnapshots removes the snapshot images and volumes of the container,
+// and the volumes mounted by the last restore.
+func (c *Container) removeSnapshots(ctx context.Context) error {
+	c.snapshotMtx.Lock()
+	images := c.snapshotImages
+	volumes := c.snapshotVolumes
+	c.snapshotImages = nil
+	c.snapshotVolumes = nil
+	c.snapshots = nil
+	c.snapshotMtx.Unlock()
+
+	var errs []error
+	for _, imageID := range images {
+		_, err := c.dockerClient.ImageRemove(ctx, imageID, dockerclient.ImageRemoveOptions{
+			Force:         true,
+			PruneChildren: true,
+		})
+		if err != nil && !errdefs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove snapshot image %s: %w", imageID, err))
+		}
+	}
+
+	errs = append(errs, c.removeVolumes(ctx, volumes), c.removeVolumes(ctx, c.restoredVolumes))
+	c.restoredVolumes = nil
+
+	return errors.Join(errs...)
+}
+
+// removeVolumes removes the volumes created for snapshots, ignoring the ones which are gone.
+func (c *Container) removeVolumes(ctx context.Context, volumes []string) error {
+	var errs []error
+	for _, volume := range volumes {
+		_, err := c.dockerClient.VolumeRemove(ctx, volume, dockerclient.VolumeRemoveOptions{Force: true})
+		if err != nil && !errdefs.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("remove snapshot volume %s: %w", volume, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+package container_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/network"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+)
+
+func TestContainer_Snapshot(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx,
+		container.WithImage(nginxAlpineImage),
+		container.WithExposedPorts("80/tcp"),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	port, err := ctr.MappedPort(ctx, network.MustParsePort("80/tcp"))
+	require.NoError(t, err)
+
+	code, _, err := ctr.Exec(ctx, []string{"sh", "-c", "echo seeded > /seed.txt"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	require.NoError(t, ctr.Snapshot(ctx, "seeded"))
+
+	code, _, err = ctr.Exec(ctx, []string{"sh", "-c", "echo dirty > /dirty.txt"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	previousID := ctr.ID()
+	require.NoError(t, ctr.Restore(ctx, "seeded"))
+	require.NotEqual(t, previousID, ctr.ID())
+	require.True(t, ctr.IsRunning())
+
+	restoredPort, err := ctr.MappedPort(ctx, network.MustParsePort("80/tcp"))
+	require.NoError(t, err)
+	require.Equal(t, port, restoredPort)
+
+	code, _, err = ctr.Exec(ctx, []string{"test", "-f", "/seed.txt"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	code, _, err = ctr.Exec(ctx, []string{"test", "-f", "/dirty.txt"})
+	require.NoError(t, err)
+	require.Equal(t, 1, code)
+
+	images, err := ctr.Client().ImageList(ctx, dockerclient.ImageListOptions{
+		Filters: make(dockerclient.Filters).Add("label", container.LabelSnapshot+"=seeded"),
+	})
+	require.NoError(t, err)
+	require.Len(t, images.Items, 1)
+	imageID := images.Items[0].ID
+
+	require.NoError(t, ctr.Terminate(ctx))
+
+	_, err = ctr.Client().ImageInspect(ctx, imageID)
+	require.True(t, errdefs.IsNotFound(err))
+}
+
+func TestContainer_Snapshot_volumes(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx,
+		container.WithImage(nginxAlpineImage),
+		container.WithMounts(
+			container.VolumeMount{ContainerPath: "/data"},
+			container.VolumeMount{Name: "snapshot-shared-" + strings.ToLower(t.Name()), ContainerPath: "/shared", RemoveOnTerminate: true},
+		),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	code, _, err := ctr.Exec(ctx, []string{"sh", "-c", "echo seeded > /data/seed.txt && chown nginx /data/seed.txt"})
+	require.NoError(t, err)
+	require.Zero(t, code)
+
+	snapshottedID := ctr.ID()
+	require.NoError(t, ctr.Snapshot(ctx, "seeded"))
+
+	for range 2 {
+		code, _, err = ctr.Exec(ctx, []string{"sh", "-c", "rm /data/seed.txt && echo dirty > /data/dirty.txt && echo kept >> /shared/kept.txt"})
+		require.NoError(t, err)
+		require.Zero(t, code)
+
+		require.NoError(t, ctr.Restore(ctx, "seeded"))
+
+		code, _, err = ctr.Exec(ctx, []string{"sh", "-c", `test "$(stat -c %U /data/seed.txt)" = nginx`})
+		require.NoError(t, err)
+		require.Zero(t, code)
+
+		code, _, err = ctr.Exec(ctx, []string{"test", "-f", "/data/dirty.txt"})
+		require.NoError(t, err)
+		require.Equal(t, 1, code)
+
+		// the named volume is not part of the snapshot
+		code, _, err = ctr.Exec(ctx, []string{"test", "-f", "/shared/kept.txt"})
+		require.NoError(t, err)
+		require.Zero(t, code)
+	}
+
+	volumes, err := ctr.Client().VolumeList(ctx, dockerclient.VolumeListOptions{
+		Filters: make(dockerclient.Filters).Add("label", container.LabelSnapshotContainer+"="+snapshottedID),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, volumes.Items)
+
+	require.NoError(t, ctr.Terminate(ctx))
+
+	for _, v := range volumes.Items {
+		_, err = ctr.Client().VolumeInspect(ctx, v.Name, dockerclient.VolumeInspectOptions{})
+		require.True(t, errdefs.IsNotFound(err))
+	}
+}
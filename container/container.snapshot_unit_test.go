@@ -0,0 +1,392 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/api/types/volume"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotFakeClient is a fake SDK client supporting the calls done to snapshot and restore a container.
+type snapshotFakeClient struct {
+	*lifecycleFakeClient
+
+	// mounts the mounts of the inspected container.
+	mounts []container.MountPoint
+
+	// hostConfig the host config of the inspected container.
+	hostConfig *container.HostConfig
+
+	mtx               sync.Mutex
+	commits           []dockerclient.ContainerCommitOptions
+	creates           []dockerclient.ContainerCreateOptions
+	removedContainers []string
+	removedImages     []string
+	createdVolumes    []dockerclient.VolumeCreateOptions
+	removedVolumes    []string
+	copies            [][3]string
+	pauses            []string
+}
+
+func (f *snapshotFakeClient) ContainerInspect(ctx context.Context, id string, options dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error) {
+	inspect, err := f.lifecycleFakeClient.ContainerInspect(ctx, id, options)
+	inspect.Container.Mounts = f.mounts
+	inspect.Container.HostConfig = f.hostConfig
+	return inspect, err
+}
+
+func (f *snapshotFakeClient) ContainerPause(_ context.Context, id string, _ dockerclient.ContainerPauseOptions) (dockerclient.ContainerPauseResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.pauses = append(f.pauses, "pause "+id)
+	return dockerclient.ContainerPauseResult{}, nil
+}
+
+func (f *snapshotFakeClient) ContainerUnpause(_ context.Context, id string, _ dockerclient.ContainerUnpauseOptions) (dockerclient.ContainerUnpauseResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.pauses = append(f.pauses, "unpause "+id)
+	return dockerclient.ContainerUnpauseResult{}, nil
+}
+
+func (f *snapshotFakeClient) VolumeCreate(_ context.Context, options dockerclient.VolumeCreateOptions) (dockerclient.VolumeCreateResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.createdVolumes = append(f.createdVolumes, options)
+	return dockerclient.VolumeCreateResult{Volume: volume.Volume{Name: fmt.Sprintf("volume-%d", len(f.createdVolumes))}}, nil
+}
+
+func (f *snapshotFakeClient) VolumeRemove(_ context.Context, volumeID string, _ dockerclient.VolumeRemoveOptions) (dockerclient.VolumeRemoveResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.removedVolumes = append(f.removedVolumes, volumeID)
+	return dockerclient.VolumeRemoveResult{}, nil
+}
+
+// CopyFromContainer returns the ID of the container and the path as content, recorded by CopyToContainer.
+func (f *snapshotFakeClient) CopyFromContainer(_ context.Context, containerID string, options dockerclient.CopyFromContainerOptions) (dockerclient.CopyFromContainerResult, error) {
+	return dockerclient.CopyFromContainerResult{
+		Content: io.NopCloser(strings.NewReader(containerID + ":" + options.SourcePath)),
+	}, nil
+}
+
+func (f *snapshotFakeClient) CopyToContainer(_ context.Context, containerID string, options dockerclient.CopyToContainerOptions) (dockerclient.CopyToContainerResult, error) {
+	content, err := io.ReadAll(options.Content)
+	if err != nil {
+		return dockerclient.CopyToContainerResult{}, err
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.copies = append(f.copies, [3]string{string(content), containerID, options.DestinationPath})
+	return dockerclient.CopyToContainerResult{}, nil
+}
+
+func (f *snapshotFakeClient) ContainerCommit(_ context.Context, _ string, options dockerclient.ContainerCommitOptions) (dockerclient.ContainerCommitResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.commits = append(f.commits, options)
+	return dockerclient.ContainerCommitResult{ID: "sha256:snapshot"}, nil
+}
+
+func (f *snapshotFakeClient) ContainerCreate(_ context.Context, options dockerclient.ContainerCreateOptions) (dockerclient.ContainerCreateResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.creates = append(f.creates, options)
+	if slices.Equal(options.Config.Entrypoint, snapshotHelperEntrypoint) {
+		return dockerclient.ContainerCreateResult{ID: fmt.Sprintf("helper%d", len(f.creates))}, nil
+	}
+	return dockerclient.ContainerCreateResult{ID: "abcdef1234567890restored"}, nil
+}
+
+func (f *snapshotFakeClient) ContainerRemove(_ context.Context, id string, _ dockerclient.ContainerRemoveOptions) (dockerclient.ContainerRemoveResult, error) {
+	f.removeCalls.Add(1)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.removedContainers = append(f.removedContainers, id)
+	return dockerclient.ContainerRemoveResult{}, nil
+}
+
+func (f *snapshotFakeClient) ImageRemove(_ context.Context, imageID string, _ dockerclient.ImageRemoveOptions) (dockerclient.ImageRemoveResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.removedImages = append(f.removedImages, imageID)
+	return dockerclient.ImageRemoveResult{}, nil
+}
+
+func TestContainer_SnapshotRestore(t *testing.T) {
+	ctr, lifecycleFake := newLifecycleTestContainer(StateRunning)
+	fake := &snapshotFakeClient{lifecycleFakeClient: lifecycleFake}
+	ctr.dockerClient = fake
+
+	t.Run("invalid-name", func(t *testing.T) {
+		require.Error(t, ctr.Snapshot(context.Background(), "not a tag"))
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		require.ErrorIs(t, ctr.Restore(context.Background(), "unknown"), ErrSnapshotNotFound)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, ctr.Snapshot(context.Background(), "seeded"))
+		require.Len(t, fake.commits, 1)
+		require.Equal(t, "docker-go-sdk-snapshot:1234567890ab-seeded", fake.commits[0].Reference)
+		require.Contains(t, fake.commits[0].Changes[0], `"com.docker.sdk.snapshot"="seeded"`)
+
+		var transitions [][2]LifecycleState
+		ctr.OnStateChange(func(_ *Container, from LifecycleState, to LifecycleState) {
+			transitions = append(transitions, [2]LifecycleState{from, to})
+		})
+
+		require.NoError(t, ctr.Restore(context.Background(), "seeded"))
+		require.Equal(t, "abcdef1234567890restored", ctr.ID())
+		require.Equal(t, "abcdef123456", ctr.ShortID())
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+		require.Equal(t, [][2]LifecycleState{
+			{StateRunning, StateStopping},
+			{StateStopping, StateStopped},
+			{StateStopped, StateCreated},
+			{StateCreated, StateStarting},
+			{StateStarting, StateRunning},
+		}, transitions)
+
+		require.Len(t, fake.creates, 1)
+		require.Equal(t, "sha256:snapshot", fake.creates[0].Config.Image)
+	})
+
+	t.Run("terminate", func(t *testing.T) {
+		require.NoError(t, ctr.Terminate(context.Background()))
+		require.Equal(t, int32(2), fake.removeCalls.Load())
+		require.Equal(t, []string{"sha256:snapshot"}, fake.removedImages)
+
+		require.ErrorIs(t, ctr.Restore(context.Background(), "seeded"), ErrSnapshotNotFound)
+	})
+}
+
+func TestContainer_SnapshotRestore_volumes(t *testing.T) {
+	ctr, lifecycleFake := newLifecycleTestContainer(StateRunning)
+	fake := &snapshotFakeClient{
+		lifecycleFakeClient: lifecycleFake,
+		mounts: []container.MountPoint{
+			{Type: mount.TypeVolume, Name: "0123456789abcdef", Destination: "/var/lib/data"},
+			{Type: mount.TypeVolume, Name: "shared", Destination: "/shared"},
+			{Type: mount.TypeBind, Source: "/host", Destination: "/config"},
+		},
+		// the named volume is shared with other containers, so it's kept as is
+		hostConfig: &container.HostConfig{Binds: []string{"shared:/shared", "/host:/config"}},
+	}
+	ctr.dockerClient = fake
+
+	t.Run("snapshot", func(t *testing.T) {
+		require.NoError(t, ctr.Snapshot(context.Background(), "seeded"))
+
+		// the container stays paused while its volumes are copied
+		require.Equal(t, []string{"pause 1234567890abcdefgh", "unpause 1234567890abcdefgh"}, fake.pauses)
+
+		require.Equal(t, []dockerclient.VolumeCreateOptions{{
+			Labels: map[string]string{
+				LabelSnapshot:          "seeded",
+				LabelSnapshotContainer: "1234567890abcdefgh",
+			},
+		}}, fake.createdVolumes)
+
+		// the snapshot volume is filled in through a helper container
+		require.Len(t, fake.creates, 1)
+		require.Equal(t, "sha256:snapshot", fake.creates[0].Config.Image)
+		require.Equal(t, []mount.Mount{{
+			Type:          mount.TypeVolume,
+			Source:        "volume-1",
+			Target:        "/var/lib/data",
+			VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+		}}, fake.creates[0].HostConfig.Mounts)
+		require.Equal(t, [][3]string{{"1234567890abcdefgh:/var/lib/data", "helper1", "/var/lib"}}, fake.copies)
+		require.Equal(t, []string{"helper1"}, fake.removedContainers)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.NoError(t, ctr.Restore(context.Background(), "seeded"))
+		require.Equal(t, "abcdef1234567890restored", ctr.ID())
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+
+		// the restored container mounts a copy of the snapshot volume
+		require.Len(t, fake.createdVolumes, 2)
+		require.Len(t, fake.creates, 3)
+		require.Equal(t, []mount.Mount{{
+			Type:          mount.TypeVolume,
+			Source:        "volume-2",
+			Target:        "/var/lib/data",
+			VolumeOptions: &mount.VolumeOptions{NoCopy: true},
+		}}, fake.creates[1].HostConfig.Mounts)
+		require.Equal(t, []string{"shared:/shared", "/host:/config"}, fake.creates[1].HostConfig.Binds)
+		require.Equal(t, "volume-1", fake.creates[2].HostConfig.Mounts[0].Source)
+		require.Equal(t, []string{"helper3:/var/lib/data", "abcdef1234567890restored", "/var/lib"}, fake.copies[1][:])
+		require.Equal(t, []string{"helper1", "1234567890abcdefgh", "helper3"}, fake.removedContainers)
+		require.Empty(t, fake.removedVolumes)
+	})
+
+	t.Run("restore-again", func(t *testing.T) {
+		require.NoError(t, ctr.Restore(context.Background(), "seeded"))
+
+		// the volume of the previous restore is removed with its container
+		require.Equal(t, []string{"volume-2"}, fake.removedVolumes)
+		require.Equal(t, "volume-3", fake.creates[3].HostConfig.Mounts[0].Source)
+	})
+
+	t.Run("terminate", func(t *testing.T) {
+		require.NoError(t, ctr.Terminate(context.Background()))
+		require.Equal(t, []string{"volume-2", "volume-1", "volume-3"}, fake.removedVolumes)
+	})
+}
+
+func TestContainer_SnapshotRestore_sidecars(t *testing.T) {
+	ctr, lifecycleFake := newLifecycleTestContainer(StateRunning)
+	fake := &snapshotFakeClient{lifecycleFakeClient: lifecycleFake}
+	ctr.dockerClient = fake
+	ctr.sidecars = []*Container{{}}
+	ctr.snapshots = map[string]snapshot{"seeded": {imageID: "sha256:snapshot"}}
+
+	require.ErrorIs(t, ctr.Snapshot(context.Background(), "seeded"), errSnapshotSidecars)
+	require.ErrorIs(t, ctr.Restore(context.Background(), "seeded"), errSnapshotSidecars)
+	require.Empty(t, fake.commits)
+	require.Empty(t, fake.creates)
+	require.Zero(t, fake.removeCalls.Load())
+}
+
+func TestVolumeDestinations(t *testing.T) {
+	inspect := container.InspectResponse{
+		HostConfig: &container.HostConfig{
+			Binds: []string{"named:/named", "/host:/host", "/anonymous-bind"},
+			Mounts: []mount.Mount{
+				{Type: mount.TypeVolume, Source: "mounted", Target: "/mounted"},
+				{Type: mount.TypeVolume, Target: "/anonymous-mount"},
+				{Type: mount.TypeVolume, Source: "restored", Target: "/restored"},
+			},
+		},
+		Mounts: []container.MountPoint{
+			{Type: mount.TypeVolume, Name: "named", Destination: "/named"},
+			{Type: mount.TypeBind, Source: "/host", Destination: "/host"},
+			{Type: mount.TypeVolume, Name: "a1", Destination: "/anonymous-bind"},
+			{Type: mount.TypeVolume, Name: "mounted", Destination: "/mounted"},
+			{Type: mount.TypeVolume, Name: "a2", Destination: "/anonymous-mount"},
+			{Type: mount.TypeVolume, Name: "restored", Destination: "/restored"},
+			{Type: mount.TypeVolume, Name: "a3", Destination: "/image-volume"},
+		},
+	}
+
+	require.Equal(t, []string{"/anonymous-bind", "/anonymous-mount", "/image-volume", "/restored"}, volumeDestinations(inspect, []string{"restored"}))
+}
+
+func TestRestoreVolumeMounts(t *testing.T) {
+	hostConfig := &container.HostConfig{
+		Binds: []string{"data:/var/lib/data", "/host:/config:ro"},
+		Mounts: []mount.Mount{
+			{Type: mount.TypeVolume, Source: "cache", Target: "/cache"},
+			{Type: mount.TypeTmpfs, Target: "/tmp"},
+		},
+	}
+	binds := hostConfig.Binds
+
+	restoreVolumeMounts(hostConfig, map[string]string{"/var/lib/data": "volume-1", "/cache": "volume-2"})
+
+	require.Equal(t, []string{"/host:/config:ro"}, hostConfig.Binds)
+	require.Equal(t, []mount.Mount{
+		{Type: mount.TypeTmpfs, Target: "/tmp"},
+		{Type: mount.TypeVolume, Source: "volume-2", Target: "/cache", VolumeOptions: &mount.VolumeOptions{NoCopy: true}},
+		{Type: mount.TypeVolume, Source: "volume-1", Target: "/var/lib/data", VolumeOptions: &mount.VolumeOptions{NoCopy: true}},
+	}, hostConfig.Mounts)
+
+	// the binds of the inspected container are not modified
+	require.Equal(t, []string{"data:/var/lib/data", "/host:/config:ro"}, binds)
+}
+
+func TestRestoreCreateOptions(t *testing.T) {
+	inspect := container.InspectResponse{
+		Name:   "/db",
+		Config: &container.Config{Image: "postgres:16", Env: []string{"POSTGRES_PASSWORD=secret"}},
+		HostConfig: &container.HostConfig{
+			NetworkMode: "backend",
+			PortBindings: network.PortMap{
+				network.MustParsePort("5432/tcp"): {{HostPort: ""}},
+			},
+		},
+		NetworkSettings: &container.NetworkSettings{
+			Ports: network.PortMap{
+				network.MustParsePort("5432/tcp"): {{HostIP: netip.IPv4Unspecified(), HostPort: "32768"}},
+				network.MustParsePort("8080/tcp"): nil,
+			},
+			Networks: map[string]*network.EndpointSettings{
+				"backend": {Aliases: []string{"1234567890ab", "db"}},
+				"bridge":  {},
+			},
+		},
+	}
+
+	options := restoreCreateOptions(inspect, "sha256:snapshot", "1234567890ab")
+
+	require.Equal(t, "db", options.Name)
+	require.Equal(t, "sha256:snapshot", options.Config.Image)
+	require.Equal(t, []string{"POSTGRES_PASSWORD=secret"}, options.Config.Env)
+	require.Equal(t, container.NetworkMode("backend"), options.HostConfig.NetworkMode)
+	require.Equal(t, network.PortMap{
+		network.MustParsePort("5432/tcp"): {{HostIP: netip.IPv4Unspecified(), HostPort: "32768"}},
+	}, options.HostConfig.PortBindings)
+	require.Equal(t, map[string]*network.EndpointSettings{
+		"backend": {Aliases: []string{"db"}},
+	}, options.NetworkingConfig.EndpointsConfig)
+
+	// the inspected container is not modified
+	require.Equal(t, "postgres:16", inspect.Config.Image)
+	require.Equal(t, network.PortMap{network.MustParsePort("5432/tcp"): {{HostPort: ""}}}, inspect.HostConfig.PortBindings)
+}
+
+func TestContainer_replaceContainer_concurrent(t *testing.T) {
+	ctr, _ := newLifecycleTestContainer(StateStopped)
+
+	var (
+		wg       sync.WaitGroup
+		shortIDs []string
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			ctr.replaceContainer("abcdef1234567890restored")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			shortIDs = append(shortIDs, ctr.ShortID())
+		}
+	}()
+	wg.Wait()
+
+	for _, shortID := range shortIDs {
+		require.Contains(t, []string{"1234567890ab", "abcdef123456"}, shortID)
+	}
+
+	require.Equal(t, "abcdef1234567890restored", ctr.ID())
+	require.Equal(t, "abcdef123456", ctr.ShortID())
+	require.Equal(t, StateCreated, ctr.LifecycleState())
+}
@@ -3,33 +3,98 @@ package container
 import (
 	"context"
 	"fmt"
-
-	"github.com/moby/moby/client"
+	"slices"
 )
 
-// Start will start an already created container
+// Start will start an already created container.
+//
+// It moves the container to the [StateStarting] state, and to [StateRunning] once it's ready.
+// If crash handlers are registered, the container is watched once it's ready, see [Container.Watch].
+// Starting a removed container, or a container that is already running, returns
+// a [StateTransitionError].
 func (c *Container) Start(ctx context.Context) error {
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	return c.start(ctx)
+}
+
+// start starts the container. The caller must hold the lifecycle lock.
+func (c *Container) start(ctx context.Context) error {
+	from := c.LifecycleState()
+	if from == StateRunning {
+		// the container could have exited on its own since it was started
+		state, err := c.State(ctx)
+		if err != nil {
+			return fmt.Errorf("state: %w", err)
+		}
+		if state.Running {
+			return &StateTransitionError{From: from, To: StateStarting}
+		}
+
+		c.Running(false)
+		from = StateStopped
+	}
+
+	if err := c.transition(StateStarting); err != nil {
+		return err
+	}
+
 	err := c.startingHook(ctx)
 	if err != nil {
+		c.revertTransition(StateStarting, from)
 		return fmt.Errorf("starting hook: %w", err)
 	}
 
-	if _, err := c.dockerClient.ContainerStart(ctx, c.ID(), client.ContainerStartOptions{}); err != nil {
+	if err := c.containerStart(ctx); err != nil {
+		c.revertTransition(StateStarting, from)
 		return fmt.Errorf("container start: %w", err)
 	}
 	defer c.dockerClient.Close()
 
+	// starting the container changes its state and port bindings
+	c.invalidateInspect()
+
 	err = c.startedHook(ctx)
 	if err != nil {
 		return fmt.Errorf("started hook: %w", err)
 	}
 
-	c.isRunning = true
+	if err := c.transition(StateRunning); err != nil {
+		return err
+	}
 
 	err = c.readiedHook(ctx)
 	if err != nil {
 		return fmt.Errorf("readied hook: %w", err)
 	}
 
+	// the monitor must outlive the start context, it's stopped when the container is stopped
+	if c.hasCrashHandlers() {
+		if err := c.Watch(context.WithoutCancel(ctx)); err != nil {
+			return fmt.Errorf("watch: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// revertTransition moves the container back to the previous state after a failed
+// lifecycle operation, as long as no other transition happened in between.
+// The previous state is the one the operation started from, so it's not checked
+// against the valid transitions.
+func (c *Container) revertTransition(current LifecycleState, previous LifecycleState) {
+	c.stateMtx.Lock()
+	if c.state != current {
+		c.stateMtx.Unlock()
+		return
+	}
+
+	c.state = previous
+	observers := slices.Clone(c.stateObservers)
+	c.stateMtx.Unlock()
+
+	for _, observer := range observers {
+		observer(c, current, previous)
+	}
+}
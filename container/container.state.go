@@ -0,0 +1,156 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// LifecycleState is the state of a [Container] in its lifecycle, as tracked by the SDK.
+// It's different from the state reported by the Docker daemon, which is returned by [Container.State].
+type LifecycleState string
+
+const (
+	// StateCreated the container has been created, but not started yet.
+	StateCreated LifecycleState = "created"
+
+	// StateStarting the container is being started, and waiting to be ready.
+	StateStarting LifecycleState = "starting"
+
+	// StateRunning the container is running, and ready.
+	StateRunning LifecycleState = "running"
+
+	// StateStopping the container is being stopped.
+	StateStopping LifecycleState = "stopping"
+
+	// StateStopped the container has been stopped.
+	StateStopped LifecycleState = "stopped"
+
+	// StateRemoved the container has been terminated, and removed from the Docker daemon.
+	StateRemoved LifecycleState = "removed"
+)
+
+// ErrInvalidStateTransition is returned when a lifecycle operation is not allowed
+// in the current state of the container, e.g. starting a removed container.
+var ErrInvalidStateTransition = errors.New("invalid container state transition")
+
+// StateTransitionError is returned when a lifecycle operation is not allowed
+// in the current state of the container. It wraps [ErrInvalidStateTransition].
+type StateTransitionError struct {
+	// From the current state of the container.
+	From LifecycleState
+
+	// To the state the operation tried to move the container to.
+	To LifecycleState
+}
+
+// Error implements the error interface.
+func (e *StateTransitionError) Error() string {
+	return fmt.Sprintf("%s: from %q to %q", ErrInvalidStateTransition, e.From, e.To)
+}
+
+// Unwrap returns [ErrInvalidStateTransition].
+func (e *StateTransitionError) Unwrap() error {
+	return ErrInvalidStateTransition
+}
+
+// StateObserver is a function called after the lifecycle state of a container changes.
+// It's called synchronously, so it must not block, nor call the lifecycle methods of the container.
+type StateObserver func(ctr *Container, from LifecycleState, to LifecycleState)
+
+// validTransitions are the allowed transitions between lifecycle states, following the lifecycle
+// of a container: a container can only be started from the created or stopped states, and it
+// stops on its own or once stopped, e.g. when it fails to start. A stopped container is running
+// again when restarted by its restart policy.
+//
+// Moving to the removed state is always allowed, so terminating is possible whatever happened
+// to the container before. A failed lifecycle operation moves the container back to its previous
+// state, see [Container.revertTransition].
+var validTransitions = map[LifecycleState][]LifecycleState{
+	StateCreated:  {StateStarting, StateStopping},
+	StateStarting: {StateRunning, StateStopped, StateStopping},
+	StateRunning:  {StateStopping, StateStopped},
+	StateStopping: {StateStopped},
+	StateStopped:  {StateStarting, StateStopping, StateRunning},
+}
+
+// stateFromDocker returns the lifecycle state matching a state reported by the Docker daemon.
+func stateFromDocker(state string) LifecycleState {
+	switch state {
+	case "created":
+		return StateCreated
+	case "running", "paused", "restarting":
+		return StateRunning
+	case "removing":
+		return StateStopping
+	default:
+		// exited, dead
+		return StateStopped
+	}
+}
+
+// LifecycleState returns the lifecycle state of the container, as tracked by the SDK.
+// It's safe to call it concurrently with the lifecycle methods.
+func (c *Container) LifecycleState() LifecycleState {
+	c.stateMtx.RLock()
+	defer c.stateMtx.RUnlock()
+
+	return c.state
+}
+
+// OnStateChange registers an observer, called after every change of the container's lifecycle state.
+func (c *Container) OnStateChange(observer StateObserver) {
+	if observer == nil {
+		return
+	}
+
+	c.stateMtx.Lock()
+	defer c.stateMtx.Unlock()
+
+	c.stateObservers = append(c.stateObservers, observer)
+}
+
+// transition moves the container to the given state, returning a [StateTransitionError]
+// if the transition is not allowed. Moving to the current state is a no-op.
+func (c *Container) transition(to LifecycleState) error {
+	c.stateMtx.Lock()
+
+	from := c.state
+	if from == to {
+		c.stateMtx.Unlock()
+		return nil
+	}
+
+	if to != StateRemoved && !slices.Contains(validTransitions[from], to) {
+		c.stateMtx.Unlock()
+		return &StateTransitionError{From: from, To: to}
+	}
+
+	c.state = to
+	observers := slices.Clone(c.stateObservers)
+	c.stateMtx.Unlock()
+
+	for _, observer := range observers {
+		observer(c, from, to)
+	}
+
+	return nil
+}
+
+// IsRunning returns true if the lifecycle state of the container is [StateRunning].
+func (c *Container) IsRunning() bool {
+	return c.LifecycleState() == StateRunning
+}
+
+// Running sets the running state of the container, moving it to [StateRunning] if b is true,
+// or to [StateStopped] otherwise. It's a no-op once the container has been removed.
+func (c *Container) Running(b bool) {
+	to := StateStopped
+	if b {
+		to = StateRunning
+	}
+
+	if err := c.transition(to); err != nil {
+		c.logger.Debug("ignoring running state change", "containerID", c.ShortID(), "error", err)
+	}
+}
@@ -0,0 +1,270 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/client"
+)
+
+// lifecycleFakeClient is a fake SDK client supporting the calls
+// done by the lifecycle methods of a container.
+type lifecycleFakeClient struct {
+	client.SDKClient
+
+	running      atomic.Bool
+	removeCalls  atomic.Int32
+	stopCalls    atomic.Int32
+	startCalls   atomic.Int32
+	inspectCalls atomic.Int32
+}
+
+func (f *lifecycleFakeClient) Logger() *slog.Logger {
+	return slog.Default()
+}
+
+func (f *lifecycleFakeClient) Close() error {
+	return nil
+}
+
+func (f *lifecycleFakeClient) DaemonHost() string {
+	return "unix:///var/run/docker.sock"
+}
+
+func (f *lifecycleFakeClient) ContainerStart(_ context.Context, _ string, _ dockerclient.ContainerStartOptions) (dockerclient.ContainerStartResult, error) {
+	f.startCalls.Add(1)
+	f.running.Store(true)
+	return dockerclient.ContainerStartResult{}, nil
+}
+
+func (f *lifecycleFakeClient) ContainerStop(_ context.Context, _ string, _ dockerclient.ContainerStopOptions) (dockerclient.ContainerStopResult, error) {
+	f.stopCalls.Add(1)
+	f.running.Store(false)
+	return dockerclient.ContainerStopResult{}, nil
+}
+
+func (f *lifecycleFakeClient) ContainerRemove(_ context.Context, _ string, _ dockerclient.ContainerRemoveOptions) (dockerclient.ContainerRemoveResult, error) {
+	if f.removeCalls.Add(1) > 1 {
+		return dockerclient.ContainerRemoveResult{}, errdefs.ErrNotFound
+	}
+	return dockerclient.ContainerRemoveResult{}, nil
+}
+
+func (f *lifecycleFakeClient) ContainerInspect(_ context.Context, id string, _ dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error) {
+	f.inspectCalls.Add(1)
+	return dockerclient.ContainerInspectResult{
+		Container: container.InspectResponse{
+			ID:    id,
+			State: &container.State{Running: f.running.Load()},
+		},
+	}, nil
+}
+
+func newLifecycleTestContainer(state LifecycleState) (*Container, *lifecycleFakeClient) {
+	fake := &lifecycleFakeClient{}
+	fake.running.Store(state == StateRunning)
+
+	return &Container{
+		dockerClient: fake,
+		containerID:  "1234567890abcdefgh",
+		shortID:      "1234567890ab",
+		logger:       fake.Logger(),
+		state:        state,
+	}, fake
+}
+
+func TestContainer_lifecycleState(t *testing.T) {
+	t.Run("start-stop-start", func(t *testing.T) {
+		ctr, _ := newLifecycleTestContainer(StateCreated)
+
+		var transitions [][2]LifecycleState
+		ctr.OnStateChange(func(_ *Container, from LifecycleState, to LifecycleState) {
+			transitions = append(transitions, [2]LifecycleState{from, to})
+		})
+
+		require.NoError(t, ctr.Start(context.Background()))
+		require.True(t, ctr.IsRunning())
+
+		require.NoError(t, ctr.Stop(context.Background()))
+		require.False(t, ctr.IsRunning())
+
+		require.NoError(t, ctr.Start(context.Background()))
+		require.NoError(t, ctr.Terminate(context.Background()))
+		require.Equal(t, StateRemoved, ctr.LifecycleState())
+
+		require.Equal(t, [][2]LifecycleState{
+			{StateCreated, StateStarting},
+			{StateStarting, StateRunning},
+			{StateRunning, StateStopping},
+			{StateStopping, StateStopped},
+			{StateStopped, StateStarting},
+			{StateStarting, StateRunning},
+			{StateRunning, StateStopping},
+			{StateStopping, StateStopped},
+			{StateStopped, StateRemoved},
+		}, transitions)
+	})
+
+	t.Run("start-running", func(t *testing.T) {
+		ctr, fake := newLifecycleTestContainer(StateRunning)
+
+		err := ctr.Start(context.Background())
+		require.ErrorIs(t, err, ErrInvalidStateTransition)
+
+		var transitionErr *StateTransitionError
+		require.ErrorAs(t, err, &transitionErr)
+		require.Equal(t, StateRunning, transitionErr.From)
+		require.Equal(t, StateStarting, transitionErr.To)
+		require.Zero(t, fake.startCalls.Load())
+	})
+
+	t.Run("start-exited", func(t *testing.T) {
+		ctr, fake := newLifecycleTestContainer(StateRunning)
+		// the container exited on its own
+		fake.running.Store(false)
+
+		require.NoError(t, ctr.Start(context.Background()))
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+		require.Equal(t, int32(1), fake.startCalls.Load())
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		ctr, fake := newLifecycleTestContainer(StateRemoved)
+
+		require.ErrorIs(t, ctr.Start(context.Background()), ErrInvalidStateTransition)
+		require.ErrorIs(t, ctr.Stop(context.Background()), ErrInvalidStateTransition)
+		require.NoError(t, ctr.Terminate(context.Background()))
+		require.Zero(t, fake.removeCalls.Load())
+	})
+
+	t.Run("running-flag", func(t *testing.T) {
+		ctr, _ := newLifecycleTestContainer(StateStarting)
+
+		ctr.Running(true)
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+
+		ctr.Running(false)
+		require.Equal(t, StateStopped, ctr.LifecycleState())
+
+		ctr.state = StateRemoved
+		ctr.Running(true)
+		require.Equal(t, StateRemoved, ctr.LifecycleState())
+	})
+}
+
+func TestContainer_transition(t *testing.T) {
+	tests := []struct {
+		from  LifecycleState
+		to    LifecycleState
+		valid bool
+	}{
+		{from: StateCreated, to: StateStarting, valid: true},
+		{from: StateCreated, to: StateStopping, valid: true},
+		{from: StateCreated, to: StateRunning},
+		{from: StateCreated, to: StateStopped},
+		{from: StateStarting, to: StateRunning, valid: true},
+		{from: StateStarting, to: StateStopped, valid: true},
+		{from: StateStarting, to: StateStopping, valid: true},
+		{from: StateStarting, to: StateCreated},
+		{from: StateRunning, to: StateStopping, valid: true},
+		{from: StateRunning, to: StateStopped, valid: true},
+		{from: StateRunning, to: StateStarting},
+		{from: StateRunning, to: StateCreated},
+		{from: StateStopping, to: StateStopped, valid: true},
+		{from: StateStopping, to: StateStarting},
+		{from: StateStopping, to: StateRunning},
+		{from: StateStopping, to: StateCreated},
+		{from: StateStopped, to: StateStarting, valid: true},
+		{from: StateStopped, to: StateStopping, valid: true},
+		{from: StateStopped, to: StateRunning, valid: true},
+		{from: StateStopped, to: StateCreated},
+		{from: StateRemoved, to: StateCreated},
+		{from: StateRemoved, to: StateStarting},
+		{from: StateRemoved, to: StateRunning},
+		{from: StateRemoved, to: StateStopping},
+		{from: StateRemoved, to: StateStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"-"+string(tt.to), func(t *testing.T) {
+			ctr, _ := newLifecycleTestContainer(tt.from)
+
+			err := ctr.transition(tt.to)
+			if tt.valid {
+				require.NoError(t, err)
+				require.Equal(t, tt.to, ctr.LifecycleState())
+				return
+			}
+
+			var transitionErr *StateTransitionError
+			require.ErrorAs(t, err, &transitionErr)
+			require.Equal(t, &StateTransitionError{From: tt.from, To: tt.to}, transitionErr)
+			require.Equal(t, tt.from, ctr.LifecycleState())
+		})
+	}
+
+	t.Run("removed", func(t *testing.T) {
+		for _, from := range []LifecycleState{StateCreated, StateStarting, StateRunning, StateStopping, StateStopped} {
+			ctr, _ := newLifecycleTestContainer(from)
+			require.NoError(t, ctr.transition(StateRemoved))
+		}
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		ctr, _ := newLifecycleTestContainer(StateStopping)
+
+		// another transition happened in between
+		ctr.revertTransition(StateStarting, StateCreated)
+		require.Equal(t, StateStopping, ctr.LifecycleState())
+
+		ctr.revertTransition(StateStopping, StateRunning)
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+	})
+}
+
+func TestContainer_Terminate_concurrent(t *testing.T) {
+	ctr, fake := newLifecycleTestContainer(StateRunning)
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ctr.Terminate(context.Background())
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = ctr.IsRunning()
+			_, _ = ctr.Inspect(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), fake.removeCalls.Load())
+	require.Equal(t, int32(1), fake.stopCalls.Load())
+	require.Equal(t, StateRemoved, ctr.LifecycleState())
+}
+
+func TestStateFromDocker(t *testing.T) {
+	require.Equal(t, StateCreated, stateFromDocker("created"))
+	require.Equal(t, StateRunning, stateFromDocker("running"))
+	require.Equal(t, StateRunning, stateFromDocker("paused"))
+	require.Equal(t, StateStopping, stateFromDocker("removing"))
+	require.Equal(t, StateStopped, stateFromDocker("exited"))
+	require.Equal(t, StateStopped, stateFromDocker("dead"))
+}
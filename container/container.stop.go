@@ -61,12 +61,33 @@ func NewStopOptions(ctx context.Context, opts ...StopOption) *StopOptions {
 //   - [LifecycleHooks.PreStops]
 //   - [LifecycleHooks.PostStops]
 //
-// If the container is already stopped, the method is a no-op.
+// It moves the container to the [StateStopping] state, and to [StateStopped] once it's stopped.
+// The crash monitor of the container, if any, is stopped first, see [Container.Watch],
+// and so are the syncs of host directories, see [Container.Sync].
+// Stopping a removed container returns a [StateTransitionError].
 func (c *Container) Stop(ctx context.Context, opts ...StopOption) error {
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	return c.stop(ctx, opts...)
+}
+
+// stop stops the container. The caller must hold the lifecycle lock.
+func (c *Container) stop(ctx context.Context, opts ...StopOption) error {
 	stopOptions := NewStopOptions(ctx, opts...)
 
+	from := c.LifecycleState()
+	if err := c.transition(StateStopping); err != nil {
+		return err
+	}
+
+	// an exit requested by the SDK is not a crash
+	c.stopWatching()
+	c.stopSyncing()
+
 	err := c.stoppingHook(stopOptions.Context())
 	if err != nil {
+		c.revertTransition(StateStopping, from)
 		return fmt.Errorf("stopping hook: %w", err)
 	}
 
@@ -75,11 +96,19 @@ func (c *Container) Stop(ctx context.Context, opts ...StopOption) error {
 	timeoutSeconds := int(stopOptions.StopTimeout().Seconds())
 	options.Timeout = &timeoutSeconds
 
-	if _, err := c.dockerClient.ContainerStop(stopOptions.Context(), c.ID(), options); err != nil {
+	_, err = c.dockerClient.ContainerStop(stopOptions.Context(), c.ID(), options)
+
+	// a failed stop could have changed the state too
+	c.invalidateInspect()
+
+	if err != nil {
+		c.revertTransition(StateStopping, from)
 		return fmt.Errorf("container stop: %w", err)
 	}
 
-	c.isRunning = false
+	if err := c.transition(StateStopped); err != nil {
+		return err
+	}
 
 	err = c.stoppedHook(stopOptions.Context())
 	if err != nil {
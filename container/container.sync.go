@@ -0,0 +1,535 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+	"github.com/moby/patternmatcher"
+
+	"github.com/docker/go-sdk/container/exec"
+	"github.com/docker/go-sdk/image"
+)
+
+const (
+	// defaultSyncPollInterval the default interval between two scans of the synced host directory.
+	defaultSyncPollInterval = 500 * time.Millisecond
+
+	// defaultSyncDebounce the default time the host directory must be unchanged before syncing.
+	defaultSyncDebounce = 200 * time.Millisecond
+
+	// maxSyncRetryBackoff the maximum time between two attempts of a failed sync.
+	maxSyncRetryBackoff = 30 * time.Second
+)
+
+// SyncResult is the result of pushing the changes of a synced directory to the container.
+type SyncResult struct {
+	// Copied the slash-separated paths, relative to the synced directory,
+	// of the files and directories created or modified.
+	Copied []string
+
+	// Deleted the slash-separated paths, relative to the synced directory,
+	// of the files and directories deleted.
+	Deleted []string
+
+	// Err the error pushing the changes or running the post-sync command, if any.
+	Err error
+}
+
+// SyncOption is an option to customize how a directory is synced with [Container.Sync].
+type SyncOption func(*syncOptions)
+
+// syncOptions the options to sync a directory.
+type syncOptions struct {
+	pollInterval time.Duration
+	debounce     time.Duration
+	exclude      []string
+	postSync     []string
+	onSync       []func(SyncResult)
+}
+
+// SyncPollInterval sets the interval between two scans of the host directory, each one walking
+// the whole directory. Defaults to 500ms; a longer interval reduces the cost of syncing large
+// directories, at the expense of latency.
+func SyncPollInterval(interval time.Duration) SyncOption {
+	return func(o *syncOptions) {
+		o.pollInterval = interval
+	}
+}
+
+// SyncDebounce sets how long the host directory must be unchanged before its changes are pushed,
+// so a burst of changes, e.g. a branch checkout, is synced at once. Defaults to 200ms.
+func SyncDebounce(debounce time.Duration) SyncOption {
+	return func(o *syncOptions) {
+		o.debounce = debounce
+	}
+}
+
+// SyncExclude excludes the paths matching the patterns, in addition to the
+// patterns of the .dockerignore file of the host directory, using the same syntax.
+func SyncExclude(patterns ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// SyncPostCommand runs a command in the container after each sync, e.g. to reload the application.
+// The sync fails if the command exits with a non-zero exit code.
+func SyncPostCommand(cmd ...string) SyncOption {
+	return func(o *syncOptions) {
+		o.postSync = cmd
+	}
+}
+
+// SyncNotify calls fn after each sync, including the initial one, with its result.
+func SyncNotify(fn func(SyncResult)) SyncOption {
+	return func(o *syncOptions) {
+		o.onSync = append(o.onSync, fn)
+	}
+}
+
+// Syncer is a running sync of a host directory into a container, see [Container.Sync].
+type Syncer struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop stops syncing and waits for the running sync, if any, to complete.
+// It's safe to call it several times.
+func (s *Syncer) Stop() {
+	s.cancel()
+	<-s.done
+}
+
+// Done returns a channel closed once the syncer is stopped.
+func (s *Syncer) Done() <-chan struct{} {
+	return s.done
+}
+
+// Sync copies the content of a host directory into a directory of the running container,
+// creating it if needed, and then keeps pushing the changes of the host directory in the background,
+// without needing a bind mount, so it works against remote daemons too.
+//
+// Changes are detected by polling, not by file system notifications: every poll interval, the whole
+// host directory is walked, calling stat on each entry not excluded. The cost of a scan grows with
+// the number of files, so exclude large trees, e.g. node_modules, with the .dockerignore file or
+// [SyncExclude], or lower the frequency of the scans with [SyncPollInterval].
+//
+// Once the host directory is unchanged for the debounce time, the created
+// and modified entries are copied as a single tar archive, preserving their modes and symbolic links,
+// and the deleted entries are removed by running rm in the container, which requires it to provide
+// the mkdir and rm commands. The patterns of the .dockerignore file of the host directory, read once
+// when the sync starts, exclude paths from the sync, as they do from a build context.
+//
+// The initial copy is synchronous and its error is returned. The errors of the following syncs are logged
+// and reported to the [SyncNotify] functions, and the failed syncs are retried, with an exponential backoff
+// starting at the poll interval, up to 30s, until they succeed. The sync runs until ctx is done,
+// [Syncer.Stop] is called, or the container is stopped.
+func (c *Container) Sync(ctx context.Context, hostDir string, containerDir string, opts ...SyncOption) (*Syncer, error) {
+	options := syncOptions{
+		pollInterval: defaultSyncPollInterval,
+		debounce:     defaultSyncDebounce,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.pollInterval <= 0 {
+		return nil, errors.New("sync poll interval must be positive")
+	}
+
+	hostDir, err := filepath.Abs(hostDir)
+	if err != nil {
+		return nil, fmt.Errorf("absolute path: %w", err)
+	}
+
+	_, patterns, err := image.ParseDockerIgnore(hostDir)
+	if err != nil {
+		return nil, fmt.Errorf("parse dockerignore: %w", err)
+	}
+
+	matcher, err := patternmatcher.New(slices.Concat(patterns, options.exclude))
+	if err != nil {
+		return nil, fmt.Errorf("exclude patterns: %w", err)
+	}
+
+	tree, err := scanSyncTree(hostDir, matcher)
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", hostDir, err)
+	}
+
+	if err := c.execSyncCommand(ctx, "mkdir", "-p", "--", containerDir); err != nil {
+		return nil, fmt.Errorf("create %s: %w", containerDir, err)
+	}
+
+	if err := c.pushSync(ctx, hostDir, containerDir, syncTree{}, tree, options); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Syncer{cancel: cancel, done: make(chan struct{})}
+
+	c.syncMtx.Lock()
+	c.syncers = append(c.syncers, s)
+	c.syncMtx.Unlock()
+
+	go func() {
+		defer close(s.done)
+		defer cancel()
+		defer c.removeSyncer(s)
+
+		c.syncLoop(ctx, hostDir, containerDir, matcher, tree, options)
+	}()
+
+	return s, nil
+}
+
+// removeSyncer forgets a stopped syncer.
+func (c *Container) removeSyncer(s *Syncer) {
+	c.syncMtx.Lock()
+	defer c.syncMtx.Unlock()
+
+	c.syncers = slices.DeleteFunc(c.syncers, func(other *Syncer) bool {
+		return other == s
+	})
+}
+
+// stopSyncing stops the syncers of the container, without waiting for them.
+func (c *Container) stopSyncing() {
+	c.syncMtx.Lock()
+	defer c.syncMtx.Unlock()
+
+	for _, s := range c.syncers {
+		s.cancel()
+	}
+}
+
+// syncLoop scans the host directory until ctx is done, pushing its changes once it's stable.
+func (c *Container) syncLoop(ctx context.Context, hostDir string, containerDir string, matcher *patternmatcher.PatternMatcher, synced syncTree, options syncOptions) {
+	ticker := time.NewTicker(options.pollInterval)
+	defer ticker.Stop()
+
+	var (
+		last       = synced
+		lastChange = time.Now()
+
+		// the backoff of a failed sync, zero once synced
+		backoff time.Duration
+		retryAt time.Time
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := scanSyncTree(hostDir, matcher)
+		if err != nil {
+			c.logger.Warn("failed to scan synced directory", "containerID", c.ShortID(), "dir", hostDir, "error", err)
+			continue
+		}
+
+		if !maps.Equal(current, last) {
+			last = current
+			lastChange = time.Now()
+		}
+
+		if maps.Equal(last, synced) || time.Since(lastChange) < options.debounce || time.Now().Before(retryAt) {
+			continue
+		}
+
+		if err := c.pushSync(ctx, hostDir, containerDir, synced, last, options); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// the changes are pushed again from the last synced tree
+			backoff = nextSyncBackoff(backoff, options.pollInterval)
+			retryAt = time.Now().Add(backoff)
+			c.logger.Warn("failed to sync directory, retrying", "containerID", c.ShortID(), "dir", hostDir, "retryIn", backoff, "error", err)
+			continue
+		}
+
+		synced = last
+		backoff, retryAt = 0, time.Time{}
+	}
+}
+
+// nextSyncBackoff returns the time to wait before retrying a failed sync, doubling
+// the previous one, starting at minBackoff, up to [maxSyncRetryBackoff].
+func nextSyncBackoff(previous time.Duration, minBackoff time.Duration) time.Duration {
+	return min(max(2*previous, minBackoff), max(minBackoff, maxSyncRetryBackoff))
+}
+
+// pushSync pushes the changes between two trees of the host directory to the container,
+// running the post-sync command afterwards, and notifies the result.
+func (c *Container) pushSync(ctx context.Context, hostDir string, containerDir string, from syncTree, to syncTree, options syncOptions) error {
+	copied, deleted := diffSyncTrees(from, to)
+	result := SyncResult{Copied: copied, Deleted: deleted}
+
+	if len(deleted) > 0 {
+		cmd := []string{"rm", "-rf", "--"}
+		for _, rel := range deleted {
+			cmd = append(cmd, path.Join(containerDir, rel))
+		}
+
+		if err := c.execSyncCommand(ctx, cmd...); err != nil {
+			result.Err = errors.Join(result.Err, fmt.Errorf("delete: %w", err))
+		}
+	}
+
+	if len(copied) > 0 {
+		buffer, err := tarSyncEntries(hostDir, copied)
+		if err != nil {
+			result.Err = errors.Join(result.Err, fmt.Errorf("tar changes: %w", err))
+		} else {
+			_, err := c.dockerClient.CopyToContainer(ctx, c.ID(), client.CopyToContainerOptions{
+				DestinationPath: containerDir,
+				Content:         buffer,
+			})
+			if err != nil {
+				result.Err = errors.Join(result.Err, fmt.Errorf("copy to container: %w", err))
+			}
+		}
+	}
+
+	if result.Err == nil && len(options.postSync) > 0 {
+		if err := c.execSyncCommand(ctx, options.postSync...); err != nil {
+			result.Err = fmt.Errorf("post-sync command: %w", err)
+		}
+	}
+
+	if result.Err == nil {
+		c.logger.Debug("synced directory", "containerID", c.ShortID(), "dir", hostDir, "copied", len(copied), "deleted", len(deleted))
+	}
+
+	for _, fn := range options.onSync {
+		fn(result)
+	}
+
+	return result.Err
+}
+
+// execSyncCommand runs a command in the container, returning an error including
+// its output if it exits with a non-zero exit code.
+func (c *Container) execSyncCommand(ctx context.Context, cmd ...string) error {
+	code, r, err := c.Exec(ctx, cmd, exec.Multiplexed())
+	if err != nil {
+		return fmt.Errorf("exec %s: %w", cmd[0], err)
+	}
+
+	if code != 0 {
+		out, _ := io.ReadAll(r)
+		return fmt.Errorf("%s exited with code %d: %s", cmd[0], code, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// syncEntry is the state of an entry of a synced directory.
+type syncEntry struct {
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	link    string
+}
+
+// syncTree is the state of a synced directory, by slash-separated path relative to it.
+type syncTree map[string]syncEntry
+
+// scanSyncTree returns the state of the entries of the host directory, skipping the excluded ones.
+// Excluded directories are skipped with their content, unless the patterns have exceptions.
+func scanSyncTree(hostDir string, matcher *patternmatcher.PatternMatcher) (syncTree, error) {
+	tree := syncTree{}
+
+	err := filepath.WalkDir(hostDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// entries can be deleted while scanning
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if p == hostDir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(hostDir, p)
+		if err != nil {
+			return err
+		}
+
+		excluded, err := matcher.MatchesOrParentMatches(rel)
+		if err != nil {
+			return fmt.Errorf("match %s: %w", rel, err)
+		}
+		if excluded {
+			if d.IsDir() && !matcher.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		entry := syncEntry{mode: fi.Mode(), size: fi.Size(), modTime: fi.ModTime()}
+		switch {
+		case fi.IsDir():
+			// the content of a directory is compared entry by entry
+			entry.size, entry.modTime = 0, time.Time{}
+		case fi.Mode().Type() == os.ModeSymlink:
+			entry.link, err = os.Readlink(p)
+			if err != nil {
+				return fmt.Errorf("read link: %w", err)
+			}
+		case !fi.Mode().IsRegular():
+			return nil
+		}
+
+		tree[filepath.ToSlash(rel)] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// diffSyncTrees returns the sorted paths of the entries to copy, being created or modified, and the entries
+// to delete. The entries of deleted directories are not listed, as deleting the directory deletes them.
+// An entry whose type changed is both deleted and copied.
+func diffSyncTrees(from syncTree, to syncTree) ([]string, []string) {
+	var copied, deleted []string
+
+	for rel, entry := range to {
+		previous, ok := from[rel]
+		if !ok || previous != entry {
+			copied = append(copied, rel)
+		}
+		if ok && previous.mode.Type() != entry.mode.Type() {
+			deleted = append(deleted, rel)
+		}
+	}
+
+	for rel := range from {
+		if _, ok := to[rel]; !ok {
+			deleted = append(deleted, rel)
+		}
+	}
+
+	slices.Sort(copied)
+	slices.Sort(deleted)
+
+	// keep the topmost deleted paths only
+	deleted = slices.DeleteFunc(deleted, func(rel string) bool {
+		for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+			if _, found := slices.BinarySearch(deleted, parent); found {
+				return true
+			}
+		}
+		return false
+	})
+
+	return copied, deleted
+}
+
+// tarSyncEntries compress the entries of the host directory using tar + gzip algorithms,
+// preserving their modes and symbolic links. Entries deleted in the meantime are skipped.
+func tarSyncEntries(hostDir string, rels []string) (*bytes.Buffer, error) {
+	buffer := &bytes.Buffer{}
+
+	// tar > gzip > buffer
+	zr := gzip.NewWriter(buffer)
+	tw := tar.NewWriter(zr)
+
+	for _, rel := range rels {
+		if err := writeSyncEntry(tw, hostDir, rel); err != nil {
+			return buffer, err
+		}
+	}
+
+	// produce tar
+	if err := tw.Close(); err != nil {
+		return buffer, fmt.Errorf("close tar file: %w", err)
+	}
+	// produce gzip
+	if err := zr.Close(); err != nil {
+		return buffer, fmt.Errorf("close gzip file: %w", err)
+	}
+
+	return buffer, nil
+}
+
+// writeSyncEntry writes an entry of the host directory to the tar archive.
+func writeSyncEntry(tw *tar.Writer, hostDir string, rel string) error {
+	hostPath := filepath.Join(hostDir, filepath.FromSlash(rel))
+
+	fi, err := os.Lstat(hostPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat: %w", err)
+	}
+
+	var link string
+	if fi.Mode().Type() == os.ModeSymlink {
+		if link, err = os.Readlink(hostPath); err != nil {
+			return fmt.Errorf("read link: %w", err)
+		}
+	}
+
+	header, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return fmt.Errorf("file info header: %w", err)
+	}
+	header.Name = rel
+
+	if !fi.Mode().IsRegular() {
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		return nil
+	}
+
+	// read the content first, so the size of the header matches it if the file changed
+	data, err := os.ReadFile(hostPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+	header.Size = int64(len(data))
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package container_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/exec"
+)
+
+func TestContainer_Sync(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx, container.WithImage(nginxAlpineImage))
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".dockerignore"), []byte("*.log\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("v1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "debug.log"), []byte("log"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "old"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old", "page.html"), []byte("old"), 0o644))
+
+	results := make(chan container.SyncResult, 10)
+	syncer, err := ctr.Sync(ctx, dir, "/usr/share/nginx/html/app",
+		container.SyncPollInterval(50*time.Millisecond),
+		container.SyncDebounce(100*time.Millisecond),
+		container.SyncPostCommand("nginx", "-s", "reload"),
+		container.SyncNotify(func(result container.SyncResult) {
+			results <- result
+		}),
+	)
+	require.NoError(t, err)
+	defer syncer.Stop()
+
+	initial := <-results
+	require.NoError(t, initial.Err)
+	require.ElementsMatch(t, []string{".dockerignore", "index.html", "old", "old/page.html"}, initial.Copied)
+
+	readFile := func(containerPath string) string {
+		t.Helper()
+
+		code, r, err := ctr.Exec(ctx, []string{"cat", containerPath}, exec.Multiplexed())
+		require.NoError(t, err)
+		require.Zero(t, code)
+
+		bs, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(bs)
+	}
+	require.Equal(t, "v1", readFile("/usr/share/nginx/html/app/index.html"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("v2 with a longer content"), 0o644))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "old")))
+
+	select {
+	case result := <-results:
+		require.NoError(t, result.Err)
+		require.Equal(t, []string{"index.html"}, result.Copied)
+		require.Equal(t, []string{"old"}, result.Deleted)
+	case <-time.After(10 * time.Second):
+		t.Fatal("changes not synced")
+	}
+
+	require.Equal(t, "v2 with a longer content", readFile("/usr/share/nginx/html/app/index.html"))
+
+	code, _, err := ctr.Exec(ctx, []string{"test", "-e", "/usr/share/nginx/html/app/old"})
+	require.NoError(t, err)
+	require.Equal(t, 1, code)
+
+	code, _, err = ctr.Exec(ctx, []string{"test", "-e", "/usr/share/nginx/html/app/debug.log"})
+	require.NoError(t, err)
+	require.Equal(t, 1, code)
+
+	require.NoError(t, ctr.Stop(ctx))
+	select {
+	case <-syncer.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("sync not stopped with the container")
+	}
+}
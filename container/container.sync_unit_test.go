@@ -0,0 +1,177 @@
+package container
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dockerclient "github.com/moby/moby/client"
+	"github.com/moby/patternmatcher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanSyncTree(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "dep"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "debug.log"), []byte("log"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node_modules", "dep", "index.js"), []byte("dep"), 0o644))
+	require.NoError(t, os.Symlink("src/main.go", filepath.Join(dir, "main.go")))
+
+	matcher, err := patternmatcher.New([]string{"node_modules", "**/*.log"})
+	require.NoError(t, err)
+
+	tree, err := scanSyncTree(dir, matcher)
+	require.NoError(t, err)
+
+	rels := make([]string, 0, len(tree))
+	for rel := range tree {
+		rels = append(rels, rel)
+	}
+	require.ElementsMatch(t, []string{"src", "src/pkg", "src/main.go", "main.go"}, rels)
+	require.Equal(t, "src/main.go", tree["main.go"].link)
+	require.Equal(t, int64(len("package main")), tree["src/main.go"].size)
+}
+
+func TestDiffSyncTrees(t *testing.T) {
+	now := time.Now()
+	file := syncEntry{mode: 0o644, size: 1, modTime: now}
+	dir := syncEntry{mode: os.ModeDir | 0o755}
+
+	from := syncTree{
+		"main.go":         file,
+		"README.md":       file,
+		"old":             dir,
+		"old/file.go":     file,
+		"old/sub":         dir,
+		"old/sub/file.go": file,
+		"kind":            file,
+	}
+	to := syncTree{
+		"main.go":    {mode: 0o644, size: 2, modTime: now.Add(time.Second)},
+		"README.md":  file,
+		"new":        dir,
+		"new/new.go": file,
+		"kind":       dir,
+	}
+
+	copied, deleted := diffSyncTrees(from, to)
+	require.Equal(t, []string{"kind", "main.go", "new", "new/new.go"}, copied)
+	require.Equal(t, []string{"kind", "old"}, deleted)
+
+	copied, deleted = diffSyncTrees(to, to)
+	require.Empty(t, copied)
+	require.Empty(t, deleted)
+}
+
+func TestTarSyncEntries(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "run.sh"), []byte("#!/bin/sh"), 0o755))
+	require.NoError(t, os.Symlink("bin/run.sh", filepath.Join(dir, "run")))
+
+	buff, err := tarSyncEntries(dir, []string{"bin", "bin/run.sh", "deleted.txt", "run"})
+	require.NoError(t, err)
+
+	gzr, err := gzip.NewReader(buff)
+	require.NoError(t, err)
+
+	headers := map[string]*tar.Header{}
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		headers[header.Name] = header
+	}
+
+	require.Len(t, headers, 3)
+	require.Equal(t, byte(tar.TypeDir), headers["bin"].Typeflag)
+	require.Equal(t, int64(0o755), headers["bin/run.sh"].Mode)
+	require.Equal(t, int64(len("#!/bin/sh")), headers["bin/run.sh"].Size)
+	require.Equal(t, "bin/run.sh", headers["run"].Linkname)
+}
+
+func TestNextSyncBackoff(t *testing.T) {
+	require.Equal(t, 500*time.Millisecond, nextSyncBackoff(0, 500*time.Millisecond))
+	require.Equal(t, time.Second, nextSyncBackoff(500*time.Millisecond, 500*time.Millisecond))
+	require.Equal(t, maxSyncRetryBackoff, nextSyncBackoff(20*time.Second, 500*time.Millisecond))
+	require.Equal(t, time.Minute, nextSyncBackoff(time.Minute, time.Minute))
+}
+
+// syncFakeClient is a fake SDK client failing the first copies to the container.
+type syncFakeClient struct {
+	*lifecycleFakeClient
+
+	failures atomic.Int32
+}
+
+func (f *syncFakeClient) CopyToContainer(_ context.Context, _ string, _ dockerclient.CopyToContainerOptions) (dockerclient.CopyToContainerResult, error) {
+	if f.failures.Add(-1) >= 0 {
+		return dockerclient.CopyToContainerResult{}, errors.New("copy failed")
+	}
+	return dockerclient.CopyToContainerResult{}, nil
+}
+
+func TestContainer_syncLoop_retry(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644))
+
+	ctr, lifecycleFake := newLifecycleTestContainer(StateRunning)
+	fake := &syncFakeClient{lifecycleFakeClient: lifecycleFake}
+	fake.failures.Store(2)
+	ctr.dockerClient = fake
+
+	matcher, err := patternmatcher.New(nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan SyncResult, 10)
+	options := syncOptions{
+		pollInterval: 10 * time.Millisecond,
+		onSync:       []func(SyncResult){func(r SyncResult) { results <- r }},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctr.syncLoop(ctx, dir, "/app", matcher, syncTree{}, options)
+	}()
+
+	// the failed syncs are retried with the same changes until they succeed
+	for i := range 3 {
+		select {
+		case r := <-results:
+			require.Equal(t, []string{"main.go"}, r.Copied)
+			if i < 2 {
+				require.ErrorContains(t, r.Err, "copy failed")
+			} else {
+				require.NoError(t, r.Err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sync %d not attempted", i)
+		}
+	}
+
+	// once synced, the unchanged directory is not pushed again
+	select {
+	case r := <-results:
+		t.Fatalf("unexpected sync: %+v", r)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+}
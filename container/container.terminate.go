@@ -107,32 +107,63 @@ func isNil(val any) bool {
 // Terminate calls stops and then removes the container including its volumes.
 // If its image was built it and all child images are also removed unless
 // the [FromDockerfile.KeepImage] on the [ContainerRequest] was set to true.
+// The snapshot images and volumes of the container, see [Container.Snapshot], are removed too,
+// and its sidecars, see [WithSidecars], are terminated first. The forwarder of
+// [WithHostPortAccess], if any, is terminated last.
 //
 // The following hooks are called in order:
 //   - [LifecycleHooks.PreTerminates]
 //   - [LifecycleHooks.PostTerminates]
 //
 // Default: timeout is 10 seconds.
+//
+// Terminate is idempotent and safe to call concurrently: once the container has been
+// removed, subsequent calls are a no-op, returning nil.
 func (c *Container) Terminate(ctx context.Context, opts ...TerminateOption) error {
+	c.lifecycleMtx.Lock()
+	defer c.lifecycleMtx.Unlock()
+
+	if c.LifecycleState() == StateRemoved {
+		return nil
+	}
+
+	// sidecars share the network namespace of the container, so they go first.
+	errSidecars := c.terminateSidecars(ctx)
+
 	options := NewTerminateOptions(ctx, opts...)
-	err := c.Stop(options.Context(), StopTimeout(options.StopTimeout()))
+	err := c.stop(options.Context(), StopTimeout(options.StopTimeout()))
 	if err != nil && !isCleanupSafe(err) {
-		return fmt.Errorf("stop: %w", err)
+		return errors.Join(errSidecars, fmt.Errorf("stop: %w", err))
 	}
 
 	// TODO: Handle errors from ContainerRemove more correctly, e.g. should we
 	// run the terminated hook?
-	errs := make([]error, 0, 4)
-	errs = append(errs, c.terminatingHook(ctx))
+	errs := make([]error, 0, 5)
+	errs = append(errs, errSidecars, c.terminatingHook(ctx))
 	_, err = c.dockerClient.ContainerRemove(ctx, c.ID(), dockerclient.ContainerRemoveOptions{
 		RemoveVolumes: true,
 		Force:         true,
 	})
 	errs = append(errs, err)
+	c.invalidateInspect()
+
+	// a container which is gone can be considered removed
+	if err == nil || isCleanupSafe(err) {
+		errs = append(errs, c.transition(StateRemoved))
+	}
+
 	errs = append(errs, c.terminatedHook(ctx))
 
-	c.isRunning = false
+	// snapshot images and volumes can only be removed once the containers using them are gone.
+	errs = append(errs, c.removeSnapshots(ctx))
+
+	if c.hostAccess != nil {
+		errs = append(errs, c.hostAccess.stop(ctx))
+		c.hostAccess = nil
+	}
 
+	// volumes mounted with RemoveOnTerminate are removed after the container.
+	options.volumes = append(options.volumes, c.volumes...)
 	if err = options.Cleanup(c.dockerClient); err != nil {
 		errs = append(errs, err)
 	}
@@ -0,0 +1,449 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/client"
+)
+
+const (
+	// crashLogTailLines is the number of log lines passed to the crash handlers.
+	crashLogTailLines = 50
+
+	// watchPollInterval is the interval used to poll the container state
+	// when the daemon events are not available.
+	watchPollInterval = time.Second
+)
+
+// CrashReason is the reason a crash handler is called.
+type CrashReason string
+
+const (
+	// CrashExited the container exited on its own.
+	CrashExited CrashReason = "exited"
+
+	// CrashOOMKilled the container was killed because it ran out of memory.
+	CrashOOMKilled CrashReason = "oom-killed"
+
+	// CrashUnhealthy the health check of the container started failing.
+	CrashUnhealthy CrashReason = "unhealthy"
+
+	// CrashRestarted the container was restarted, by its restart policy or outside the SDK.
+	CrashRestarted CrashReason = "restarted"
+)
+
+// ExitInfo describes an unexpected change of a running container, as reported to a [CrashHandler].
+type ExitInfo struct {
+	// Reason the reason the crash handler is called.
+	Reason CrashReason
+
+	// ExitCode the exit code of the last run of the container.
+	ExitCode int
+
+	// OOMKilled whether the container was killed because it ran out of memory.
+	OOMKilled bool
+
+	// Error the error reported by the daemon for the last run of the container, if any.
+	Error string
+
+	// RestartCount the number of times the daemon restarted the container.
+	RestartCount int
+
+	// HealthStatus the health status of the container, empty if it has no health check.
+	HealthStatus container.HealthStatus
+
+	// LogTail the last lines of the container logs.
+	LogTail string
+
+	// Time the time the change was detected.
+	Time time.Time
+}
+
+// String returns a human readable description of the crash.
+func (i ExitInfo) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(i.Reason))
+	fmt.Fprintf(&sb, " (exit code: %d", i.ExitCode)
+	if i.OOMKilled {
+		sb.WriteString(", OOM killed")
+	}
+	if i.RestartCount > 0 {
+		fmt.Fprintf(&sb, ", restarts: %d", i.RestartCount)
+	}
+	if i.HealthStatus != "" {
+		fmt.Fprintf(&sb, ", health: %s", i.HealthStatus)
+	}
+	if i.Error != "" {
+		fmt.Fprintf(&sb, ", error: %s", i.Error)
+	}
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// CrashHandler is a function called when a watched container exits, is OOM killed,
+// becomes unhealthy or is restarted, without the SDK being asked to stop it.
+// It's called from the goroutine watching the container, so it must not call
+// the lifecycle methods of the container.
+type CrashHandler func(ctx context.Context, ctr ContainerInfo, info ExitInfo)
+
+// WithCrashHandler registers a handler called when the container crashes after being ready.
+// The container is watched automatically once it's ready, see [Container.Watch].
+func WithCrashHandler(handler CrashHandler) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if handler == nil {
+			return errors.New("crash handler is nil")
+		}
+
+		def.crashHandlers = append(def.crashHandlers, handler)
+		return nil
+	}
+}
+
+// WithFailOnCrash fails the test when the container crashes after being ready.
+// It's a shortcut for [WithCrashHandler] with [FailOnCrash].
+func WithFailOnCrash(tb testing.TB) CustomizeDefinitionOption {
+	return WithCrashHandler(FailOnCrash(tb))
+}
+
+// FailOnCrash returns a crash handler marking the test as failed, including the exit
+// details and the log tail of the container. Crashes reported after the test
+// has finished are ignored.
+func FailOnCrash(tb testing.TB) CrashHandler {
+	var (
+		mtx  sync.Mutex
+		done bool
+	)
+
+	tb.Cleanup(func() {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		done = true
+	})
+
+	return func(_ context.Context, ctr ContainerInfo, info ExitInfo) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if done {
+			return
+		}
+
+		tb.Errorf("container %s (%s) crashed: %s\n%s", ctr.ShortID(), ctr.Image(), info, info.LogTail)
+	}
+}
+
+// OnCrash registers a handler called when the container crashes while it's watched.
+func (c *Container) OnCrash(handler CrashHandler) {
+	if handler == nil {
+		return
+	}
+
+	c.watchMtx.Lock()
+	defer c.watchMtx.Unlock()
+
+	c.crashHandlers = append(c.crashHandlers, handler)
+}
+
+// watcher is a running monitor of the container.
+type watcher struct {
+	cancel context.CancelFunc
+}
+
+// Watch starts monitoring the container in the background, calling the crash handlers
+// registered with [WithCrashHandler] or [Container.OnCrash] when the container exits,
+// is OOM killed, becomes unhealthy or is restarted.
+//
+// The monitor follows the daemon events for the container, falling back to polling
+// its state if the events are not available. Each event invalidates the cached inspect result,
+// see [Container.CachedInspect], and each check refreshes it. It stops when ctx is done, when the
+// container is stopped or terminated by the SDK, or when the container exits and
+// it has no restart policy. Containers with crash handlers are watched automatically
+// once they are ready, so calling Watch is only needed for containers not started by the SDK.
+//
+// Calling Watch on a container which is already watched is a no-op.
+func (c *Container) Watch(ctx context.Context) error {
+	c.watchMtx.Lock()
+	defer c.watchMtx.Unlock()
+
+	if c.watcher != nil {
+		return nil
+	}
+
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w := &watcher{cancel: cancel}
+	c.watcher = w
+
+	go c.watch(ctx, w, snapshotOf(inspect))
+
+	return nil
+}
+
+// hasCrashHandlers returns true if crash handlers are registered for the container.
+func (c *Container) hasCrashHandlers() bool {
+	c.watchMtx.Lock()
+	defer c.watchMtx.Unlock()
+
+	return len(c.crashHandlers) > 0
+}
+
+// stopWatching stops the monitor of the container, if any.
+// It's called before the SDK stops the container, so the exit is not reported as a crash.
+func (c *Container) stopWatching() {
+	c.watchMtx.Lock()
+	defer c.watchMtx.Unlock()
+
+	if c.watcher != nil {
+		c.watcher.cancel()
+		c.watcher = nil
+	}
+}
+
+// watch follows the daemon events for the container until ctx is done,
+// or there is nothing left to watch.
+func (c *Container) watch(ctx context.Context, w *watcher, last watchSnapshot) {
+	defer func() {
+		w.cancel()
+
+		c.watchMtx.Lock()
+		defer c.watchMtx.Unlock()
+
+		if c.watcher == w {
+			c.watcher = nil
+		}
+	}()
+
+	result := c.dockerClient.Events(ctx, client.EventsListOptions{
+		Since: strconv.FormatInt(last.at.Unix(), 10),
+		Filters: make(client.Filters).
+			Add("type", string(events.ContainerEventType)).
+			Add("container", c.ID()),
+	})
+
+	var done bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-result.Messages:
+			// the event is a change made outside the SDK too, e.g. a restart or a docker stop
+			c.invalidateInspect()
+
+			if !isWatchedAction(msg.Action) {
+				continue
+			}
+
+			if last, done = c.checkState(ctx, last); done {
+				return
+			}
+		case err := <-result.Err:
+			if ctx.Err() != nil {
+				return
+			}
+
+			c.logger.Debug("container events not available, polling the container state", "containerID", c.ShortID(), "error", err)
+			c.pollState(ctx, last)
+			return
+		}
+	}
+}
+
+// pollState polls the container state until ctx is done, or there is nothing left to watch.
+func (c *Container) pollState(ctx context.Context, last watchSnapshot) {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	var done bool
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if last, done = c.checkState(ctx, last); done {
+				return
+			}
+		}
+	}
+}
+
+// isWatchedAction returns true if the event action can change the state reported to the crash handlers.
+func isWatchedAction(action events.Action) bool {
+	switch action {
+	case events.ActionDie, events.ActionOOM, events.ActionStart, events.ActionRestart, events.ActionDestroy:
+		return true
+	default:
+		return strings.HasPrefix(string(action), string(events.ActionHealthStatus))
+	}
+}
+
+// watchSnapshot is the part of the container state compared to detect crashes.
+type watchSnapshot struct {
+	at           time.Time
+	running      bool
+	restarting   bool
+	restartCount int
+	startedAt    string
+	health       container.HealthStatus
+	noRestart    bool
+}
+
+// snapshotOf returns the snapshot of an inspect result.
+func snapshotOf(inspect client.ContainerInspectResult) watchSnapshot {
+	s := watchSnapshot{
+		at:           time.Now(),
+		restartCount: inspect.Container.RestartCount,
+		noRestart:    true,
+	}
+
+	if state := inspect.Container.State; state != nil {
+		// the daemon reports a container restarted by its restart policy as running
+		s.running = state.Running && !state.Restarting
+		s.restarting = state.Restarting
+		s.startedAt = state.StartedAt
+		if state.Health != nil {
+			s.health = state.Health.Status
+		}
+	}
+
+	if hc := inspect.Container.HostConfig; hc != nil {
+		s.noRestart = hc.RestartPolicy.IsNone()
+	}
+
+	return s
+}
+
+// crashReasons returns the crashes that happened between two snapshots.
+func crashReasons(last watchSnapshot, current watchSnapshot) []CrashReason {
+	var reasons []CrashReason
+
+	if last.running && !current.running {
+		reasons = append(reasons, CrashExited)
+	}
+
+	restarted := current.restartCount > last.restartCount ||
+		(current.running && last.startedAt != "" && current.startedAt != last.startedAt)
+	if restarted {
+		reasons = append(reasons, CrashRestarted)
+	}
+
+	if current.health == container.Unhealthy && last.health != container.Unhealthy {
+		reasons = append(reasons, CrashUnhealthy)
+	}
+
+	return reasons
+}
+
+// checkState inspects the container, calling the crash handlers for the crashes since
+// the last snapshot. It returns the new snapshot, and true if there is nothing left to watch.
+func (c *Container) checkState(ctx context.Context, last watchSnapshot) (watchSnapshot, bool) {
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return last, true
+		}
+
+		if errdefs.IsNotFound(err) {
+			c.logger.Debug("watched container removed", "containerID", c.ShortID())
+			return last, true
+		}
+
+		c.logger.Debug("failed to inspect watched container", "containerID", c.ShortID(), "error", err)
+		return last, false
+	}
+
+	// the SDK stopped the container while it was inspected
+	if ctx.Err() != nil {
+		return last, true
+	}
+
+	current := snapshotOf(inspect)
+	switch {
+	case last.running && !current.running:
+		c.Running(false)
+	case !last.running && current.running:
+		c.Running(true)
+	}
+
+	for _, reason := range crashReasons(last, current) {
+		c.reportCrash(ctx, reason, inspect)
+	}
+
+	return current, !current.running && !current.restarting && current.noRestart
+}
+
+// reportCrash calls the crash handlers of the container.
+func (c *Container) reportCrash(ctx context.Context, reason CrashReason, inspect client.ContainerInspectResult) {
+	info := ExitInfo{
+		Reason:       reason,
+		RestartCount: inspect.Container.RestartCount,
+		Time:         time.Now(),
+	}
+
+	if state := inspect.Container.State; state != nil {
+		info.ExitCode = state.ExitCode
+		info.OOMKilled = state.OOMKilled
+		info.Error = state.Error
+		if state.Health != nil {
+			info.HealthStatus = state.Health.Status
+		}
+	}
+
+	if reason == CrashExited && info.OOMKilled {
+		info.Reason = CrashOOMKilled
+	}
+
+	tty := inspect.Container.Config != nil && inspect.Container.Config.Tty
+	info.LogTail = c.logTail(ctx, tty, crashLogTailLines)
+
+	c.watchMtx.Lock()
+	handlers := append([]CrashHandler(nil), c.crashHandlers...)
+	c.watchMtx.Unlock()
+
+	c.logger.Warn("container crashed", "containerID", c.ShortID(), "reason", info.Reason, "exitCode", info.ExitCode)
+
+	for _, handler := range handlers {
+		handler(ctx, c, info)
+	}
+}
+
+// logTail returns the last lines of the container logs, or an empty string if they can't be read.
+func (c *Container) logTail(ctx context.Context, tty bool, lines int) string {
+	rc, err := c.dockerClient.ContainerLogs(ctx, c.ID(), client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(lines),
+	})
+	if err != nil {
+		c.logger.Debug("failed to read container logs", "containerID", c.ShortID(), "error", err)
+		return ""
+	}
+
+	if !tty {
+		rc = c.parseMultiplexedLogs(rc)
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		c.logger.Debug("failed to read container logs", "containerID", c.ShortID(), "error", err)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,286 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+)
+
+// watchFakeClient is a fake SDK client supporting the calls done by the crash monitor.
+type watchFakeClient struct {
+	*lifecycleFakeClient
+
+	state        atomic.Pointer[container.State]
+	restartCount atomic.Int32
+	messages     chan events.Message
+	errs         chan error
+}
+
+func (f *watchFakeClient) ContainerInspect(_ context.Context, id string, _ dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error) {
+	f.inspectCalls.Add(1)
+
+	state := f.state.Load()
+	if state == nil {
+		state = &container.State{Running: f.running.Load(), StartedAt: "start-1"}
+	}
+
+	return dockerclient.ContainerInspectResult{
+		Container: container.InspectResponse{
+			ID:           id,
+			State:        state,
+			RestartCount: int(f.restartCount.Load()),
+			Config:       &container.Config{Tty: true},
+		},
+	}, nil
+}
+
+func (f *watchFakeClient) ContainerLogs(_ context.Context, _ string, options dockerclient.ContainerLogsOptions) (dockerclient.ContainerLogsResult, error) {
+	return io.NopCloser(strings.NewReader("tail=" + options.Tail + "\nout of memory\n")), nil
+}
+
+func (f *watchFakeClient) Events(_ context.Context, _ dockerclient.EventsListOptions) dockerclient.EventsResult {
+	return dockerclient.EventsResult{Messages: f.messages, Err: f.errs}
+}
+
+// exit simulates the container exiting on its own.
+func (f *watchFakeClient) exit(exitCode int, oomKilled bool) {
+	f.running.Store(false)
+	f.state.Store(&container.State{ExitCode: exitCode, OOMKilled: oomKilled, StartedAt: "start-1"})
+}
+
+func newWatchTestContainer(t *testing.T) (*Container, *watchFakeClient, <-chan ExitInfo) {
+	t.Helper()
+
+	ctr, lifecycleFake := newLifecycleTestContainer(StateCreated)
+	fake := &watchFakeClient{
+		lifecycleFakeClient: lifecycleFake,
+		messages:            make(chan events.Message),
+		errs:                make(chan error, 1),
+	}
+	ctr.dockerClient = fake
+
+	crashes := make(chan ExitInfo, 10)
+	ctr.OnCrash(func(_ context.Context, _ ContainerInfo, info ExitInfo) {
+		crashes <- info
+	})
+
+	return ctr, fake, crashes
+}
+
+func TestContainer_Watch(t *testing.T) {
+	t.Run("events/oom-killed", func(t *testing.T) {
+		ctr, fake, crashes := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+
+		fake.exit(137, true)
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionDie}
+
+		select {
+		case info := <-crashes:
+			require.Equal(t, CrashOOMKilled, info.Reason)
+			require.Equal(t, 137, info.ExitCode)
+			require.True(t, info.OOMKilled)
+			require.Equal(t, "tail=50\nout of memory\n", info.LogTail)
+		case <-time.After(5 * time.Second):
+			t.Fatal("crash not reported")
+		}
+
+		require.Equal(t, StateStopped, ctr.LifecycleState())
+	})
+
+	t.Run("events/unhealthy-restarted", func(t *testing.T) {
+		ctr, fake, crashes := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+
+		fake.state.Store(&container.State{Running: true, StartedAt: "start-1", Health: &container.Health{Status: container.Unhealthy}})
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionHealthStatusUnhealthy}
+		require.Equal(t, CrashUnhealthy, (<-crashes).Reason)
+
+		fake.restartCount.Store(1)
+		fake.state.Store(&container.State{Running: true, StartedAt: "start-2", ExitCode: 1})
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionStart}
+
+		info := <-crashes
+		require.Equal(t, CrashRestarted, info.Reason)
+		require.Equal(t, 1, info.RestartCount)
+		require.Equal(t, StateRunning, ctr.LifecycleState())
+	})
+
+	t.Run("polling-fallback", func(t *testing.T) {
+		ctr, fake, crashes := newWatchTestContainer(t)
+		fake.errs <- errors.New("events not supported")
+
+		require.NoError(t, ctr.Start(context.Background()))
+
+		fake.exit(1, false)
+
+		select {
+		case info := <-crashes:
+			require.Equal(t, CrashExited, info.Reason)
+			require.Equal(t, 1, info.ExitCode)
+		case <-time.After(5 * time.Second):
+			t.Fatal("crash not reported")
+		}
+	})
+
+	t.Run("stopped-by-sdk", func(t *testing.T) {
+		ctr, fake, crashes := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+		require.NoError(t, ctr.Stop(context.Background()))
+
+		ctr.watchMtx.Lock()
+		require.Nil(t, ctr.watcher)
+		ctr.watchMtx.Unlock()
+
+		fake.exit(0, false)
+		select {
+		case fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionDie}:
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		select {
+		case info := <-crashes:
+			t.Fatalf("exit requested by the SDK reported as a crash: %s", info)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("stopped-out-of-band", func(t *testing.T) {
+		ctr, fake, crashes := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+
+		inspect, err := ctr.CachedInspect(context.Background(), -1)
+		require.NoError(t, err)
+		require.True(t, inspect.Container.State.Running)
+
+		// a docker stop is not done by the SDK, so only the events report it
+		fake.exit(0, false)
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionStop}
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionDie}
+		require.Equal(t, CrashExited, (<-crashes).Reason)
+
+		inspect, err = ctr.CachedInspect(context.Background(), -1)
+		require.NoError(t, err)
+		require.False(t, inspect.Container.State.Running)
+	})
+
+	t.Run("invalidated-by-events", func(t *testing.T) {
+		ctr, fake, _ := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+		_, err := ctr.CachedInspect(context.Background(), -1)
+		require.NoError(t, err)
+
+		// events not checked by the monitor invalidate the cache too
+		fake.messages <- events.Message{Type: events.ContainerEventType, Action: events.ActionPause}
+		require.Eventually(t, func() bool {
+			_, ok := ctr.inspectCache.get(-1)
+			return !ok
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		ctr, _, _ := newWatchTestContainer(t)
+
+		require.NoError(t, ctr.Start(context.Background()))
+
+		ctr.watchMtx.Lock()
+		w := ctr.watcher
+		ctr.watchMtx.Unlock()
+
+		require.NoError(t, ctr.Watch(context.Background()))
+
+		ctr.watchMtx.Lock()
+		require.Same(t, w, ctr.watcher)
+		ctr.watchMtx.Unlock()
+
+		require.NoError(t, ctr.Terminate(context.Background()))
+	})
+}
+
+func TestCrashReasons(t *testing.T) {
+	running := watchSnapshot{running: true, startedAt: "start-1"}
+
+	testCases := []struct {
+		name    string
+		current watchSnapshot
+		expect  []CrashReason
+	}{
+		{name: "unchanged", current: running},
+		{name: "exited", current: watchSnapshot{startedAt: "start-1"}, expect: []CrashReason{CrashExited}},
+		{name: "restarting", current: watchSnapshot{restarting: true, startedAt: "start-1"}, expect: []CrashReason{CrashExited}},
+		{name: "restart-policy", current: watchSnapshot{running: true, restartCount: 1, startedAt: "start-2"}, expect: []CrashReason{CrashRestarted}},
+		{name: "manual-restart", current: watchSnapshot{running: true, startedAt: "start-2"}, expect: []CrashReason{CrashRestarted}},
+		{name: "unhealthy", current: watchSnapshot{running: true, startedAt: "start-1", health: container.Unhealthy}, expect: []CrashReason{CrashUnhealthy}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, crashReasons(running, tc.current))
+		})
+	}
+}
+
+// recordingTB records the failures of a test.
+type recordingTB struct {
+	testing.TB
+
+	mtx      sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func TestFailOnCrash(t *testing.T) {
+	tb := &recordingTB{}
+	handler := FailOnCrash(tb)
+
+	ctr, _ := newLifecycleTestContainer(StateRunning)
+	ctr.image = "kafka:latest"
+
+	handler(context.Background(), ctr, ExitInfo{Reason: CrashOOMKilled, ExitCode: 137, OOMKilled: true, LogTail: "out of memory"})
+	require.Len(t, tb.errors, 1)
+	require.Contains(t, tb.errors[0], "container 1234567890ab (kafka:latest) crashed: oom-killed (exit code: 137, OOM killed)")
+	require.Contains(t, tb.errors[0], "out of memory")
+
+	// crashes after the end of the test are ignored
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+	handler(context.Background(), ctr, ExitInfo{Reason: CrashExited})
+	require.Len(t, tb.errors, 1)
+}
+
+func TestWithCrashHandler(t *testing.T) {
+	def := Definition{}
+
+	require.Error(t, WithCrashHandler(nil)(&def))
+	require.NoError(t, WithCrashHandler(func(context.Context, ContainerInfo, ExitInfo) {})(&def))
+	require.NoError(t, WithFailOnCrash(t)(&def))
+	require.Len(t, def.crashHandlers, 2)
+}
@@ -2,10 +2,17 @@ package container
 
 import (
 	"context"
+	"net/netip"
 	"testing"
+	"time"
 
+	"github.com/containerd/errdefs"
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/client"
 	"github.com/stretchr/testify/require"
+
+	sdkclient "github.com/docker/go-sdk/client"
 )
 
 func TestFromResponse(t *testing.T) {
@@ -25,6 +32,268 @@ func TestFromResponse(t *testing.T) {
 	require.Equal(t, "1234567890ab", ctr.ShortID())
 	require.Equal(t, "nginx:latest", ctr.Image())
 	require.Equal(t, []string{"80/tcp", "8080/udp"}, ctr.exposedPorts)
+	require.Equal(t, StateRunning, ctr.LifecycleState())
 	require.NotNil(t, ctr.dockerClient)
 	require.NotNil(t, ctr.logger)
 }
+
+func TestPortSpecOf(t *testing.T) {
+	ports := []container.PortSummary{
+		{IP: netip.MustParseAddr("0.0.0.0"), PrivatePort: 80, PublicPort: 32768, Type: "tcp"},
+		{IP: netip.MustParseAddr("::"), PrivatePort: 80, PublicPort: 32769, Type: "tcp"},
+		{IP: netip.MustParseAddr("127.0.0.1"), PrivatePort: 53, PublicPort: 5353, Type: "udp"},
+		{PrivatePort: 9090, PublicPort: 9091, Type: "tcp"},
+		{PublicPort: 8080, Type: "udp"},
+	}
+
+	specs := make([]string, 0, len(ports))
+	for _, port := range ports {
+		specs = append(specs, portSpecOf(port))
+	}
+
+	require.Equal(t, []string{
+		"0.0.0.0:32768:80/tcp",
+		"[::]:32769:80/tcp",
+		"127.0.0.1:5353:53/udp",
+		"9091:9090/tcp",
+		"8080/udp",
+	}, specs)
+
+	// the specs can be used to define a new container
+	_, _, err := parsePortSpecs(specs)
+	require.NoError(t, err)
+}
+
+// hostFakeClient is a fake SDK client returning a fixed daemon host.
+type hostFakeClient struct {
+	sdkclient.SDKClient
+
+	host string
+}
+
+func (f *hostFakeClient) DaemonHostWithContext(_ context.Context) (string, error) {
+	return f.host, nil
+}
+
+func newPortsTestContainer(t *testing.T, inspect container.InspectResponse) *Container {
+	t.Helper()
+
+	// the inspect result is cached, so only the daemon host is queried
+	ctr := &Container{
+		dockerClient: &hostFakeClient{host: "localhost"},
+		containerID:  "1234567890abcdefgh",
+	}
+	ctr.inspectCache.set(client.ContainerInspectResult{Container: inspect})
+
+	return ctr
+}
+
+func TestContainer_PortBindings(t *testing.T) {
+	inspect := container.InspectResponse{
+		HostConfig: &container.HostConfig{},
+		NetworkSettings: &container.NetworkSettings{
+			Ports: network.PortMap{
+				network.MustParsePort("8080/tcp"): {
+					{HostIP: netip.MustParseAddr("0.0.0.0"), HostPort: "32768"},
+					{HostIP: netip.MustParseAddr("::"), HostPort: "32769"},
+				},
+				network.MustParsePort("53/udp"): {
+					{HostIP: netip.MustParseAddr("127.0.0.1"), HostPort: "5353"},
+				},
+				network.MustParsePort("9090/tcp"): nil,
+			},
+		},
+	}
+
+	t.Run("bindings", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		bindings, err := ctr.PortBindings(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []PortBinding{
+			{
+				Port: network.MustParsePort("53/udp"),
+				Bindings: []HostBinding{
+					{HostIP: netip.MustParseAddr("127.0.0.1"), HostPort: 5353, Protocol: network.UDP, Family: IPv4},
+				},
+			},
+			{
+				Port: network.MustParsePort("8080/tcp"),
+				Bindings: []HostBinding{
+					{HostIP: netip.MustParseAddr("0.0.0.0"), HostPort: 32768, Protocol: network.TCP, Family: IPv4},
+					{HostIP: netip.MustParseAddr("::"), HostPort: 32769, Protocol: network.TCP, Family: IPv6},
+				},
+			},
+			{Port: network.MustParsePort("9090/tcp")},
+		}, bindings)
+	})
+
+	t.Run("host-network", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, container.InspectResponse{
+			HostConfig: &container.HostConfig{NetworkMode: "host"},
+			Config: &container.Config{
+				ExposedPorts: network.PortSet{network.MustParsePort("80/tcp"): {}},
+			},
+		})
+
+		bindings, err := ctr.PortBindings(context.Background())
+		require.NoError(t, err)
+		require.Len(t, bindings, 1)
+		require.Equal(t, uint16(80), bindings[0].Bindings[0].HostPort)
+	})
+
+	t.Run("endpoints", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		endpoints, err := ctr.PortEndpoints(context.Background(), "http")
+		require.NoError(t, err)
+		require.Equal(t, map[network.Port][]string{
+			network.MustParsePort("53/udp"):   {"http://127.0.0.1:5353"},
+			network.MustParsePort("8080/tcp"): {"http://localhost:32768", "http://[::1]:32769"},
+		}, endpoints)
+	})
+
+	t.Run("endpoint-family", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		endpoint, err := ctr.PortEndpoint(context.Background(), network.MustParsePort("8080/tcp"), "", WithAddressFamily(IPv6))
+		require.NoError(t, err)
+		require.Equal(t, "[::1]:32769", endpoint)
+
+		endpoint, err = ctr.PortEndpoint(context.Background(), network.MustParsePort("8080/tcp"), "", WithAddressFamily(IPv4))
+		require.NoError(t, err)
+		require.Equal(t, "localhost:32768", endpoint)
+
+		_, err = ctr.PortEndpoint(context.Background(), network.MustParsePort("53/udp"), "", WithAddressFamily(IPv6))
+		require.ErrorIs(t, err, errdefs.ErrNotFound)
+	})
+}
+
+func TestInspectCache(t *testing.T) {
+	result := client.ContainerInspectResult{
+		Container: container.InspectResponse{ID: "1234567890abcdefgh"},
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		var ic inspectCache
+
+		_, ok := ic.get(-1)
+		require.False(t, ok)
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		var ic inspectCache
+		ic.set(result)
+
+		got, ok := ic.get(time.Minute)
+		require.True(t, ok)
+		require.Equal(t, result, got)
+
+		got, ok = ic.get(-1)
+		require.True(t, ok)
+		require.Equal(t, result, got)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		var ic inspectCache
+		ic.set(result)
+		ic.updatedAt = time.Now().Add(-time.Minute)
+
+		_, ok := ic.get(time.Second)
+		require.False(t, ok)
+
+		// a negative max age accepts any valid result
+		_, ok = ic.get(-1)
+		require.True(t, ok)
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		var ic inspectCache
+		ic.set(result)
+		ic.invalidate()
+
+		_, ok := ic.get(-1)
+		require.False(t, ok)
+	})
+}
+
+func TestContainer_CachedInspect(t *testing.T) {
+	result := client.ContainerInspectResult{
+		Container: container.InspectResponse{ID: "1234567890abcdefgh"},
+	}
+
+	// no docker client is set: a cache miss would panic
+	ctr := &Container{containerID: "1234567890abcdefgh"}
+	ctr.inspectCache.set(result)
+
+	got, err := ctr.CachedInspect(context.Background(), time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, result, got)
+}
+
+func TestInternalEndpointFromInspect(t *testing.T) {
+	inspect := container.InspectResponse{
+		Name: "/kafka-1",
+		NetworkSettings: &container.NetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {NetworkID: "bridge-id", IPAddress: netip.MustParseAddr("172.17.0.2")},
+				"app": {
+					NetworkID: "app-id",
+					Aliases:   []string{"1234567890ab", "kafka"},
+					IPAddress: netip.MustParseAddr("172.18.0.2"),
+				},
+			},
+		},
+	}
+	port := network.MustParsePort("9092/tcp")
+
+	t.Run("by-name", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+		ctr.shortID = "1234567890ab"
+
+		endpoint, err := ctr.InternalEndpoint(context.Background(), "app", port, "")
+		require.NoError(t, err)
+		require.Equal(t, "kafka:9092", endpoint)
+	})
+
+	t.Run("by-id", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		endpoint, err := ctr.InternalEndpoint(context.Background(), "app-id", port, "tcp")
+		require.NoError(t, err)
+		require.Equal(t, "tcp://1234567890ab:9092", endpoint)
+	})
+
+	t.Run("only-user-defined-network", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+		ctr.shortID = "1234567890ab"
+
+		endpoint, err := ctr.InternalEndpoint(context.Background(), "", port, "")
+		require.NoError(t, err)
+		require.Equal(t, "kafka:9092", endpoint)
+	})
+
+	t.Run("default-network", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		_, err := ctr.InternalEndpoint(context.Background(), "bridge", port, "")
+		require.ErrorIs(t, err, ErrNoSharedNetwork)
+	})
+
+	t.Run("not-attached", func(t *testing.T) {
+		ctr := newPortsTestContainer(t, inspect)
+
+		_, err := ctr.InternalEndpoint(context.Background(), "other", port, "")
+		require.ErrorIs(t, err, ErrNoSharedNetwork)
+	})
+}
+
+func TestInternalHost(t *testing.T) {
+	settings := &network.EndpointSettings{IPAddress: netip.MustParseAddr("172.18.0.2")}
+
+	require.Equal(t, "kafka-1", internalHost(settings, "kafka-1", "1234567890ab"))
+	require.Equal(t, "172.18.0.2", internalHost(settings, "", "1234567890ab"))
+
+	settings.Aliases = []string{"1234567890ab", "kafka"}
+	require.Equal(t, "kafka", internalHost(settings, "kafka-1", "1234567890ab"))
+}
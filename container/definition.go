@@ -3,7 +3,7 @@ package container
 import (
 	"errors"
 	"fmt"
-	"strings"
+	"io"
 
 	"github.com/containerd/platforms"
 	"github.com/moby/moby/api/types/container"
@@ -27,6 +27,9 @@ type Definition struct {
 	// dockerClient the docker client to use for the container.
 	dockerClient client.SDKClient
 
+	// autoRemove whether to remove the container of a job once its result is collected.
+	autoRemove bool
+
 	// configModifier the modifier for the config before container creation
 	configModifier func(*container.Config)
 
@@ -36,6 +39,12 @@ type Definition struct {
 	// endpointSettingsModifier the modifier for the network settings before container creation
 	endpointSettingsModifier func(map[string]*network.EndpointSettings)
 
+	// crashHandlers the functions called when the container crashes after being ready.
+	crashHandlers []CrashHandler
+
+	// durableEntrypoint whether the entrypoint is wrapped to run the durable startup dispatcher.
+	durableEntrypoint bool
+
 	// entrypoint the entrypoint to use for the container.
 	entrypoint []string
 
@@ -45,15 +54,40 @@ type Definition struct {
 	// files the files to be copied when container starts
 	files []File
 
+	// healthCheck the health check for the container, overriding the image's one.
+	healthCheck *container.HealthConfig
+
+	// hostAccessPorts the ports of the host reachable from the container at HostInternal.
+	hostAccessPorts []uint16
+
+	// hostPorts the allocator of the host ports pinned in the port bindings, nil to leave them to the daemon.
+	hostPorts *hostPortAllocator
+
 	// hostConfigModifier the modifier for the host config before container creation
 	hostConfigModifier func(*container.HostConfig)
 
+	// mounts the typed mounts to add to the host config before container creation
+	mounts []ContainerMount
+
+	// securityOpts the typed resource limits and security options to add to the host config
+	// before container creation
+	securityOpts *securityOptions
+
+	// user the user the container process runs as.
+	user string
+
 	// validateFuncs the functions to validate the definition.
 	validateFuncs []func() error
 
 	// imageSubstitutors the image substitutors to use for the container.
 	imageSubstitutors []ImageSubstitutor
 
+	// initContainers the customizers of the containers run to completion before the container is created.
+	initContainers [][]ContainerCustomizer
+
+	// job whether the container is run to completion by RunJob.
+	job bool
+
 	// labels the labels to use for the container.
 	labels map[string]string
 
@@ -91,8 +125,26 @@ type Definition struct {
 	// pullOptions are used to change the pull image behavior.
 	pullOptions []image.PullOption
 
+	// pulls the image pulls shared with the definitions run together, nil if the definition runs alone.
+	pulls *pullGroup
+
+	// onCreated the functions called with the container once it's created, before the created hooks.
+	onCreated []func(*Container)
+
+	// sharedVolumes whether a sidecar mounts the volumes of the container it's attached to.
+	sharedVolumes bool
+
+	// sidecarOf the ID of the container a sidecar is attached to, empty for other containers.
+	sidecarOf string
+
+	// sidecars the customizers of the containers started once the container is started.
+	sidecars [][]ContainerCustomizer
+
 	// started whether to auto-start the container.
 	started bool
+
+	// stdin the input written to the standard input of a job.
+	stdin io.Reader
 }
 
 // validate validates the definition.
@@ -146,11 +198,34 @@ func (d *Definition) HostConfigModifier() func(*container.HostConfig) {
 	return d.hostConfigModifier
 }
 
+// Mounts returns the typed mounts of the container.
+func (d *Definition) Mounts() []ContainerMount {
+	return d.mounts
+}
+
 // validateMounts ensures that the mounts do not have duplicate targets.
-// It will check the HostConfigModifier.Binds field.
+// It checks the typed mounts, and the Binds, Mounts and Tmpfs fields
+// resulting from the HostConfigModifier.
 func (d *Definition) validateMounts() error {
 	targets := make(map[string]bool, 0)
 
+	addTarget := func(target string) error {
+		if targets[target] {
+			return fmt.Errorf("%w: %s", ErrDuplicateMountTarget, target)
+		}
+		targets[target] = true
+		return nil
+	}
+
+	for _, m := range d.mounts {
+		if _, err := m.AsMount(); err != nil {
+			return err
+		}
+		if err := addTarget(m.Target()); err != nil {
+			return err
+		}
+	}
+
 	if d.hostConfigModifier == nil {
 		return nil
 	}
@@ -159,17 +234,25 @@ func (d *Definition) validateMounts() error {
 
 	d.hostConfigModifier(&hostConfig)
 
-	if len(hostConfig.Binds) > 0 {
-		for _, bind := range hostConfig.Binds {
-			parts := strings.Split(bind, ":")
-			if len(parts) != 2 && len(parts) != 3 {
-				return fmt.Errorf("%w: %s", ErrInvalidBindMount, bind)
-			}
-			targetPath := parts[1]
-			if targets[targetPath] {
-				return fmt.Errorf("%w: %s", ErrDuplicateMountTarget, targetPath)
-			}
-			targets[targetPath] = true
+	for _, bind := range hostConfig.Binds {
+		targetPath, err := parseBindTarget(bind)
+		if err != nil {
+			return err
+		}
+		if err := addTarget(targetPath); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range hostConfig.Mounts {
+		if err := addTarget(m.Target); err != nil {
+			return err
+		}
+	}
+
+	for target := range hostConfig.Tmpfs {
+		if err := addTarget(target); err != nil {
+			return err
 		}
 	}
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,4 +54,65 @@ func TestValidateMounts(t *testing.T) {
 		err := d.validateMounts()
 		require.NoError(t, err)
 	})
+
+	t.Run("windows-bind-paths", func(t *testing.T) {
+		d := &Definition{
+			hostConfigModifier: func(hc *container.HostConfig) {
+				hc.Binds = []string{`C:\data:C:\data:ro`, `C:\logs:/logs`, "named:/named:ro"}
+			},
+		}
+		err := d.validateMounts()
+		require.NoError(t, err)
+	})
+
+	t.Run("duplicate-windows-target", func(t *testing.T) {
+		d := &Definition{
+			hostConfigModifier: func(hc *container.HostConfig) {
+				hc.Binds = []string{`C:\a:C:\data`, `D:\b:C:\data:ro`}
+			},
+		}
+		err := d.validateMounts()
+		require.ErrorIs(t, err, ErrDuplicateMountTarget)
+	})
+
+	t.Run("duplicate-target/typed-and-bind", func(t *testing.T) {
+		d := &Definition{
+			mounts: []ContainerMount{TmpfsMount{ContainerPath: "/data"}},
+			hostConfigModifier: func(hc *container.HostConfig) {
+				hc.Binds = []string{"/host:/data"}
+			},
+		}
+		err := d.validateMounts()
+		require.ErrorIs(t, err, ErrDuplicateMountTarget)
+	})
+
+	t.Run("duplicate-target/typed-and-host-config-mounts", func(t *testing.T) {
+		d := &Definition{
+			mounts: []ContainerMount{VolumeMount{Name: "vol", ContainerPath: "/data"}},
+			hostConfigModifier: func(hc *container.HostConfig) {
+				hc.Mounts = []mount.Mount{{Type: mount.TypeBind, Source: "/host", Target: "/data"}}
+			},
+		}
+		err := d.validateMounts()
+		require.ErrorIs(t, err, ErrDuplicateMountTarget)
+	})
+
+	t.Run("duplicate-target/tmpfs", func(t *testing.T) {
+		d := &Definition{
+			mounts: []ContainerMount{ImageMount{Image: "alpine", ContainerPath: "/run"}},
+			hostConfigModifier: func(hc *container.HostConfig) {
+				hc.Tmpfs = map[string]string{"/run": "rw"}
+			},
+		}
+		err := d.validateMounts()
+		require.ErrorIs(t, err, ErrDuplicateMountTarget)
+	})
+
+	t.Run("invalid-typed-mount", func(t *testing.T) {
+		d := &Definition{
+			mounts: []ContainerMount{BindMount{ContainerPath: "/data"}},
+		}
+		err := d.validateMounts()
+		require.ErrorIs(t, err, ErrInvalidMount)
+	})
 }
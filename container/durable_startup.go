@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/docker/go-sdk/container/exec"
@@ -25,6 +26,10 @@ import (
 //	    000-cmd.sh         registration order
 //	  002-<name>/
 //	    000-cmd.sh
+//	    run-once           from WithDurableStartupRunOnce
+//	  .state/              written by the dispatcher at runtime
+//	    status             see Container.DurableStartupReport
+//	    once/<name>        markers of the applied run-once namespaces
 //	  run.sh               single dispatcher
 const DurableStartupDir = "/etc/durable-startup.d"
 
@@ -36,6 +41,14 @@ const defaultDurableNamespace = "default"
 // durableStartupDispatcherName is the basename of the dispatcher script.
 const durableStartupDispatcherName = "run.sh"
 
+// durableRunOnceName is the basename of the file flagging a namespace
+// to run once. See [WithDurableStartupRunOnce].
+const durableRunOnceName = "run-once"
+
+// durableStderrTailSize is the number of trailing bytes of the standard
+// error of each script kept in the status file.
+const durableStderrTailSize = 2048
+
 // durableStartupFileMode is the mode used for rendered script files.
 // They must be executable so the dispatcher can invoke them directly.
 const durableStartupFileMode int64 = 0o755
@@ -46,6 +59,15 @@ const durableStartupFileMode int64 = 0o755
 // coverage, or invoked directly from a reconnect path.
 const DurableStartupDispatcherPath = DurableStartupDir + "/" + durableStartupDispatcherName
 
+// DurableStartupStateDir is the directory where the dispatcher records
+// the outcome of its last run and the run-once markers. It is hidden, so
+// the dispatcher never mistakes it for a namespace.
+const DurableStartupStateDir = DurableStartupDir + "/.state"
+
+// DurableStartupStatusPath is the status file the dispatcher writes on
+// each run. See [Container.DurableStartupReport].
+const DurableStartupStatusPath = DurableStartupStateDir + "/status"
+
 // durableNamespaceNameRe restricts namespace names to a safe, lexically
 // well-behaved subset. Lowercase letters, digits, '-', '_'. Must start
 // with an alphanumeric. This avoids slashes/dots in path components and
@@ -74,10 +96,11 @@ var ErrDurableStartupReservedNamespace = fmt.Errorf("durable startup namespace %
 // files are part of the container's own filesystem state.
 //
 // The dispatcher that walks the directory and runs the scripts is rendered
-// alongside, but invocation is the consumer's responsibility (typically:
-// register the dispatcher as a regular [WithStartupCommand] for
-// first-create coverage, and invoke it manually from any reconnect path
-// the consumer owns — e.g. CLI's "run" or "exec" entry points).
+// alongside. Add [WithDurableStartupEntrypoint] to have the container run
+// it on every start. Otherwise invocation is the consumer's responsibility
+// (typically: register the dispatcher as a regular [WithStartupCommand]
+// for first-create coverage, and invoke it manually from any reconnect
+// path the consumer owns — e.g. CLI's "run" or "exec" entry points).
 //
 // Commands registered here always execute before any namespace registered
 // via [WithDurableStartupCommandsFromDir], because the default namespace
@@ -135,6 +158,52 @@ func WithDurableStartupCommandsFromDir(dirName string, execs ...Executable) Cust
 	}
 }
 
+// WithDurableStartupRunOnce flags the named namespace, registered with
+// [WithDurableStartupCommandsFromDir] before or after this option, to
+// run once: after all its commands succeed, the dispatcher records a
+// marker under [DurableStartupStateDir], and skips the namespace on the
+// following starts of the container. This is meant for migrations and
+// seeds, which must not be re-applied on restart.
+//
+// A namespace failing midway is run again, from its first command, on
+// the next start. The marker is part of the container's filesystem, so
+// recreating the container runs the namespace again. If the dispatcher
+// can't write to [DurableStartupStateDir], e.g. when it doesn't run as
+// root, no marker is recorded and the namespace runs on every start.
+//
+// dirName follows the rules of [WithDurableStartupCommandsFromDir].
+func WithDurableStartupRunOnce(dirName string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if dirName == defaultDurableNamespace {
+			return ErrDurableStartupReservedNamespace
+		}
+		if !durableNamespaceNameRe.MatchString(dirName) {
+			return fmt.Errorf("invalid durable startup namespace %q: must match %s", dirName, durableNamespaceNameRe)
+		}
+
+		nsDir, dispatcherPresent := resolveDurableNamespaceDir(def.files, dirName)
+		flagPath := path.Join(nsDir, durableRunOnceName)
+
+		if !slices.ContainsFunc(def.files, func(f File) bool { return f.ContainerPath == flagPath }) {
+			def.files = append(def.files, File{
+				Reader:        bytes.NewReader([]byte("# the commands of this namespace run once, see " + DurableStartupStateDir + "/once\n")),
+				ContainerPath: flagPath,
+				Mode:          0o644,
+			})
+		}
+
+		if !dispatcherPresent {
+			def.files = append(def.files, File{
+				Reader:        bytes.NewReader([]byte(renderDurableDispatcher(DurableStartupDir))),
+				ContainerPath: DurableStartupDispatcherPath,
+				Mode:          durableStartupFileMode,
+			})
+		}
+
+		return nil
+	}
+}
+
 // appendDurableScripts is the shared core. It resolves (or allocates) the
 // namespace subdirectory for name, renders each exec into the next
 // MMM-cmd.sh slot in that subdir, and ensures the dispatcher is rendered
@@ -348,21 +417,66 @@ func renderDurableScript(e Executable) (string, error) {
 // subdirectory of root in lexical order and invokes its *-cmd.sh files,
 // also in lexical order. LC_ALL=C is pinned so byte-sort is independent
 // of the runtime locale.
+//
+// When the state directory under root is writable, the dispatcher
+// records each run in its status file, one tab-separated record per line:
+//
+//	start   <unix time>
+//	script  <namespace dir>/<script>  <start>  <end>  <exit code>  <hex stderr tail>
+//	skip    <namespace dir>
+//	end     <unix time>  <exit code>
+//
+// The standard error of each script is buffered to keep its tail, and
+// relayed to the dispatcher's own standard error once the script exits.
+// The tail is hex-encoded with od, so the record stays on one line
+// whatever the script prints. Namespaces flagged with a run-once file
+// write their marker once all their scripts succeed, and are skipped
+// while it exists. Without a writable state directory, scripts run as
+// they would without reporting.
 func renderDurableDispatcher(root string) string {
 	return fmt.Sprintf(`#!/bin/sh
 set -e
 LC_ALL=C
 export LC_ALL
-ROOT=%s
+ROOT=%[1]s
 [ -d "$ROOT" ] || exit 0
+STATE="$ROOT/.state"
+if mkdir -p "$STATE/once" 2>/dev/null && : > "$STATE/status" 2>/dev/null; then
+	printf 'start\t%%s\n' "$(date +%%s)" >> "$STATE/status"
+	trap 'rc=$?; printf "end\t%%s\t%%s\n" "$(date +%%s)" "$rc" >> "$STATE/status"' EXIT
+else
+	STATE=
+fi
 for ns in "$ROOT"/*/; do
 	[ -d "$ns" ] || continue
+	base=${ns%%/}
+	base=${base##*/}
+	once=
+	if [ -n "$STATE" ] && [ -f "${ns}%[2]s" ]; then
+		once="$STATE/once/${base#*-}"
+		if [ -f "$once" ]; then
+			printf 'skip\t%%s\n' "$base" >> "$STATE/status"
+			continue
+		fi
+	fi
 	for f in "$ns"*-cmd.sh; do
 		[ -f "$f" ] || continue
-		"$f"
+		if [ -z "$STATE" ]; then
+			"$f"
+			continue
+		fi
+		start=$(date +%%s)
+		rc=0
+		"$f" 2> "$STATE/stderr" || rc=$?
+		end=$(date +%%s)
+		cat "$STATE/stderr" >&2
+		tail=$(tail -c %[3]d "$STATE/stderr" | od -An -v -tx1 | tr -d ' \n')
+		printf 'script\t%%s\t%%s\t%%s\t%%s\t%%s\n' "$base/${f##*/}" "$start" "$end" "$rc" "$tail" >> "$STATE/status"
+		[ "$rc" -eq 0 ] || exit "$rc"
 	done
+	[ -z "$once" ] || : > "$once"
 done
-`, shellSingleQuote(root))
+`, shellSingleQuote(root), durableRunOnceName, durableStderrTailSize)
 }
 
 // shellSingleQuote returns s wrapped in POSIX single quotes, with any
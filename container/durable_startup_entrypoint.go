@@ -0,0 +1,154 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// durableStartupEntrypointName is the basename of the entrypoint wrapper.
+const durableStartupEntrypointName = "entrypoint.sh"
+
+// DurableStartupEntrypointPath is the absolute path of the entrypoint
+// wrapper installed by [WithDurableStartupEntrypoint]. It lives at the
+// root of [DurableStartupDir], next to the dispatcher, so the dispatcher
+// never picks it up as a startup script.
+const DurableStartupEntrypointPath = DurableStartupDir + "/" + durableStartupEntrypointName
+
+// WithDurableStartupEntrypoint wires the durable startup dispatcher into
+// the container's own entrypoint, so the scripts registered with
+// [WithDurableStartupCommand] and [WithDurableStartupCommandsFromDir]
+// run on every start of the container — including restarts by the
+// daemon or a restart policy — without the consumer rewriting the
+// entrypoint.
+//
+// Right before the container is created, the effective entrypoint and
+// command are resolved with Docker's semantics: the ones set on the
+// [Definition] (or by a config modifier) win, and the image's ones,
+// inspected after the image is pulled, fill the gaps. Overriding the
+// entrypoint discards the image's command, as `docker run --entrypoint`
+// does. The container is then created with [DurableStartupEntrypointPath]
+// prepended to the entrypoint, and the same command.
+//
+// The wrapper runs the dispatcher and then `exec`s the original process
+// with its original arguments, so it keeps PID 1 and receives the
+// container's signals directly. A SIGTERM or SIGINT received while the
+// dispatcher runs is forwarded to it and stops the startup. A failing
+// script aborts the startup, and the container exits with its exit code.
+//
+// Creating the container fails if neither the definition nor the image
+// define an entrypoint or a command, as there is no process to exec.
+// The wrapper requires /bin/sh in the container.
+func WithDurableStartupEntrypoint() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.durableEntrypoint = true
+
+		// The dispatcher is rendered once, whichever option comes first.
+		if _, dispatcherPresent := resolveDurableNamespaceDir(def.files, defaultDurableNamespace); !dispatcherPresent {
+			def.files = append(def.files, File{
+				Reader:        bytes.NewReader([]byte(renderDurableDispatcher(DurableStartupDir))),
+				ContainerPath: DurableStartupDispatcherPath,
+				Mode:          durableStartupFileMode,
+			})
+		}
+
+		if !slices.ContainsFunc(def.files, func(f File) bool {
+			return f.ContainerPath == DurableStartupEntrypointPath
+		}) {
+			def.files = append(def.files, File{
+				Reader:        bytes.NewReader([]byte(renderDurableEntrypoint(DurableStartupDispatcherPath))),
+				ContainerPath: DurableStartupEntrypointPath,
+				Mode:          durableStartupFileMode,
+			})
+		}
+
+		return nil
+	}
+}
+
+// wrapDurableEntrypoint prepends the entrypoint wrapper to the entrypoint
+// of cfg, when [WithDurableStartupEntrypoint] is set. It must be called
+// once the image is pulled, as the image's entrypoint and command are
+// inspected when cfg doesn't define them.
+func (d *Definition) wrapDurableEntrypoint(ctx context.Context, cfg *container.Config) error {
+	if !d.durableEntrypoint {
+		return nil
+	}
+
+	var imageEntrypoint, imageCmd []string
+	if len(cfg.Entrypoint) == 0 || len(cfg.Cmd) == 0 {
+		img, err := d.dockerClient.ImageInspect(ctx, cfg.Image)
+		if err != nil {
+			return fmt.Errorf("image inspect: %w", err)
+		}
+		if img.Config != nil {
+			imageEntrypoint, imageCmd = img.Config.Entrypoint, img.Config.Cmd
+		}
+	}
+
+	entrypoint, cmd, err := durableEntrypointArgs(cfg.Entrypoint, cfg.Cmd, imageEntrypoint, imageCmd)
+	if err != nil {
+		return err
+	}
+
+	cfg.Entrypoint, cfg.Cmd = entrypoint, cmd
+	return nil
+}
+
+// durableEntrypointArgs returns the entrypoint and the command wrapping
+// the effective process of the container with the entrypoint wrapper.
+//
+// The effective process follows Docker's rules: a configured entrypoint
+// replaces the image's one and drops the image's command, while a
+// configured command only replaces the image's command. An entrypoint
+// which is already wrapped is returned unchanged.
+func durableEntrypointArgs(entrypoint, cmd, imageEntrypoint, imageCmd []string) ([]string, []string, error) {
+	if len(entrypoint) > 0 && entrypoint[0] == DurableStartupEntrypointPath {
+		return entrypoint, cmd, nil
+	}
+
+	if len(entrypoint) == 0 {
+		entrypoint = imageEntrypoint
+		if len(cmd) == 0 {
+			cmd = imageCmd
+		}
+	}
+
+	if len(entrypoint) == 0 && len(cmd) == 0 {
+		return nil, nil, errors.New("durable startup entrypoint: no entrypoint or command to exec, neither in the definition nor in the image")
+	}
+
+	wrapped := make([]string, 0, len(entrypoint)+1)
+	wrapped = append(wrapped, DurableStartupEntrypointPath)
+	wrapped = append(wrapped, entrypoint...)
+
+	return wrapped, slices.Clone(cmd), nil
+}
+
+// renderDurableEntrypoint builds the entrypoint wrapper. It runs the
+// dispatcher, when present, and then execs its arguments — the original
+// entrypoint and command — so the original process replaces the shell.
+//
+// The dispatcher runs in the background so the wrapper, which is PID 1
+// and would otherwise ignore signals without a handler, can forward
+// SIGTERM/SIGINT to it and exit with the conventional 128+signal code.
+// `wait` returns the dispatcher's exit code, which `set -e` propagates.
+func renderDurableEntrypoint(dispatcher string) string {
+	return fmt.Sprintf(`#!/bin/sh
+set -e
+DISPATCHER=%s
+if [ -x "$DISPATCHER" ]; then
+	"$DISPATCHER" &
+	pid=$!
+	trap 'kill -TERM "$pid" 2>/dev/null; exit 143' TERM
+	trap 'kill -INT "$pid" 2>/dev/null; exit 130' INT
+	wait "$pid"
+	trap - TERM INT
+fi
+exec "$@"
+`, shellSingleQuote(dispatcher))
+}
@@ -299,3 +299,186 @@ func TestRenderedDispatcher_propagatesScriptFailure(t *testing.T) {
 	require.Equal(t, "ran\n", string(bs),
 		"only the script before the failure should have appended")
 }
+
+func TestRenderedEntrypoint_runsDispatcherThenExecsArgs(t *testing.T) {
+	sh, err := osexec.LookPath("/bin/sh")
+	if err != nil {
+		t.Skipf("/bin/sh unavailable: %v", err)
+	}
+
+	sandbox := t.TempDir()
+	log := filepath.Join(sandbox, "log")
+
+	dispatcher := filepath.Join(sandbox, "run.sh")
+	require.NoError(t, os.WriteFile(dispatcher,
+		[]byte(fmt.Sprintf("#!/bin/sh\necho dispatched >> %s\n", shellSingleQuote(log))), 0o755))
+
+	wrapper := filepath.Join(sandbox, "entrypoint.sh")
+	require.NoError(t, os.WriteFile(wrapper, []byte(renderDurableEntrypoint(dispatcher)), 0o755))
+
+	args := []string{"with space", `"dq"`, "'sq'", "$HOME", "with\nnewline", ""}
+	cmdArgs := append([]string{wrapper, "sh", "-c", `echo "exec $$" >> "$1"; shift; for a in "$@"; do printf '[%s]\n' "$a"; done`, "_", log}, args...)
+	out, err := osexec.Command(sh, cmdArgs...).CombinedOutput()
+	require.NoError(t, err, "output:\n%s", out)
+
+	var want strings.Builder
+	for _, a := range args {
+		fmt.Fprintf(&want, "[%s]\n", a)
+	}
+	require.Equal(t, want.String(), string(out))
+
+	bs, err := os.ReadFile(log)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, "dispatched", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "exec "))
+}
+
+func TestRenderedEntrypoint_failingDispatcherAbortsExec(t *testing.T) {
+	sh, err := osexec.LookPath("/bin/sh")
+	if err != nil {
+		t.Skipf("/bin/sh unavailable: %v", err)
+	}
+
+	sandbox := t.TempDir()
+	dispatcher := filepath.Join(sandbox, "run.sh")
+	require.NoError(t, os.WriteFile(dispatcher, []byte("#!/bin/sh\nexit 3\n"), 0o755))
+
+	wrapper := filepath.Join(sandbox, "entrypoint.sh")
+	require.NoError(t, os.WriteFile(wrapper, []byte(renderDurableEntrypoint(dispatcher)), 0o755))
+
+	out, err := osexec.Command(sh, wrapper, "echo", "should-not-run").CombinedOutput()
+	var exitErr *osexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 3, exitErr.ExitCode())
+	require.Empty(t, string(out))
+}
+
+func TestRenderedEntrypoint_missingDispatcherExecsArgs(t *testing.T) {
+	out := runScriptWithSh(t, strings.Replace(
+		renderDurableEntrypoint("/path/that/does/not/exist/0987"),
+		`exec "$@"`, `exec echo "no dispatcher"`, 1,
+	))
+	require.Equal(t, "no dispatcher\n", out)
+}
+
+func TestRenderedDispatcher_writesStatusAndRunsOnce(t *testing.T) {
+	sh, err := osexec.LookPath("/bin/sh")
+	if err != nil {
+		t.Skipf("/bin/sh unavailable: %v", err)
+	}
+
+	sandbox := t.TempDir()
+	log := filepath.Join(sandbox, "log")
+
+	mkAppendScript := func(tag string) string {
+		s, err := renderDurableScript(exec.NewRawCommand(
+			[]string{"sh", "-c", `printf '%s\n' "$1" >> "$2"; printf 'warn %s\n' "$1" >&2`, "_", tag, log},
+		))
+		require.NoError(t, err)
+		return s
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(sandbox, "000-default"), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sandbox, "000-default", "000-cmd.sh"),
+		[]byte(mkAppendScript("always")), 0o755,
+	))
+	require.NoError(t, os.MkdirAll(filepath.Join(sandbox, "001-migrations"), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sandbox, "001-migrations", "000-cmd.sh"),
+		[]byte(mkAppendScript("once")), 0o755,
+	))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sandbox, "001-migrations", durableRunOnceName),
+		nil, 0o644,
+	))
+
+	p := filepath.Join(t.TempDir(), "dispatcher.sh")
+	require.NoError(t, os.WriteFile(p, []byte(renderDurableDispatcher(sandbox)), 0o755))
+
+	readReport := func() DurableStartupReport {
+		bs, err := os.ReadFile(filepath.Join(sandbox, ".state", "status"))
+		require.NoError(t, err)
+		report, err := parseDurableStartupReport(bs)
+		require.NoError(t, err)
+		return report
+	}
+
+	// First run: both namespaces run, and the stderr of the scripts is
+	// still relayed.
+	out, err := osexec.Command(sh, p).CombinedOutput()
+	require.NoError(t, err, "output:\n%s", out)
+	require.Equal(t, "warn always\nwarn once\n", string(out))
+
+	report := readReport()
+	require.True(t, report.Finished())
+	require.Zero(t, report.ExitCode)
+	require.Empty(t, report.Skipped)
+	require.Len(t, report.Scripts, 2)
+	require.Equal(t, "default", report.Scripts[0].Namespace)
+	require.Equal(t, "warn always\n", report.Scripts[0].StderrTail)
+	require.Equal(t, "migrations", report.Scripts[1].Namespace)
+	require.Equal(t, filepath.Join(DurableStartupDir, "001-migrations", "000-cmd.sh"), report.Scripts[1].Path)
+
+	// Second run: the run-once namespace is skipped.
+	out, err = osexec.Command(sh, p).CombinedOutput()
+	require.NoError(t, err, "output:\n%s", out)
+
+	report = readReport()
+	require.Equal(t, []string{"migrations"}, report.Skipped)
+	require.Len(t, report.Scripts, 1)
+
+	bs, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, "always\nonce\nalways\n", string(bs))
+}
+
+func TestRenderedDispatcher_reportsFailure(t *testing.T) {
+	sh, err := osexec.LookPath("/bin/sh")
+	if err != nil {
+		t.Skipf("/bin/sh unavailable: %v", err)
+	}
+
+	sandbox := t.TempDir()
+
+	failing, err := renderDurableScript(exec.NewRawCommand(
+		[]string{"sh", "-c", `printf 'migration failed\n' >&2; exit 7`},
+	))
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(sandbox, "001-migrations"), 0o755))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sandbox, "001-migrations", "000-cmd.sh"),
+		[]byte(failing), 0o755,
+	))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(sandbox, "001-migrations", durableRunOnceName),
+		nil, 0o644,
+	))
+
+	p := filepath.Join(t.TempDir(), "dispatcher.sh")
+	require.NoError(t, os.WriteFile(p, []byte(renderDurableDispatcher(sandbox)), 0o755))
+
+	err = osexec.Command(sh, p).Run()
+	var exitErr *osexec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 7, exitErr.ExitCode())
+
+	bs, err := os.ReadFile(filepath.Join(sandbox, ".state", "status"))
+	require.NoError(t, err)
+	report, err := parseDurableStartupReport(bs)
+	require.NoError(t, err)
+
+	require.True(t, report.Finished())
+	require.Equal(t, 7, report.ExitCode)
+
+	failed, ok := report.Failed()
+	require.True(t, ok)
+	require.Equal(t, 7, failed.ExitCode)
+	require.Equal(t, "migration failed\n", failed.StderrTail)
+
+	// A failed run-once namespace records no marker.
+	require.NoFileExists(t, filepath.Join(sandbox, ".state", "once", "migrations"))
+}
@@ -0,0 +1,207 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+)
+
+// ErrDurableStartupReportNotFound is returned by [Container.DurableStartupReport]
+// when the dispatcher has not written its status file, e.g. because it never ran.
+var ErrDurableStartupReportNotFound = errors.New("durable startup report not found")
+
+// DurableStartupReport is the outcome of the last run of the durable startup
+// dispatcher, as recorded in [DurableStartupStatusPath].
+type DurableStartupReport struct {
+	// StartedAt the time the dispatcher started.
+	StartedAt time.Time
+
+	// FinishedAt the time the dispatcher exited, zero while it's running.
+	FinishedAt time.Time
+
+	// ExitCode the exit code of the dispatcher, once finished.
+	ExitCode int
+
+	// Scripts the scripts which ran, in execution order.
+	Scripts []DurableScriptResult
+
+	// Skipped the names of the run-once namespaces skipped because they were
+	// already applied. See [WithDurableStartupRunOnce].
+	Skipped []string
+}
+
+// DurableScriptResult is the outcome of a durable startup script.
+type DurableScriptResult struct {
+	// Namespace the name of the namespace of the script, "default" for the
+	// scripts registered with [WithDurableStartupCommand].
+	Namespace string
+
+	// Path the path of the script in the container.
+	Path string
+
+	// StartedAt the time the script started.
+	StartedAt time.Time
+
+	// FinishedAt the time the script exited.
+	FinishedAt time.Time
+
+	// ExitCode the exit code of the script.
+	ExitCode int
+
+	// StderrTail the last bytes written by the script to its standard error.
+	StderrTail string
+}
+
+// Finished returns true if the dispatcher has exited.
+func (r DurableStartupReport) Finished() bool {
+	return !r.FinishedAt.IsZero()
+}
+
+// Failed returns the script which failed, if any. A failing script stops
+// the dispatcher, so it's the last one which ran.
+func (r DurableStartupReport) Failed() (DurableScriptResult, bool) {
+	if len(r.Scripts) > 0 && r.Scripts[len(r.Scripts)-1].ExitCode != 0 {
+		return r.Scripts[len(r.Scripts)-1], true
+	}
+
+	return DurableScriptResult{}, false
+}
+
+// DurableStartupReport reads the outcome of the last run of the durable
+// startup dispatcher, from the status file it writes in the container.
+// Timestamps have a one-second resolution.
+//
+// If the dispatcher is still running, the report holds the scripts which
+// already ran, and it's not finished. If the dispatcher has not written
+// its status file, the returned error wraps [ErrDurableStartupReportNotFound].
+func (c *Container) DurableStartupReport(ctx context.Context) (DurableStartupReport, error) {
+	r, err := c.CopyFromContainer(ctx, DurableStartupStatusPath)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return DurableStartupReport{}, fmt.Errorf("%w: %w", ErrDurableStartupReportNotFound, err)
+		}
+		return DurableStartupReport{}, fmt.Errorf("copy from container: %w", err)
+	}
+	defer r.Close()
+
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return DurableStartupReport{}, fmt.Errorf("read status: %w", err)
+	}
+
+	return parseDurableStartupReport(bs)
+}
+
+// parseDurableStartupReport parses the status file written by the dispatcher.
+// Unknown records are ignored. See [renderDurableDispatcher] for the format.
+func parseDurableStartupReport(bs []byte) (DurableStartupReport, error) {
+	var report DurableStartupReport
+
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+	// the stderr tail is hex-encoded, doubling its size
+	scanner.Buffer(make([]byte, 0, 4*durableStderrTailSize), 16*durableStderrTailSize)
+
+	for n := 1; scanner.Scan(); n++ {
+		if scanner.Text() == "" {
+			continue
+		}
+
+		fields := strings.Split(scanner.Text(), "\t")
+
+		var err error
+		switch fields[0] {
+		case "start":
+			if len(fields) < 2 {
+				return report, fmt.Errorf("line %d: malformed start record", n)
+			}
+			report.StartedAt, err = parseUnixTime(fields[1])
+		case "end":
+			if len(fields) < 3 {
+				return report, fmt.Errorf("line %d: malformed end record", n)
+			}
+			report.FinishedAt, err = parseUnixTime(fields[1])
+			if err == nil {
+				report.ExitCode, err = strconv.Atoi(fields[2])
+			}
+		case "skip":
+			if len(fields) < 2 {
+				return report, fmt.Errorf("line %d: malformed skip record", n)
+			}
+			report.Skipped = append(report.Skipped, durableNamespaceName(fields[1]))
+		case "script":
+			if len(fields) < 6 {
+				return report, fmt.Errorf("line %d: malformed script record", n)
+			}
+			var result DurableScriptResult
+			result, err = parseDurableScriptResult(fields[1:])
+			report.Scripts = append(report.Scripts, result)
+		}
+		if err != nil {
+			return report, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("scan status: %w", err)
+	}
+
+	return report, nil
+}
+
+// parseDurableScriptResult parses the fields of a script record: its path,
+// relative to [DurableStartupDir], start and end times, exit code, and
+// hex-encoded stderr tail.
+func parseDurableScriptResult(fields []string) (DurableScriptResult, error) {
+	result := DurableScriptResult{
+		Namespace: durableNamespaceName(path.Dir(fields[0])),
+		Path:      path.Join(DurableStartupDir, fields[0]),
+	}
+
+	var err error
+	if result.StartedAt, err = parseUnixTime(fields[1]); err != nil {
+		return result, err
+	}
+	if result.FinishedAt, err = parseUnixTime(fields[2]); err != nil {
+		return result, err
+	}
+	if result.ExitCode, err = strconv.Atoi(fields[3]); err != nil {
+		return result, fmt.Errorf("exit code: %w", err)
+	}
+
+	stderr, err := hex.DecodeString(fields[4])
+	if err != nil {
+		return result, fmt.Errorf("stderr tail: %w", err)
+	}
+	result.StderrTail = string(stderr)
+
+	return result, nil
+}
+
+// durableNamespaceName returns the name of a namespace from its directory
+// name, stripping the NNN- index.
+func durableNamespaceName(dir string) string {
+	if _, name, ok := strings.Cut(dir, "-"); ok {
+		return name
+	}
+
+	return dir
+}
+
+// parseUnixTime parses a time in seconds since the Unix epoch.
+func parseUnixTime(s string) (time.Time, error) {
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time: %w", err)
+	}
+
+	return time.Unix(secs, 0), nil
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -1023,3 +1024,219 @@ func TestErrDurableStartupReservedNamespace_messageMentionsName(t *testing.T) {
 	// who don't unwrap with errors.Is still get a useful diagnostic.
 	require.Contains(t, ErrDurableStartupReservedNamespace.Error(), `"default"`)
 }
+
+func TestWithDurableStartupEntrypoint(t *testing.T) {
+	t.Run("renders-wrapper-and-dispatcher", func(t *testing.T) {
+		def := Definition{}
+		require.NoError(t, WithDurableStartupEntrypoint()(&def))
+
+		require.True(t, def.durableEntrypoint)
+		require.Len(t, def.files, 2)
+
+		wrapper := findFile(t, def.files, DurableStartupEntrypointPath)
+		require.Equal(t, durableStartupFileMode, wrapper.Mode)
+		require.Equal(t, renderDurableEntrypoint(DurableStartupDispatcherPath), readFile(t, wrapper))
+
+		dispatcher := findFile(t, def.files, DurableStartupDispatcherPath)
+		require.Equal(t, renderDurableDispatcher(DurableStartupDir), readFile(t, dispatcher))
+	})
+
+	t.Run("idempotent-with-commands", func(t *testing.T) {
+		def := Definition{}
+		require.NoError(t, WithDurableStartupEntrypoint()(&def))
+		require.NoError(t, WithDurableStartupCommand(exec.NewRawCommand([]string{"true"}))(&def))
+		require.NoError(t, WithDurableStartupEntrypoint()(&def))
+
+		var paths []string
+		for _, f := range def.files {
+			paths = append(paths, f.ContainerPath)
+		}
+		require.ElementsMatch(t, []string{
+			DurableStartupEntrypointPath,
+			DurableStartupDispatcherPath,
+			DurableStartupDir + "/000-default/000-cmd.sh",
+		}, paths)
+	})
+}
+
+func TestDurableEntrypointArgs(t *testing.T) {
+	tests := []struct {
+		name            string
+		entrypoint      []string
+		cmd             []string
+		imageEntrypoint []string
+		imageCmd        []string
+		wantEntrypoint  []string
+		wantCmd         []string
+		wantErr         bool
+	}{
+		{
+			name:            "image-entrypoint-and-cmd",
+			imageEntrypoint: []string{"docker-entrypoint.sh"},
+			imageCmd:        []string{"postgres"},
+			wantEntrypoint:  []string{DurableStartupEntrypointPath, "docker-entrypoint.sh"},
+			wantCmd:         []string{"postgres"},
+		},
+		{
+			name:           "image-cmd-only",
+			imageCmd:       []string{"nginx", "-g", "daemon off;"},
+			wantEntrypoint: []string{DurableStartupEntrypointPath},
+			wantCmd:        []string{"nginx", "-g", "daemon off;"},
+		},
+		{
+			name:            "definition-cmd-replaces-image-cmd",
+			cmd:             []string{"postgres", "-c", "fsync=off"},
+			imageEntrypoint: []string{"docker-entrypoint.sh"},
+			imageCmd:        []string{"postgres"},
+			wantEntrypoint:  []string{DurableStartupEntrypointPath, "docker-entrypoint.sh"},
+			wantCmd:         []string{"postgres", "-c", "fsync=off"},
+		},
+		{
+			name:            "definition-entrypoint-drops-image-cmd",
+			entrypoint:      []string{"/bin/app"},
+			imageEntrypoint: []string{"docker-entrypoint.sh"},
+			imageCmd:        []string{"postgres"},
+			wantEntrypoint:  []string{DurableStartupEntrypointPath, "/bin/app"},
+		},
+		{
+			name:           "definition-entrypoint-and-cmd",
+			entrypoint:     []string{"/bin/app"},
+			cmd:            []string{"serve"},
+			imageCmd:       []string{"postgres"},
+			wantEntrypoint: []string{DurableStartupEntrypointPath, "/bin/app"},
+			wantCmd:        []string{"serve"},
+		},
+		{
+			name:           "already-wrapped",
+			entrypoint:     []string{DurableStartupEntrypointPath, "/bin/app"},
+			cmd:            []string{"serve"},
+			wantEntrypoint: []string{DurableStartupEntrypointPath, "/bin/app"},
+			wantCmd:        []string{"serve"},
+		},
+		{
+			name:    "nothing-to-exec",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entrypoint, cmd, err := durableEntrypointArgs(tt.entrypoint, tt.cmd, tt.imageEntrypoint, tt.imageCmd)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEntrypoint, entrypoint)
+			require.Equal(t, tt.wantCmd, cmd)
+		})
+	}
+}
+
+func TestWithDurableStartupRunOnce(t *testing.T) {
+	t.Run("flags-registered-namespace", func(t *testing.T) {
+		def := Definition{}
+		require.NoError(t, WithDurableStartupCommandsFromDir("migrations", exec.NewRawCommand([]string{"true"}))(&def))
+		require.NoError(t, WithDurableStartupRunOnce("migrations")(&def))
+		require.NoError(t, WithDurableStartupRunOnce("migrations")(&def))
+
+		var paths []string
+		for _, f := range def.files {
+			paths = append(paths, f.ContainerPath)
+		}
+		require.Equal(t, []string{
+			DurableStartupDir + "/001-migrations/000-cmd.sh",
+			DurableStartupDispatcherPath,
+			DurableStartupDir + "/001-migrations/run-once",
+		}, paths)
+	})
+
+	t.Run("flags-namespace-registered-later", func(t *testing.T) {
+		def := Definition{}
+		require.NoError(t, WithDurableStartupRunOnce("seed")(&def))
+		require.NoError(t, WithDurableStartupCommandsFromDir("other", exec.NewRawCommand([]string{"true"}))(&def))
+		require.NoError(t, WithDurableStartupCommandsFromDir("seed", exec.NewRawCommand([]string{"true"}))(&def))
+
+		findFile(t, def.files, DurableStartupDir+"/001-seed/run-once")
+		findFile(t, def.files, DurableStartupDir+"/001-seed/000-cmd.sh")
+		findFile(t, def.files, DurableStartupDir+"/002-other/000-cmd.sh")
+		findFile(t, def.files, DurableStartupDispatcherPath)
+	})
+
+	t.Run("invalid-namespace", func(t *testing.T) {
+		def := Definition{}
+		require.ErrorIs(t, WithDurableStartupRunOnce(defaultDurableNamespace)(&def), ErrDurableStartupReservedNamespace)
+		require.Error(t, WithDurableStartupRunOnce("Bad/Name")(&def))
+		require.Empty(t, def.files)
+	})
+}
+
+func TestParseDurableStartupReport(t *testing.T) {
+	t.Run("finished", func(t *testing.T) {
+		status := "start\t1700000000\n" +
+			"skip\t001-migrations\n" +
+			"script\t000-default/000-cmd.sh\t1700000000\t1700000001\t0\t\n" +
+			"script\t002-seed/000-cmd.sh\t1700000001\t1700000003\t3\t626f6f6d0a\n" +
+			"end\t1700000003\t3\n"
+
+		report, err := parseDurableStartupReport([]byte(status))
+		require.NoError(t, err)
+
+		require.True(t, report.Finished())
+		require.Equal(t, time.Unix(1700000000, 0), report.StartedAt)
+		require.Equal(t, time.Unix(1700000003, 0), report.FinishedAt)
+		require.Equal(t, 3, report.ExitCode)
+		require.Equal(t, []string{"migrations"}, report.Skipped)
+		require.Equal(t, []DurableScriptResult{
+			{
+				Namespace:  "default",
+				Path:       DurableStartupDir + "/000-default/000-cmd.sh",
+				StartedAt:  time.Unix(1700000000, 0),
+				FinishedAt: time.Unix(1700000001, 0),
+			},
+			{
+				Namespace:  "seed",
+				Path:       DurableStartupDir + "/002-seed/000-cmd.sh",
+				StartedAt:  time.Unix(1700000001, 0),
+				FinishedAt: time.Unix(1700000003, 0),
+				ExitCode:   3,
+				StderrTail: "boom\n",
+			},
+		}, report.Scripts)
+
+		failed, ok := report.Failed()
+		require.True(t, ok)
+		require.Equal(t, "seed", failed.Namespace)
+	})
+
+	t.Run("running", func(t *testing.T) {
+		report, err := parseDurableStartupReport([]byte("start\t1700000000\nscript\t000-default/000-cmd.sh\t1700000000\t1700000001\t0\t\n"))
+		require.NoError(t, err)
+		require.False(t, report.Finished())
+		require.Len(t, report.Scripts, 1)
+
+		_, ok := report.Failed()
+		require.False(t, ok)
+	})
+
+	t.Run("ignores-unknown-records", func(t *testing.T) {
+		report, err := parseDurableStartupReport([]byte("start\t1700000000\nfuture\tx\n\nend\t1700000001\t0\n"))
+		require.NoError(t, err)
+		require.True(t, report.Finished())
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, status := range []string{
+			"start\n",
+			"start\tnow\n",
+			"end\t1700000001\n",
+			"script\t000-default/000-cmd.sh\t1\t2\t0\n",
+			"script\t000-default/000-cmd.sh\t1\t2\tx\t\n",
+			"script\t000-default/000-cmd.sh\t1\t2\t0\tzz\n",
+		} {
+			_, err := parseDurableStartupReport([]byte(status))
+			require.Error(t, err, status)
+		}
+	})
+}
@@ -8,15 +8,20 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/moby/moby/client"
 )
 
-// File represents a file that will be copied when container starts
+// File represents a file that will be copied when container starts.
+// Its content is read from the first source set, in this order:
+// [File.Template], [File.Reader], [File.FS] and [File.HostPath].
 type File struct {
 	// Reader the reader to read the file from.
 	// It takes precedence over [HostPath].
@@ -26,6 +31,31 @@ type File struct {
 	// If [Reader] is not specified, the file will be read from the host path.
 	HostPath string
 
+	// FS the file system to read the file or the directory at [FSPath] from,
+	// e.g. an [embed.FS] of test fixtures. It takes precedence over [HostPath].
+	FS fs.FS
+
+	// FSPath the slash-separated path of the file or the directory in [FS].
+	// Defaults to ".", copying the whole file system. The content of a directory
+	// is copied to the container path, see [Container.CopyFSToContainer].
+	FSPath string
+
+	// Template the [text/template] source of the file, rendered against the [TemplateContext]
+	// of the container when the file is copied. It takes precedence over all the other sources.
+	Template string
+
+	// AfterStart copies the file once the container is started, before waiting for it to be ready,
+	// instead of once it's created, e.g. to render the mapped ports of the container in a template.
+	AfterStart bool
+
+	// UID the user ID owning the file, or the entries of the directory.
+	// The owner is only set if UID or GID is not zero.
+	UID int
+
+	// GID the group ID owning the file, or the entries of the directory.
+	// The owner is only set if UID or GID is not zero.
+	GID int
+
 	// ContainerPath the path to the file in the container.
 	// Use the slash character that matches the path separator of the operating system
 	// for the container.
@@ -33,18 +63,143 @@ type File struct {
 
 	// Mode the mode of the file
 	Mode int64
+
+	// CopyOptions the options to copy a directory from the host path or the file system, e.g. to preserve
+	// the modes of its files or to exclude some of them. Only [CopyOwner] applies to single files.
+	CopyOptions []CopyOption
+}
+
+// ErrUnsafeArchivePath is returned when an archive copied from a container contains
+// an entry, or a link, pointing outside the target directory.
+var ErrUnsafeArchivePath = errors.New("unsafe path in archive")
+
+// symlinkMode is the way symbolic links are copied into a container.
+type symlinkMode int
+
+const (
+	// symlinksSkip symbolic links are skipped, logging a warning.
+	symlinksSkip symlinkMode = iota
+
+	// symlinksPreserve symbolic links are copied as links.
+	symlinksPreserve
+
+	// symlinksFollow the targets of symbolic links are copied.
+	symlinksFollow
+)
+
+// CopyOption is an option to customize how a directory is copied into a container
+// with [Container.CopyDirToContainerWithOptions] or [Container.CopyFSToContainer]. Only [CopyOwner]
+// applies to single files copied with [Container.CopyToContainerWithOptions].
+type CopyOption func(*copyOptions)
+
+// copyOptions the options to copy a directory into a container.
+type copyOptions struct {
+	preserveModes bool
+	owner         *[2]int
+	symlinks      symlinkMode
+	include       []string
+	exclude       []string
+}
+
+// CopyPreserveModes keeps the original modes of the copied files and directories,
+// instead of using the same mode for every entry.
+func CopyPreserveModes() CopyOption {
+	return func(o *copyOptions) {
+		o.preserveModes = true
+	}
+}
+
+// CopyOwner sets the user and group IDs owning the copied files and directories.
+// By default, the IDs of the owner on the host are kept.
+func CopyOwner(uid int, gid int) CopyOption {
+	return func(o *copyOptions) {
+		o.owner = &[2]int{uid, gid}
+	}
+}
+
+// CopyPreserveSymlinks copies symbolic links as links, keeping their targets unchanged.
+// By default, symbolic links are skipped.
+func CopyPreserveSymlinks() CopyOption {
+	return func(o *copyOptions) {
+		o.symlinks = symlinksPreserve
+	}
+}
+
+// CopyFollowSymlinks copies the files and directories symbolic links point to, instead of the links.
+// By default, symbolic links are skipped.
+func CopyFollowSymlinks() CopyOption {
+	return func(o *copyOptions) {
+		o.symlinks = symlinksFollow
+	}
+}
+
+// CopyInclude only copies the files matching at least one of the glob patterns,
+// in the [path.Match] syntax. Directories are always copied, unless excluded.
+// Patterns are matched against the slash-separated path relative to the copied directory,
+// and patterns without a slash are matched against the base name too.
+func CopyInclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// CopyExclude skips the files and directories, with their content, matching at least one
+// of the glob patterns. Patterns are matched as in [CopyInclude], and take precedence over them.
+func CopyExclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// newCopyOptions returns the copy options, validating the glob patterns.
+func newCopyOptions(opts ...CopyOption) (copyOptions, error) {
+	var options copyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	for _, pattern := range slices.Concat(options.include, options.exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return copyOptions{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return options, nil
+}
+
+// matchAny returns true if the relative path matches one of the patterns.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 // validate validates the [File]
 func (f *File) validate() error {
-	if f.Reader == nil && f.HostPath == "" {
-		return errors.New("reader or host path must be specified")
+	if f.Reader == nil && f.HostPath == "" && f.FS == nil && f.Template == "" {
+		return errors.New("reader, host path, file system or template must be specified")
 	}
 
 	if f.ContainerPath == "" {
 		return errors.New("container path must be specified")
 	}
 
+	if f.Template != "" {
+		if _, err := f.parseTemplate(); err != nil {
+			return fmt.Errorf("parse template: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -90,9 +245,375 @@ func (c *Container) CopyFromContainer(ctx context.Context, containerFilePath str
 	return ret, nil
 }
 
+// CopyDirFromContainer copies the content of a directory of the container into a directory
+// of the host, which is created if it doesn't exist. Files, directories, and links are extracted
+// with their modes; other entries, such as devices, are skipped.
+//
+// Entries and links pointing outside the host directory, e.g. using "..", or absolute
+// symbolic links, are rejected with an error wrapping [ErrUnsafeArchivePath], and so are
+// entries whose parent is a symbolic link, and hard links to anything but a regular file.
+// Symbolic links are resolved through the other symbolic links of the archive, and are
+// created once all the other entries are extracted.
+func (c *Container) CopyDirFromContainer(ctx context.Context, containerDir string, hostDir string) error {
+	r, err := c.dockerClient.CopyFromContainer(ctx, c.ID(), client.CopyFromContainerOptions{
+		SourcePath: containerDir,
+	})
+	if err != nil {
+		return fmt.Errorf("copy from container: %w", err)
+	}
+	defer r.Content.Close()
+
+	if err := os.MkdirAll(hostDir, 0o755); err != nil {
+		return fmt.Errorf("create host dir: %w", err)
+	}
+
+	if err := extractDir(c.logger, tar.NewReader(r.Content), hostDir); err != nil {
+		return fmt.Errorf("extract %s: %w", containerDir, err)
+	}
+
+	return nil
+}
+
+// extractDir extracts a tar archive of a directory into dst, stripping the directory itself
+// from the names of the entries.
+func extractDir(logger *slog.Logger, tr *tar.Reader, dst string) error {
+	return extractArchive(logger, tr, dst, false)
+}
+
+// extractArchive extracts a tar archive into dst. When rootfs is false, the archive is the one
+// of a directory, which is stripped from the names of the entries, and symbolic links must be relative.
+// When rootfs is true, the archive is the one of a root filesystem, whose absolute symbolic links
+// are rewritten relative to dst.
+//
+// Symbolic links are created once all the other entries are extracted, and their targets are
+// resolved through all the symbolic links of the archive, so they can't point outside of dst,
+// whatever the order of the entries.
+func extractArchive(logger *slog.Logger, tr *tar.Reader, dst string, rootfs bool) error {
+	root, err := os.OpenRoot(dst)
+	if err != nil {
+		return fmt.Errorf("open root: %w", err)
+	}
+	defer root.Close()
+
+	entryPath := archiveEntryPath
+	if rootfs {
+		entryPath = rootfsEntryPath
+	}
+
+	links := archiveLinks{root: root}
+
+	first := !rootfs
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return links.create(dst)
+		}
+		if err != nil {
+			return fmt.Errorf("next entry: %w", err)
+		}
+
+		// the first entry is the copied directory itself
+		if first {
+			first = false
+			if header.Typeflag != tar.TypeDir {
+				return fmt.Errorf("%s is not a directory", header.Name)
+			}
+			continue
+		}
+
+		name, err := entryPath(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			continue
+		}
+
+		// links are created outside of the root, so their parents must not be links
+		if err := links.checkParents(name); err != nil {
+			return err
+		}
+		if err := checkParentsInRoot(root, name); err != nil {
+			return err
+		}
+
+		// a later entry replaces an earlier one
+		links.remove(name)
+
+		if err := mkdirAllInRoot(root, path.Dir(name)); err != nil {
+			return fmt.Errorf("create parent of %s: %w", name, err)
+		}
+
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirAllInRoot(root, name); err != nil {
+				return fmt.Errorf("create dir %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			if err := writeFileInRoot(root, name, tr, mode.Perm()); err != nil {
+				return fmt.Errorf("write file %s: %w", name, err)
+			}
+		case tar.TypeSymlink:
+			link := header.Linkname
+			if rootfs && path.IsAbs(link) {
+				// absolute links of a root filesystem point inside of it
+				rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(name)), filepath.FromSlash(strings.TrimPrefix(path.Clean(link), "/")))
+				if err != nil {
+					return fmt.Errorf("rewrite symbolic link %s: %w", name, err)
+				}
+				link = filepath.ToSlash(rel)
+			}
+
+			if path.IsAbs(link) {
+				return fmt.Errorf("%w: symbolic link %s points to %s", ErrUnsafeArchivePath, name, header.Linkname)
+			}
+
+			links.add(name, link)
+		case tar.TypeLink:
+			target, err := entryPath(header.Linkname)
+			if err != nil {
+				return err
+			}
+
+			// the target must be a file extracted from the archive, not reached through a link
+			if _, ok := links.targets[target]; ok {
+				return fmt.Errorf("%w: hard link %s points to %s, which is not a regular file", ErrUnsafeArchivePath, name, header.Linkname)
+			}
+			if err := links.checkParents(target); err != nil {
+				return err
+			}
+			if err := checkParentsInRoot(root, target); err != nil {
+				return err
+			}
+			fi, err := root.Lstat(filepath.FromSlash(target))
+			if err != nil {
+				return fmt.Errorf("hard link %s: %w", name, err)
+			}
+			if !fi.Mode().IsRegular() {
+				return fmt.Errorf("%w: hard link %s points to %s, which is not a regular file", ErrUnsafeArchivePath, name, header.Linkname)
+			}
+
+			if err := os.Link(filepath.Join(dst, filepath.FromSlash(target)), filepath.Join(dst, filepath.FromSlash(name))); err != nil {
+				return fmt.Errorf("create hard link %s: %w", name, err)
+			}
+		default:
+			logger.Debug("skipping unsupported archive entry", "name", name, "type", header.Typeflag)
+		}
+	}
+}
+
+// maxSymlinkHops the maximum number of symbolic links followed to resolve the target of a symbolic link.
+const maxSymlinkHops = 255
+
+// archiveLinks are the symbolic links of an archive being extracted, created once
+// all the other entries are extracted.
+type archiveLinks struct {
+	// root the extraction directory, whose existing symbolic links are not followed.
+	root *os.Root
+
+	// targets the targets of the symbolic links, relative to their directory, by cleaned name.
+	targets map[string]string
+
+	// names the names of the symbolic links, in the order of the archive.
+	names []string
+}
+
+// add records the symbolic link name, pointing to target.
+func (l *archiveLinks) add(name string, target string) {
+	if l.targets == nil {
+		l.targets = map[string]string{}
+	}
+	l.names = append(l.names, name)
+	l.targets[name] = target
+}
+
+// remove forgets the symbolic link name, if any, replaced by a later entry.
+func (l *archiveLinks) remove(name string) {
+	if _, ok := l.targets[name]; !ok {
+		return
+	}
+
+	delete(l.targets, name)
+	l.names = slices.DeleteFunc(l.names, func(n string) bool { return n == name })
+}
+
+// checkParents returns an error wrapping [ErrUnsafeArchivePath] if a parent directory
+// of name is a symbolic link of the archive.
+func (l *archiveLinks) checkParents(name string) error {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if _, ok := l.targets[dir]; ok {
+			return fmt.Errorf("%w: parent %s of %s is a symbolic link", ErrUnsafeArchivePath, dir, name)
+		}
+	}
+
+	return nil
+}
+
+// resolve resolves the path p, relative to the resolved directory dir, following the symbolic
+// links of the archive. It returns false if the path points outside of the extraction directory.
+func (l *archiveLinks) resolve(dir []string, p string, hops *int) ([]string, bool) {
+	resolved := slices.Clone(dir)
+	for _, part := range strings.Split(p, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return nil, false
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, part)
+		target, ok := l.targets[strings.Join(resolved, "/")]
+		if !ok {
+			// the symbolic links which were in the extraction directory are not followed
+			fi, err := l.root.Lstat(filepath.Join(resolved...))
+			if err == nil && fi.Mode()&fs.ModeSymlink != 0 {
+				return nil, false
+			}
+			continue
+		}
+
+		*hops++
+		if *hops > maxSymlinkHops {
+			return nil, false
+		}
+		if resolved, ok = l.resolve(resolved[:len(resolved)-1], target, hops); !ok {
+			return nil, false
+		}
+	}
+
+	return resolved, true
+}
+
+// create creates the symbolic links in dst, once their targets are checked to resolve inside of it.
+func (l *archiveLinks) create(dst string) error {
+	for _, name := range l.names {
+		target := l.targets[name]
+
+		var dir []string
+		if d := path.Dir(name); d != "." {
+			dir = strings.Split(d, "/")
+		}
+		hops := 0
+		if _, ok := l.resolve(dir, target, &hops); !ok {
+			return fmt.Errorf("%w: symbolic link %s points to %s", ErrUnsafeArchivePath, name, target)
+		}
+	}
+
+	for _, name := range l.names {
+		if err := os.Symlink(filepath.FromSlash(l.targets[name]), filepath.Join(dst, filepath.FromSlash(name))); err != nil {
+			return fmt.Errorf("create symbolic link %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// rootfsEntryPath returns the cleaned path of an archive entry of a root filesystem.
+// It returns an error if the path points outside of it.
+func rootfsEntryPath(name string) (string, error) {
+	cleaned := path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(cleaned) || !filepath.IsLocal(filepath.FromSlash(cleaned)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+
+	return cleaned, nil
+}
+
+// archiveEntryPath returns the cleaned path of an archive entry, relative to the copied directory,
+// stripping its first component. It returns an error if the path points outside of it.
+func archiveEntryPath(name string) (string, error) {
+	slashed := strings.TrimPrefix(filepath.ToSlash(name), "./")
+
+	first, rel, _ := strings.Cut(slashed, "/")
+	if rel == "" {
+		rel = "."
+	}
+
+	if path.IsAbs(slashed) || first == ".." || !filepath.IsLocal(filepath.FromSlash(path.Clean(rel))) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafeArchivePath, name)
+	}
+
+	return path.Clean(rel), nil
+}
+
+// checkParentsInRoot returns an error wrapping [ErrUnsafeArchivePath] if a parent directory
+// of name in root is a symbolic link, so no entry is created nor read through a link.
+// Parents which don't exist yet are created as directories.
+func checkParentsInRoot(root *os.Root, name string) error {
+	dir := path.Dir(name)
+	if dir == "." {
+		return nil
+	}
+
+	parent := ""
+	for _, part := range strings.Split(dir, "/") {
+		parent = path.Join(parent, part)
+
+		fi, err := root.Lstat(filepath.FromSlash(parent))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("lstat %s: %w", parent, err)
+		}
+
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: parent %s of %s is a symbolic link", ErrUnsafeArchivePath, parent, name)
+		}
+	}
+
+	return nil
+}
+
+// mkdirAllInRoot creates a directory and its parents in root.
+func mkdirAllInRoot(root *os.Root, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+
+	if err := mkdirAllInRoot(root, path.Dir(dir)); err != nil {
+		return err
+	}
+
+	err := root.Mkdir(filepath.FromSlash(dir), 0o755)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	return nil
+}
+
+// writeFileInRoot writes the content of r to a file in root.
+func writeFileInRoot(root *os.Root, name string, r io.Reader, perm os.FileMode) error {
+	f, err := root.OpenFile(filepath.FromSlash(name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
 // CopyDirToContainer copies a directory to a container, using the parent directory
-// of the container path as the target directory.
+// of the container path as the target directory. Every entry gets the given file mode,
+// the IDs of its owner on the host are kept, and symbolic links are skipped.
 func (c *Container) CopyDirToContainer(ctx context.Context, hostDirPath string, containerFilePath string, fileMode int64) error {
+	return c.CopyDirToContainerWithOptions(ctx, hostDirPath, containerFilePath, fileMode)
+}
+
+// CopyDirToContainerWithOptions copies a directory to a container, as [Container.CopyDirToContainer] does,
+// using the [CopyOption] functions to change how the entries are copied, or to select the copied files
+// with glob patterns.
+func (c *Container) CopyDirToContainerWithOptions(ctx context.Context, hostDirPath string, containerFilePath string, fileMode int64, opts ...CopyOption) error {
 	dir, err := isDir(hostDirPath)
 	if err != nil {
 		return fmt.Errorf("is dir: %w", err)
@@ -103,7 +624,7 @@ func (c *Container) CopyDirToContainer(ctx context.Context, hostDirPath string,
 		return fmt.Errorf("path %s is not a directory", hostDirPath)
 	}
 
-	buffer, err := tarDir(c.logger, hostDirPath, fileMode)
+	buffer, err := tarDir(c.logger, hostDirPath, fileMode, opts...)
 	if err != nil {
 		return fmt.Errorf("tar dir: %w", err)
 	}
@@ -122,14 +643,20 @@ func (c *Container) CopyDirToContainer(ctx context.Context, hostDirPath string,
 	return nil
 }
 
-// CopyToContainer copies fileContent data to a file in container
+// CopyToContainer copies fileContent data to a file in container.
 func (c *Container) CopyToContainer(ctx context.Context, fileContent []byte, containerFilePath string, fileMode int64) error {
+	return c.CopyToContainerWithOptions(ctx, fileContent, containerFilePath, fileMode)
+}
+
+// CopyToContainerWithOptions copies fileContent data to a file in container, as [Container.CopyToContainer] does.
+// Use [CopyOwner] to set the owner of the file.
+func (c *Container) CopyToContainerWithOptions(ctx context.Context, fileContent []byte, containerFilePath string, fileMode int64, opts ...CopyOption) error {
 	contentFn := func(tw io.Writer) error {
 		_, err := tw.Write(fileContent)
 		return err
 	}
 
-	buffer, err := tarFile(containerFilePath, contentFn, int64(len(fileContent)), fileMode)
+	buffer, err := tarFile(containerFilePath, contentFn, int64(len(fileContent)), fileMode, opts...)
 	if err != nil {
 		return fmt.Errorf("tar file: %w", err)
 	}
@@ -166,7 +693,12 @@ func isDir(path string) (bool, error) {
 }
 
 // tarDir compress a directory using tar + gzip algorithms
-func tarDir(logger *slog.Logger, src string, fileMode int64) (*bytes.Buffer, error) {
+func tarDir(logger *slog.Logger, src string, fileMode int64, opts ...CopyOption) (*bytes.Buffer, error) {
+	options, err := newCopyOptions(opts...)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+
 	// always pass src as absolute path
 	abs, err := filepath.Abs(src)
 	if err != nil {
@@ -182,72 +714,189 @@ func tarDir(logger *slog.Logger, src string, fileMode int64) (*bytes.Buffer, err
 	zr := gzip.NewWriter(buffer)
 	tw := tar.NewWriter(zr)
 
-	_, baseDir := filepath.Split(src)
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return buffer, fmt.Errorf("stat: %w", err)
+	}
+
 	// keep the path relative to the parent directory
-	index := strings.LastIndex(src, baseDir)
+	dw := &dirTarWriter{
+		tw:       tw,
+		logger:   logger,
+		fileMode: fileMode,
+		options:  options,
+		visited:  map[string]bool{},
+	}
+	if err := dw.add(src, filepath.Base(src), ".", fi); err != nil {
+		return buffer, err
+	}
 
-	// walk through every file in the folder
-	err = filepath.Walk(src, func(file string, fi os.FileInfo, errFn error) error {
-		if errFn != nil {
-			return fmt.Errorf("walk the file system: %w", errFn)
-		}
+	// produce tar
+	if err := tw.Close(); err != nil {
+		return buffer, fmt.Errorf("close tar file: %w", err)
+	}
+	// produce gzip
+	if err := zr.Close(); err != nil {
+		return buffer, fmt.Errorf("close gzip file: %w", err)
+	}
+
+	return buffer, nil
+}
+
+// dirTarWriter writes the entries of a host directory to a tar archive.
+type dirTarWriter struct {
+	tw       *tar.Writer
+	logger   *slog.Logger
+	fileMode int64
+	options  copyOptions
+
+	// visited the real paths of the directories already written, to break symbolic link cycles.
+	visited map[string]bool
+}
 
-		// if a symlink, skip file
-		if fi.Mode().Type() == os.ModeSymlink {
-			logger.Warn("skipping symlink", "file", file)
+// add writes a host path, named name in the archive, and rel relative to the copied directory.
+// Directories are written recursively.
+func (w *dirTarWriter) add(hostPath string, name string, rel string, fi os.FileInfo) error {
+	if rel != "." && matchAny(w.options.exclude, rel) {
+		return nil
+	}
+
+	switch {
+	case fi.Mode().Type() == os.ModeSymlink:
+		return w.addSymlink(hostPath, name, rel, fi)
+	case fi.IsDir():
+		return w.addDir(hostPath, name, rel, fi)
+	case fi.Mode().IsRegular():
+		if len(w.options.include) > 0 && !matchAny(w.options.include, rel) {
 			return nil
 		}
 
-		// generate tar header
-		header, err := tar.FileInfoHeader(fi, file)
+		if err := w.writeHeader(fi, name, ""); err != nil {
+			return err
+		}
+
+		data, err := os.Open(hostPath)
 		if err != nil {
-			return fmt.Errorf("file info header: %w", err)
+			return fmt.Errorf("open file: %w", err)
 		}
+		defer data.Close()
+
+		if _, err := io.Copy(w.tw, data); err != nil {
+			return fmt.Errorf("copy: %w", err)
+		}
+
+		return nil
+	default:
+		w.logger.Warn("skipping unsupported file", "file", hostPath, "mode", fi.Mode().String())
+		return nil
+	}
+}
+
+// addDir writes a directory and its entries.
+func (w *dirTarWriter) addDir(hostPath string, name string, rel string, fi os.FileInfo) error {
+	realPath, err := filepath.EvalSymlinks(hostPath)
+	if err != nil {
+		return fmt.Errorf("eval symlinks: %w", err)
+	}
+	if w.visited[realPath] {
+		w.logger.Warn("skipping symlink cycle", "file", hostPath)
+		return nil
+	}
+	w.visited[realPath] = true
+	defer delete(w.visited, realPath)
+
+	if err := w.writeHeader(fi, name, ""); err != nil {
+		return err
+	}
 
-		// see https://pkg.go.dev/archive/tar#FileInfoHeader:
-		// Since fs.FileInfo's Name method only returns the base name of the file it describes,
-		// it may be necessary to modify Header.Name to provide the full path name of the file.
-		header.Name = filepath.ToSlash(file[index:])
-		header.Mode = fileMode
+	entries, err := os.ReadDir(hostPath)
+	if err != nil {
+		return fmt.Errorf("read dir: %w", err)
+	}
 
-		// write header
-		if err := tw.WriteHeader(header); err != nil {
-			return fmt.Errorf("write header: %w", err)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("file info: %w", err)
 		}
 
-		// if not a dir, write file content
-		if !fi.IsDir() {
-			data, err := os.Open(file)
-			if err != nil {
-				return fmt.Errorf("open file: %w", err)
-			}
-			defer data.Close()
-			if _, err := io.Copy(tw, data); err != nil {
-				return fmt.Errorf("copy: %w", err)
-			}
+		entryRel := entry.Name()
+		if rel != "." {
+			entryRel = rel + "/" + entry.Name()
 		}
+
+		if err := w.add(filepath.Join(hostPath, entry.Name()), name+"/"+entry.Name(), entryRel, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addSymlink writes a symbolic link, as a link or as its target, depending on the options.
+func (w *dirTarWriter) addSymlink(hostPath string, name string, rel string, fi os.FileInfo) error {
+	switch w.options.symlinks {
+	case symlinksPreserve:
+		if len(w.options.include) > 0 && !matchAny(w.options.include, rel) {
+			return nil
+		}
+
+		target, err := os.Readlink(hostPath)
+		if err != nil {
+			return fmt.Errorf("read link: %w", err)
+		}
+
+		return w.writeHeader(fi, name, target)
+	case symlinksFollow:
+		targetInfo, err := os.Stat(hostPath)
+		if err != nil {
+			return fmt.Errorf("follow symlink: %w", err)
+		}
+
+		return w.add(hostPath, name, rel, targetInfo)
+	default:
+		w.logger.Warn("skipping symlink", "file", hostPath)
 		return nil
-	})
+	}
+}
+
+// writeHeader writes the tar header of an entry, applying the mode and owner options.
+func (w *dirTarWriter) writeHeader(fi os.FileInfo, name string, link string) error {
+	header, err := tar.FileInfoHeader(fi, link)
 	if err != nil {
-		return buffer, err
+		return fmt.Errorf("file info header: %w", err)
 	}
 
-	// produce tar
-	if err := tw.Close(); err != nil {
-		return buffer, fmt.Errorf("close tar file: %w", err)
+	// see https://pkg.go.dev/archive/tar#FileInfoHeader:
+	// Since fs.FileInfo's Name method only returns the base name of the file it describes,
+	// it may be necessary to modify Header.Name to provide the full path name of the file.
+	header.Name = name
+
+	if !w.options.preserveModes {
+		header.Mode = w.fileMode
 	}
-	// produce gzip
-	if err := zr.Close(); err != nil {
-		return buffer, fmt.Errorf("close gzip file: %w", err)
+
+	if w.options.owner != nil {
+		header.Uid, header.Gid = w.options.owner[0], w.options.owner[1]
+		header.Uname, header.Gname = "", ""
 	}
 
-	return buffer, nil
+	if err := w.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	return nil
 }
 
 // tarFile compress a single file using tar + gzip algorithms
-func tarFile(basePath string, fileContent func(tw io.Writer) error, fileContentSize int64, fileMode int64) (*bytes.Buffer, error) {
+func tarFile(basePath string, fileContent func(tw io.Writer) error, fileContentSize int64, fileMode int64, opts ...CopyOption) (*bytes.Buffer, error) {
 	buffer := &bytes.Buffer{}
 
+	var options copyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	zr := gzip.NewWriter(buffer)
 	tw := tar.NewWriter(zr)
 
@@ -256,6 +905,9 @@ func tarFile(basePath string, fileContent func(tw io.Writer) error, fileContentS
 		Mode: fileMode,
 		Size: fileContentSize,
 	}
+	if options.owner != nil {
+		hdr.Uid, hdr.Gid = options.owner[0], options.owner[1]
+	}
 	if err := tw.WriteHeader(hdr); err != nil {
 		return buffer, fmt.Errorf("write header: %w", err)
 	}
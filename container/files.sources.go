@@ -0,0 +1,364 @@
+package container
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/moby/moby/client"
+)
+
+// TemplateContext is the data templated files are rendered against. See [File.Template].
+type TemplateContext struct {
+	// ID the ID of the container.
+	ID string
+
+	// Name the name of the container, without the leading slash.
+	Name string
+
+	// Env the environment variables of the container.
+	Env map[string]string
+
+	// NetworkAliases the aliases of the container, by network name.
+	NetworkAliases map[string][]string
+
+	// Host the host of the Docker daemon, where the ports of the container are mapped.
+	Host string
+
+	// Ports the first host port each container port is mapped to, by container port,
+	// e.g. "8080/tcp". Ports are only mapped once the container is started,
+	// see [File.AfterStart].
+	Ports map[string]uint16
+}
+
+// TemplateContext returns the data templated files are rendered against,
+// from the current state of the container.
+func (c *Container) TemplateContext(ctx context.Context) (TemplateContext, error) {
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return TemplateContext{}, fmt.Errorf("inspect: %w", err)
+	}
+
+	data := TemplateContext{
+		ID:             c.ID(),
+		Name:           strings.TrimPrefix(inspect.Container.Name, "/"),
+		Env:            map[string]string{},
+		NetworkAliases: map[string][]string{},
+		Ports:          map[string]uint16{},
+	}
+
+	if inspect.Container.Config != nil {
+		for _, env := range inspect.Container.Config.Env {
+			key, value, _ := strings.Cut(env, "=")
+			data.Env[key] = value
+		}
+	}
+
+	if inspect.Container.NetworkSettings != nil {
+		for name, nw := range inspect.Container.NetworkSettings.Networks {
+			if nw != nil {
+				data.NetworkAliases[name] = nw.Aliases
+			}
+		}
+	}
+
+	bindings, err := c.PortBindings(ctx)
+	if err != nil {
+		return TemplateContext{}, fmt.Errorf("port bindings: %w", err)
+	}
+	for _, pb := range bindings {
+		if len(pb.Bindings) > 0 {
+			data.Ports[pb.Port.String()] = pb.Bindings[0].HostPort
+		}
+	}
+
+	data.Host, err = c.Host(ctx)
+	if err != nil {
+		return TemplateContext{}, fmt.Errorf("host: %w", err)
+	}
+
+	return data, nil
+}
+
+// parseTemplate parses the template of the file.
+func (f *File) parseTemplate() (*template.Template, error) {
+	return template.New(path.Base(f.ContainerPath)).Option("missingkey=error").Parse(f.Template)
+}
+
+// render renders the template of the file against data.
+func (f *File) render(data TemplateContext) ([]byte, error) {
+	tmpl, err := f.parseTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return nil, fmt.Errorf("execute template: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// copyOptions returns the copy options of the file, including its owner.
+func (f *File) copyOptions() []CopyOption {
+	opts := f.CopyOptions
+	if f.UID != 0 || f.GID != 0 {
+		opts = append(opts[:len(opts):len(opts)], CopyOwner(f.UID, f.GID))
+	}
+
+	return opts
+}
+
+// copyFile copies a file, from any of its sources, to the container.
+// Files with copy options require the container to implement [ContainerFileCopier].
+func copyFile(ctx context.Context, c ContainerInfo, fileOperator ContainerFileOperator, f File) error {
+	if err := f.validate(); err != nil {
+		return fmt.Errorf("invalid file: %w", err)
+	}
+
+	opts := f.copyOptions()
+
+	var bs []byte
+	var err error
+	switch {
+	case f.Template != "":
+		templater, ok := c.(ContainerTemplater)
+		if !ok {
+			return errors.New("container does not support templates")
+		}
+
+		data, err := templater.TemplateContext(ctx)
+		if err != nil {
+			return fmt.Errorf("template context: %w", err)
+		}
+
+		bs, err = f.render(data)
+		if err != nil {
+			return fmt.Errorf("render %s: %w", f.ContainerPath, err)
+		}
+	case f.Reader != nil:
+		// Bytes takes precedence over HostFilePath
+		bs, err = io.ReadAll(f.Reader)
+		if err != nil {
+			return fmt.Errorf("read all: %w", err)
+		}
+	case f.FS != nil:
+		fileCopier, ok := c.(ContainerFileCopier)
+		if !ok {
+			return errors.New("container does not support copying from file systems")
+		}
+
+		if err := fileCopier.CopyFSToContainer(ctx, f.FS, f.FSPath, f.ContainerPath, f.Mode, opts...); err != nil {
+			return fmt.Errorf("copy fs to container: %w", err)
+		}
+		return nil
+	default:
+		// no reader, read from host path, checking if it's a directory first
+		ok, err := isDir(f.HostPath)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			if err := copyDirToContainer(ctx, c, fileOperator, f, opts); err != nil {
+				return fmt.Errorf("copy dir to container: %w", err)
+			}
+			return nil
+		}
+
+		bs, err = os.ReadFile(f.HostPath)
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+	}
+
+	if err := copyToContainer(ctx, c, fileOperator, f, bs, opts); err != nil {
+		return fmt.Errorf("copy to container at %s: %w", f.ContainerPath, err)
+	}
+
+	return nil
+}
+
+// copyToContainer copies the content of the file to the container, with the copy options, if any.
+func copyToContainer(ctx context.Context, c ContainerInfo, fileOperator ContainerFileOperator, f File, content []byte, opts []CopyOption) error {
+	if fileCopier, ok := c.(ContainerFileCopier); ok {
+		return fileCopier.CopyToContainerWithOptions(ctx, content, f.ContainerPath, f.Mode, opts...)
+	}
+
+	if len(opts) > 0 {
+		return errors.New("container does not support copy options")
+	}
+
+	return fileOperator.CopyToContainer(ctx, content, f.ContainerPath, f.Mode)
+}
+
+// copyDirToContainer copies the host directory of the file to the container, with the copy options, if any.
+func copyDirToContainer(ctx context.Context, c ContainerInfo, fileOperator ContainerFileOperator, f File, opts []CopyOption) error {
+	if fileCopier, ok := c.(ContainerFileCopier); ok {
+		return fileCopier.CopyDirToContainerWithOptions(ctx, f.HostPath, f.ContainerPath, f.Mode, opts...)
+	}
+
+	if len(opts) > 0 {
+		return errors.New("container does not support copy options")
+	}
+
+	return fileOperator.CopyDirToContainer(ctx, f.HostPath, f.ContainerPath, f.Mode)
+}
+
+// CopyFSToContainer copies a file or a directory of a file system, e.g. an [embed.FS],
+// to the container path. Use "." as fsPath to copy the whole file system.
+//
+// Unlike [Container.CopyDirToContainer], the content of a directory is copied to the container path
+// itself, whose parent directory must exist. The options are the same as for [Container.CopyDirToContainerWithOptions],
+// although symbolic links are always skipped.
+func (c *Container) CopyFSToContainer(ctx context.Context, fsys fs.FS, fsPath string, containerPath string, fileMode int64, opts ...CopyOption) error {
+	if fsPath == "" {
+		fsPath = "."
+	}
+
+	fi, err := fs.Stat(fsys, fsPath)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", fsPath, err)
+	}
+
+	if !fi.IsDir() {
+		bs, err := fs.ReadFile(fsys, fsPath)
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+
+		return c.CopyToContainerWithOptions(ctx, bs, containerPath, fileMode, opts...)
+	}
+
+	cleaned := path.Clean(containerPath)
+	parent, name := path.Dir(cleaned), path.Base(cleaned)
+	if cleaned == "/" {
+		parent, name = "/", "."
+	}
+
+	buffer, err := tarFS(c.logger, fsys, fsPath, name, fileMode, opts...)
+	if err != nil {
+		return fmt.Errorf("tar fs: %w", err)
+	}
+
+	_, err = c.dockerClient.CopyToContainer(ctx, c.ID(), client.CopyToContainerOptions{
+		DestinationPath: parent,
+		Content:         buffer,
+	})
+	if err != nil {
+		return fmt.Errorf("copy to container: %w", err)
+	}
+
+	return nil
+}
+
+// tarFS compress a directory of a file system using tar + gzip algorithms,
+// naming the directory name in the archive.
+func tarFS(logger *slog.Logger, fsys fs.FS, root string, name string, fileMode int64, opts ...CopyOption) (*bytes.Buffer, error) {
+	options, err := newCopyOptions(opts...)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+
+	buffer := &bytes.Buffer{}
+
+	// tar > gzip > buffer
+	zr := gzip.NewWriter(buffer)
+	tw := tar.NewWriter(zr)
+
+	w := &dirTarWriter{
+		tw:       tw,
+		logger:   logger,
+		fileMode: fileMode,
+		options:  options,
+	}
+
+	err = fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel := "."
+		if p != root {
+			rel = p
+			if root != "." {
+				rel = strings.TrimPrefix(p, root+"/")
+			}
+		}
+
+		if rel != "." && matchAny(options.exclude, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		entryName := name
+		if rel != "." {
+			entryName = path.Join(name, rel)
+		}
+
+		switch {
+		case d.IsDir():
+			fi, err := d.Info()
+			if err != nil {
+				return fmt.Errorf("file info: %w", err)
+			}
+
+			return w.writeHeader(fi, entryName, "")
+		case d.Type().IsRegular():
+			if len(options.include) > 0 && !matchAny(options.include, rel) {
+				return nil
+			}
+
+			fi, err := d.Info()
+			if err != nil {
+				return fmt.Errorf("file info: %w", err)
+			}
+
+			if err := w.writeHeader(fi, entryName, ""); err != nil {
+				return err
+			}
+
+			data, err := fsys.Open(p)
+			if err != nil {
+				return fmt.Errorf("open file: %w", err)
+			}
+			defer data.Close()
+
+			if _, err := io.Copy(tw, data); err != nil {
+				return fmt.Errorf("copy: %w", err)
+			}
+
+			return nil
+		default:
+			logger.Warn("skipping unsupported file", "file", p, "mode", d.Type().String())
+			return nil
+		}
+	})
+	if err != nil {
+		return buffer, fmt.Errorf("walk %s: %w", root, err)
+	}
+
+	// produce tar
+	if err := tw.Close(); err != nil {
+		return buffer, fmt.Errorf("close tar file: %w", err)
+	}
+	// produce gzip
+	if err := zr.Close(); err != nil {
+		return buffer, fmt.Errorf("close gzip file: %w", err)
+	}
+
+	return buffer, nil
+}
@@ -4,13 +4,16 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -47,6 +50,80 @@ func TestFile_validate(t *testing.T) {
 		err := file.validate()
 		require.NoError(t, err)
 	})
+
+	t.Run("fs", func(t *testing.T) {
+		file := File{
+			FS:            fstest.MapFS{},
+			ContainerPath: "/test",
+		}
+		err := file.validate()
+		require.NoError(t, err)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		file := File{
+			Template:      "id={{ .ID }}",
+			ContainerPath: "/test",
+		}
+		err := file.validate()
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid-template", func(t *testing.T) {
+		file := File{
+			Template:      "id={{ .ID ",
+			ContainerPath: "/test",
+		}
+		err := file.validate()
+		require.ErrorContains(t, err, "parse template")
+	})
+}
+
+func TestFile_render(t *testing.T) {
+	data := TemplateContext{
+		ID:             "1234567890abcdef",
+		Name:           "kafka",
+		Env:            map[string]string{"MODE": "cluster"},
+		NetworkAliases: map[string][]string{"backend": {"broker"}},
+		Host:           "localhost",
+		Ports:          map[string]uint16{"9092/tcp": 32768},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		file := File{
+			Template:      `{{ .Name }} {{ .Env.MODE }} {{ index .NetworkAliases.backend 0 }} {{ .Host }}:{{ index .Ports "9092/tcp" }}`,
+			ContainerPath: "/etc/app.conf",
+		}
+
+		bs, err := file.render(data)
+		require.NoError(t, err)
+		require.Equal(t, "kafka cluster broker localhost:32768", string(bs))
+	})
+
+	t.Run("missing-key", func(t *testing.T) {
+		file := File{
+			Template:      "{{ .Env.UNKNOWN }}",
+			ContainerPath: "/etc/app.conf",
+		}
+
+		_, err := file.render(data)
+		require.ErrorContains(t, err, "execute template")
+	})
+}
+
+func TestFile_copyOptions(t *testing.T) {
+	require.Empty(t, (&File{}).copyOptions())
+
+	opts := []CopyOption{CopyPreserveModes()}
+	file := File{CopyOptions: opts, UID: 1000, GID: 1000}
+
+	options, err := newCopyOptions(file.copyOptions()...)
+	require.NoError(t, err)
+	require.True(t, options.preserveModes)
+	require.Equal(t, &[2]int{1000, 1000}, options.owner)
+
+	// the options of the file are not modified
+	require.Len(t, opts, 1)
 }
 
 func TestIsDir(t *testing.T) {
@@ -122,6 +199,335 @@ func TestTarDir(t *testing.T) {
 	})
 }
 
+func TestTarDir_options(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	src := filepath.Join(t.TempDir(), "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "conf"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "node_modules"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "conf", "app.yaml"), []byte("app"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "conf", "app.log"), []byte("log"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "node_modules", "dep.js"), []byte("dep"), 0o644))
+	require.NoError(t, os.Symlink("conf", filepath.Join(src, "link")))
+	require.NoError(t, os.Symlink("..", filepath.Join(src, "conf", "loop")))
+
+	headersOf := func(t *testing.T, opts ...CopyOption) map[string]*tar.Header {
+		t.Helper()
+
+		buff, err := tarDir(logger, src, 0o755, opts...)
+		require.NoError(t, err)
+
+		gzr, err := gzip.NewReader(buff)
+		require.NoError(t, err)
+
+		headers := map[string]*tar.Header{}
+		tr := tar.NewReader(gzr)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return headers
+			}
+			require.NoError(t, err)
+			headers[header.Name] = header
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		headers := headersOf(t)
+
+		require.Len(t, headers, 7)
+		require.NotContains(t, headers, "src/link")
+		require.NotContains(t, headers, "src/conf/loop")
+		require.Equal(t, int64(0o755), headers["src/conf/app.yaml"].Mode)
+	})
+
+	t.Run("preserve-modes", func(t *testing.T) {
+		headers := headersOf(t, CopyPreserveModes())
+
+		require.Equal(t, int64(0o700), headers["src/run.sh"].Mode)
+		require.Equal(t, int64(0o600), headers["src/conf/app.yaml"].Mode)
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		headers := headersOf(t, CopyOwner(1000, 2000))
+
+		for name, header := range headers {
+			require.Equal(t, 1000, header.Uid, name)
+			require.Equal(t, 2000, header.Gid, name)
+			require.Empty(t, header.Uname, name)
+		}
+	})
+
+	t.Run("preserve-symlinks", func(t *testing.T) {
+		headers := headersOf(t, CopyPreserveSymlinks())
+
+		require.Equal(t, byte(tar.TypeSymlink), headers["src/link"].Typeflag)
+		require.Equal(t, "conf", headers["src/link"].Linkname)
+		require.Equal(t, "..", headers["src/conf/loop"].Linkname)
+	})
+
+	t.Run("follow-symlinks", func(t *testing.T) {
+		headers := headersOf(t, CopyFollowSymlinks())
+
+		require.Equal(t, byte(tar.TypeDir), headers["src/link"].Typeflag)
+		require.Equal(t, byte(tar.TypeReg), headers["src/link/app.yaml"].Typeflag)
+		// the cycle through the parent directory is skipped
+		require.NotContains(t, headers, "src/conf/loop")
+	})
+
+	t.Run("include-exclude", func(t *testing.T) {
+		headers := headersOf(t, CopyInclude("*.yaml", "*.sh"), CopyExclude("node_modules", "conf/*.log"))
+
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		require.ElementsMatch(t, []string{"src", "src/conf", "src/conf/app.yaml", "src/run.sh"}, names)
+	})
+
+	t.Run("invalid-pattern", func(t *testing.T) {
+		_, err := tarDir(logger, src, 0o755, CopyInclude("[a-"))
+		require.ErrorIs(t, err, path.ErrBadPattern)
+	})
+}
+
+func TestExtractDir(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	type entry struct {
+		name     string
+		typeflag byte
+		linkname string
+		content  string
+	}
+
+	archiveOf := func(t *testing.T, entries ...entry) *tar.Reader {
+		t.Helper()
+
+		var buff bytes.Buffer
+		tw := tar.NewWriter(&buff)
+		for _, e := range entries {
+			require.NoError(t, tw.WriteHeader(&tar.Header{
+				Name:     e.name,
+				Typeflag: e.typeflag,
+				Linkname: e.linkname,
+				Mode:     0o640,
+				Size:     int64(len(e.content)),
+			}))
+			_, err := tw.Write([]byte(e.content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, tw.Close())
+
+		return tar.NewReader(&buff)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		dst := t.TempDir()
+
+		err := extractDir(logger, archiveOf(t,
+			entry{name: "data/", typeflag: tar.TypeDir},
+			entry{name: "data/sub/", typeflag: tar.TypeDir},
+			entry{name: "data/sub/file.txt", typeflag: tar.TypeReg, content: "hello"},
+			entry{name: "data/deep/nested/file.txt", typeflag: tar.TypeReg, content: "nested"},
+			entry{name: "data/link", typeflag: tar.TypeSymlink, linkname: "sub/file.txt"},
+			entry{name: "data/hardlink", typeflag: tar.TypeLink, linkname: "data/sub/file.txt"},
+			entry{name: "data/fifo", typeflag: tar.TypeFifo},
+		), dst)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dst, "sub", "file.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(content))
+
+		fi, err := os.Stat(filepath.Join(dst, "sub", "file.txt"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+		content, err = os.ReadFile(filepath.Join(dst, "deep", "nested", "file.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "nested", string(content))
+
+		target, err := os.Readlink(filepath.Join(dst, "link"))
+		require.NoError(t, err)
+		require.Equal(t, "sub/file.txt", target)
+
+		content, err = os.ReadFile(filepath.Join(dst, "hardlink"))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(content))
+
+		_, err = os.Lstat(filepath.Join(dst, "fifo"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("not-a-directory", func(t *testing.T) {
+		err := extractDir(logger, archiveOf(t, entry{name: "file.txt", typeflag: tar.TypeReg}), t.TempDir())
+		require.ErrorContains(t, err, "is not a directory")
+	})
+
+	unsafe := []struct {
+		name  string
+		entry entry
+	}{
+		{name: "parent", entry: entry{name: "data/../../evil.txt", typeflag: tar.TypeReg}},
+		{name: "absolute", entry: entry{name: "/etc/evil.txt", typeflag: tar.TypeReg}},
+		{name: "absolute-symlink", entry: entry{name: "data/link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		{name: "escaping-symlink", entry: entry{name: "data/sub/link", typeflag: tar.TypeSymlink, linkname: "../../secret"}},
+		{name: "escaping-hardlink", entry: entry{name: "data/link", typeflag: tar.TypeLink, linkname: "../secret"}},
+	}
+
+	t.Run("unsafe/chained-symlinks", func(t *testing.T) {
+		parent := t.TempDir()
+		dst := filepath.Join(parent, "dst")
+		require.NoError(t, os.Mkdir(dst, 0o755))
+		victim := filepath.Join(parent, "victim")
+		require.NoError(t, os.WriteFile(victim, []byte("safe"), 0o600))
+
+		// each link is lexically local, but the hard link resolves through both of them
+		err := extractDir(logger, archiveOf(t,
+			entry{name: "root/", typeflag: tar.TypeDir},
+			entry{name: "root/d/", typeflag: tar.TypeDir},
+			entry{name: "root/d/l", typeflag: tar.TypeSymlink, linkname: ".."},
+			entry{name: "root/d/l/l2", typeflag: tar.TypeSymlink, linkname: ".."},
+			entry{name: "root/h", typeflag: tar.TypeLink, linkname: "root/d/l/l2/victim"},
+			entry{name: "root/h", typeflag: tar.TypeReg, content: "pwned"},
+		), dst)
+		require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+		content, err := os.ReadFile(victim)
+		require.NoError(t, err)
+		require.Equal(t, "safe", string(content))
+	})
+
+	t.Run("chained-symlinks", func(t *testing.T) {
+		dst := t.TempDir()
+
+		err := extractDir(logger, archiveOf(t,
+			entry{name: "data/", typeflag: tar.TypeDir},
+			entry{name: "data/usr/bin/vi", typeflag: tar.TypeSymlink, linkname: "../../bin/sh"},
+			entry{name: "data/bin/sh", typeflag: tar.TypeSymlink, linkname: "busybox"},
+			entry{name: "data/bin/busybox", typeflag: tar.TypeReg, content: "elf!"},
+		), dst)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(filepath.Join(dst, "usr", "bin", "vi"))
+		require.NoError(t, err)
+		require.Equal(t, "elf!", string(content))
+	})
+
+	// each symbolic link is lexically local, but resolves outside of dst through the other one
+	for name, entries := range map[string][]entry{
+		"symlink-through-symlink": {
+			{name: "root/d/l1", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "root/l2", typeflag: tar.TypeSymlink, linkname: "d/l1/.."},
+		},
+		"symlink-through-later-symlink": {
+			{name: "root/l2", typeflag: tar.TypeSymlink, linkname: "x/.."},
+			{name: "root/x", typeflag: tar.TypeSymlink, linkname: "."},
+		},
+	} {
+		t.Run("unsafe/"+name, func(t *testing.T) {
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "dst")
+			require.NoError(t, os.Mkdir(dst, 0o755))
+
+			err := extractDir(logger, archiveOf(t, append([]entry{
+				{name: "root/", typeflag: tar.TypeDir},
+				{name: "root/d/", typeflag: tar.TypeDir},
+			}, entries...)...), dst)
+			require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+			_, err = os.Lstat(filepath.Join(dst, "l2"))
+			require.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+
+	t.Run("unsafe/hardlink-to-symlink", func(t *testing.T) {
+		err := extractDir(logger, archiveOf(t,
+			entry{name: "data/", typeflag: tar.TypeDir},
+			entry{name: "data/link", typeflag: tar.TypeSymlink, linkname: "."},
+			entry{name: "data/hardlink", typeflag: tar.TypeLink, linkname: "data/link"},
+		), t.TempDir())
+		require.ErrorIs(t, err, ErrUnsafeArchivePath)
+	})
+
+	for _, tc := range unsafe {
+		t.Run("unsafe/"+tc.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "dst")
+			require.NoError(t, os.Mkdir(dst, 0o755))
+
+			err := extractDir(logger, archiveOf(t, entry{name: "data/", typeflag: tar.TypeDir}, tc.entry), dst)
+			require.ErrorIs(t, err, ErrUnsafeArchivePath)
+
+			entries, err := os.ReadDir(parent)
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestTarFS(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	fsys := fstest.MapFS{
+		"fixtures/init.sql":         {Data: []byte("create table t;"), Mode: 0o600},
+		"fixtures/seed/data.csv":    {Data: []byte("a,b")},
+		"fixtures/seed/data.tmp":    {Data: []byte("tmp")},
+		"fixtures/ignored/file.txt": {Data: []byte("ignored")},
+	}
+
+	entriesOf := func(t *testing.T, buff *bytes.Buffer) map[string]*tar.Header {
+		t.Helper()
+
+		gzr, err := gzip.NewReader(buff)
+		require.NoError(t, err)
+
+		headers := map[string]*tar.Header{}
+		tr := tar.NewReader(gzr)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return headers
+			}
+			require.NoError(t, err)
+			headers[header.Name] = header
+		}
+	}
+
+	t.Run("subdirectory", func(t *testing.T) {
+		buff, err := tarFS(logger, fsys, "fixtures", "docker-entrypoint-initdb.d", 0o644,
+			CopyExclude("ignored", "*.tmp"), CopyOwner(999, 999))
+		require.NoError(t, err)
+
+		headers := entriesOf(t, buff)
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		require.ElementsMatch(t, []string{
+			"docker-entrypoint-initdb.d",
+			"docker-entrypoint-initdb.d/init.sql",
+			"docker-entrypoint-initdb.d/seed",
+			"docker-entrypoint-initdb.d/seed/data.csv",
+		}, names)
+
+		require.Equal(t, int64(0o644), headers["docker-entrypoint-initdb.d/init.sql"].Mode)
+		require.Equal(t, 999, headers["docker-entrypoint-initdb.d/init.sql"].Uid)
+	})
+
+	t.Run("root", func(t *testing.T) {
+		buff, err := tarFS(logger, fsys, ".", "data", 0o644, CopyInclude("*.sql"), CopyPreserveModes())
+		require.NoError(t, err)
+
+		headers := entriesOf(t, buff)
+		require.Contains(t, headers, "data/fixtures/seed")
+		require.NotContains(t, headers, "data/fixtures/seed/data.csv")
+		require.Equal(t, int64(0o600), headers["data/fixtures/init.sql"].Mode)
+	})
+}
+
 func TestTarFile(t *testing.T) {
 	b, err := os.ReadFile(filepath.Join(".", "testdata", "Dockerfile"))
 	require.NoError(t, err)
@@ -206,3 +612,47 @@ func untar(dst string, r io.Reader) error {
 		}
 	}
 }
+
+// fileOperatorContainer is a container implementing the file operations only, without copy options.
+type fileOperatorContainer struct {
+	ContainerInfo
+
+	dirs []string
+}
+
+func (c *fileOperatorContainer) CopyDirToContainer(_ context.Context, hostDirPath string, _ string, _ int64) error {
+	c.dirs = append(c.dirs, hostDirPath)
+	return nil
+}
+
+func (c *fileOperatorContainer) CopyToContainer(_ context.Context, _ []byte, _ string, _ int64) error {
+	return nil
+}
+
+func TestCopyFile_capabilities(t *testing.T) {
+	var (
+		_ ContainerFileOperator = (*Container)(nil)
+		_ ContainerFileCopier   = (*Container)(nil)
+	)
+
+	src := t.TempDir()
+	ctr := &fileOperatorContainer{}
+
+	t.Run("without-options", func(t *testing.T) {
+		require.NoError(t, copyFile(context.Background(), ctr, ctr, File{HostPath: src, ContainerPath: "/data"}))
+		require.Equal(t, []string{src}, ctr.dirs)
+	})
+
+	t.Run("with-options", func(t *testing.T) {
+		err := copyFile(context.Background(), ctr, ctr, File{HostPath: src, ContainerPath: "/data", UID: 1000})
+		require.ErrorContains(t, err, "does not support copy options")
+
+		err = copyFile(context.Background(), ctr, ctr, File{Reader: strings.NewReader("data"), ContainerPath: "/file", UID: 1000})
+		require.ErrorContains(t, err, "does not support copy options")
+	})
+
+	t.Run("file-system", func(t *testing.T) {
+		err := copyFile(context.Background(), ctr, ctr, File{FS: fstest.MapFS{"file": {}}, ContainerPath: "/data"})
+		require.ErrorContains(t, err, "does not support copying from file systems")
+	})
+}
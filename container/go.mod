@@ -8,6 +8,7 @@ replace (
 	github.com/docker/go-sdk/context => ../context
 	github.com/docker/go-sdk/image => ../image
 	github.com/docker/go-sdk/network => ../network
+	github.com/docker/go-sdk/volume => ../volume
 )
 
 require (
@@ -19,8 +20,10 @@ require (
 	github.com/docker/go-sdk/config v0.1.0-alpha013
 	github.com/docker/go-sdk/image v0.1.0-alpha015
 	github.com/docker/go-sdk/network v0.1.0-alpha013
+	github.com/docker/go-sdk/volume v0.1.0-alpha005
 	github.com/moby/moby/api v1.52.0
 	github.com/moby/moby/client v0.1.0
+	github.com/moby/patternmatcher v0.6.0
 	github.com/stretchr/testify v1.11.1
 	golang.org/x/sys v0.35.0
 )
@@ -43,7 +46,6 @@ require (
 	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/moby/docker-image-spec v1.3.1 // indirect
 	github.com/moby/go-archive v0.1.0 // indirect
-	github.com/moby/patternmatcher v0.6.0 // indirect
 	github.com/moby/sys/sequential v0.6.0 // indirect
 	github.com/moby/sys/user v0.4.0 // indirect
 	github.com/moby/sys/userns v0.1.0 // indirect
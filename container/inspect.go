@@ -2,21 +2,104 @@ package container
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/client"
 )
 
-// Inspect returns the container's raw info
+// inspectCacheMaxAge is the maximum age of a cached inspect result used by the
+// container methods reading data that rarely changes, such as port bindings or networks.
+const inspectCacheMaxAge = time.Second
+
+// inspectCache holds the last inspect result of a container.
+// Lifecycle transitions invalidate it, as they change the state, ports or networks.
+type inspectCache struct {
+	mtx       sync.Mutex
+	result    client.ContainerInspectResult
+	updatedAt time.Time
+	valid     bool
+}
+
+// get returns the cached result if it's valid and not older than maxAge.
+// A negative maxAge accepts a valid result of any age.
+func (ic *inspectCache) get(maxAge time.Duration) (client.ContainerInspectResult, bool) {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+
+	if !ic.valid {
+		return client.ContainerInspectResult{}, false
+	}
+
+	if maxAge >= 0 && time.Since(ic.updatedAt) > maxAge {
+		return client.ContainerInspectResult{}, false
+	}
+
+	return ic.result, true
+}
+
+// set stores a fresh inspect result.
+func (ic *inspectCache) set(result client.ContainerInspectResult) {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+
+	ic.result = result
+	ic.updatedAt = time.Now()
+	ic.valid = true
+}
+
+// invalidate discards the cached result.
+func (ic *inspectCache) invalidate() {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+
+	ic.result = client.ContainerInspectResult{}
+	ic.valid = false
+}
+
+// Inspect returns the container's raw info.
+// It always queries the Docker daemon, refreshing the cached inspect result.
 func (c *Container) Inspect(ctx context.Context) (client.ContainerInspectResult, error) {
 	inspect, err := c.dockerClient.ContainerInspect(ctx, c.ID(), client.ContainerInspectOptions{})
 	if err != nil {
 		return client.ContainerInspectResult{}, err
 	}
 
+	c.inspectCache.set(inspect)
+
 	return inspect, nil
 }
 
+// CachedInspect returns the cached inspect result of the container if it's not older than maxAge,
+// otherwise it queries the Docker daemon, refreshing the cache. A negative maxAge accepts a cached
+// result of any age, as long as it has not been invalidated.
+//
+// The cache is invalidated when the container is started, stopped or terminated,
+// and when the SDK connects it to a network. Changes made outside the SDK, e.g. a restart
+// by the daemon, an OOM kill or a docker stop, are only reflected while the container is
+// watched, see [Container.Watch], which invalidates the cache on the daemon events of the container.
+// Otherwise they are seen once the cached result is older than maxAge, or after [Container.Refresh].
+func (c *Container) CachedInspect(ctx context.Context, maxAge time.Duration) (client.ContainerInspectResult, error) {
+	if inspect, ok := c.inspectCache.get(maxAge); ok {
+		return inspect, nil
+	}
+
+	return c.Inspect(ctx)
+}
+
+// Refresh queries the Docker daemon for the container's info, refreshing the cached inspect result.
+// Use it after changing the container outside the SDK, e.g. connecting it to a network with the Docker client.
+func (c *Container) Refresh(ctx context.Context) error {
+	_, err := c.Inspect(ctx)
+	return err
+}
+
+// invalidateInspect discards the cached inspect result.
+func (c *Container) invalidateInspect() {
+	c.inspectCache.invalidate()
+}
+
 // InspectWithOptions returns the container's raw info, passing custom options.
 //
 // This method may be deprecated in the near future, to be replaced by functional options for Inspect.
@@ -0,0 +1,38 @@
+package container_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+)
+
+func TestContainer_CachedInspect_stoppedOutOfBand(t *testing.T) {
+	ctx := context.Background()
+
+	ctr, err := container.Run(ctx,
+		container.WithImage(alpineLatest),
+		container.WithEntrypoint("tail", "-f", "/dev/null"),
+	)
+	container.Cleanup(t, ctr)
+	require.NoError(t, err)
+
+	require.NoError(t, ctr.Watch(ctx))
+
+	inspect, err := ctr.CachedInspect(ctx, -1)
+	require.NoError(t, err)
+	require.True(t, inspect.Container.State.Running)
+
+	// stopped with the Docker client, as docker stop does
+	_, err = ctr.Client().ContainerStop(ctx, ctr.ID(), dockerclient.ContainerStopOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		inspect, err := ctr.CachedInspect(ctx, -1)
+		return err == nil && !inspect.Container.State.Running
+	}, 10*time.Second, 100*time.Millisecond)
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/netip"
-	"os"
 
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/api/types/network"
@@ -28,59 +26,37 @@ var defaultPreCreateHook = func(dockerClient client.SDKClient, dockerInput *cont
 }
 
 // defaultCopyFileToContainerHook is a hook that will copy files to the container after it's created
-// but before it's started
+// but before it's started, or after it's started for the files copied after start
 var defaultCopyFileToContainerHook = func(files []File) LifecycleHooks {
-	return LifecycleHooks{
-		PostCreates: []ContainerHook{
-			// copy files to container after it's created
-			func(ctx context.Context, c ContainerInfo) error {
-				fileOperator, ok := c.(ContainerFileOperator)
-				if !ok {
-					return errors.New("container does not support file operations")
-				}
-
-				for _, f := range files {
-					if err := f.validate(); err != nil {
-						return fmt.Errorf("invalid file: %w", err)
-					}
-
-					var bs []byte
-					var err error
-					if f.Reader != nil {
-						// Bytes takes precedence over HostFilePath
-						bs, err = io.ReadAll(f.Reader)
-						if err != nil {
-							return fmt.Errorf("read all: %w", err)
-						}
-					} else {
-						// no reader, read from host path, checking if it's a directory first
-						ok, err := isDir(f.HostPath)
-						if err != nil {
-							return err
-						}
-
-						if ok {
-							err := fileOperator.CopyDirToContainer(ctx, f.HostPath, f.ContainerPath, f.Mode)
-							if err != nil {
-								return fmt.Errorf("copy dir to container: %w", err)
-							}
-							continue
-						}
+	copyFiles := func(afterStart bool) ContainerHook {
+		return func(ctx context.Context, c ContainerInfo) error {
+			fileOperator, ok := c.(ContainerFileOperator)
+			if !ok {
+				return errors.New("container does not support file operations")
+			}
 
-						bs, err = os.ReadFile(f.HostPath)
-						if err != nil {
-							return fmt.Errorf("read file: %w", err)
-						}
-					}
+			for _, f := range files {
+				if f.AfterStart != afterStart {
+					continue
+				}
 
-					err = fileOperator.CopyToContainer(ctx, bs, f.ContainerPath, f.Mode)
-					if err != nil {
-						return fmt.Errorf("copy to container at %s: %w", f.ContainerPath, err)
-					}
+				if err := copyFile(ctx, c, fileOperator, f); err != nil {
+					return err
 				}
+			}
 
-				return nil
-			},
+			return nil
+		}
+	}
+
+	return LifecycleHooks{
+		PostCreates: []ContainerHook{
+			// copy files to container after it's created
+			copyFiles(false),
+		},
+		PostStarts: []ContainerHook{
+			// copy files to container after it's started
+			copyFiles(true),
 		},
 	}
 }
@@ -105,6 +81,9 @@ var defaultReadinessHook = func() LifecycleHooks {
 					}
 					c.Logger().Info("Waiting for container to be ready", "containerID", c.ShortID(), "image", c.Image(), "strategy", strategyDesc)
 					if err := strategy.WaitUntilReady(ctx, waiter); err != nil {
+						if recorder, ok := c.(waitErrorRecorder); ok {
+							recorder.recordWaitError(err)
+						}
 						return fmt.Errorf("wait until ready: %w", err)
 					}
 				}
@@ -196,10 +175,46 @@ func preCreateContainerHook(ctx context.Context, dockerClient client.SDKClient,
 
 	}
 
+	if def.user != "" {
+		dockerInput.User = def.user
+	}
+
+	if def.healthCheck != nil {
+		dockerInput.Healthcheck = def.healthCheck
+	}
+
+	// the standard input of a job is attached before the container is started
+	if def.stdin != nil {
+		dockerInput.OpenStdin = true
+		dockerInput.StdinOnce = true
+		dockerInput.AttachStdin = true
+	}
+
 	if def.configModifier != nil {
 		def.configModifier(dockerInput)
 	}
 
+	// typed mounts are applied before the modifier, so it can still override them
+	mounts, err := dockerMounts(def.mounts)
+	if err != nil {
+		return fmt.Errorf("mounts: %w", err)
+	}
+	hostConfig.Mounts = append(hostConfig.Mounts, mounts...)
+
+	// typed security options are validated against the daemon, and applied before the modifier too
+	if def.securityOpts != nil {
+		info, err := dockerClient.Info(ctx, dockerclient.InfoOptions{})
+		if err != nil {
+			return fmt.Errorf("docker info: %w", err)
+		}
+
+		if err := def.securityOpts.validateDaemon(info.Info); err != nil {
+			return fmt.Errorf("security options: %w", err)
+		}
+
+		def.securityOpts.apply(hostConfig)
+	}
+
 	if def.hostConfigModifier != nil {
 		def.hostConfigModifier(hostConfig)
 	}
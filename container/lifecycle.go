@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"reflect"
 	"strings"
@@ -60,6 +61,20 @@ type ContainerFileOperator interface {
 	CopyToContainer(ctx context.Context, fileContent []byte, containerFilePath string, fileMode int64) error
 }
 
+// ContainerFileCopier is an optional capability interface that can be used to copy files to the container
+// with [CopyOption]s, or from a file system. It's required to copy the files of a [File] with copy options,
+// an owner, or a file system.
+type ContainerFileCopier interface {
+	CopyDirToContainerWithOptions(ctx context.Context, hostDirPath string, containerFilePath string, fileMode int64, opts ...CopyOption) error
+	CopyFSToContainer(ctx context.Context, fsys fs.FS, fsPath string, containerPath string, fileMode int64, opts ...CopyOption) error
+	CopyToContainerWithOptions(ctx context.Context, fileContent []byte, containerFilePath string, fileMode int64, opts ...CopyOption) error
+}
+
+// ContainerTemplater is an optional capability interface that can be used to render templated files.
+type ContainerTemplater interface {
+	TemplateContext(ctx context.Context) (TemplateContext, error)
+}
+
 // ContainerWaiter is an optional capability interface that can be used to wait for the container to be ready.
 // It embeds the [wait.StrategyTarget] interface to allow the wait strategy to be used to wait for the container to be ready.
 type ContainerWaiter interface {
@@ -73,6 +88,12 @@ type ContainerStateManager interface {
 	Running(b bool)
 }
 
+// waitErrorRecorder is an optional capability interface that can be used to record the errors
+// of the wait strategy of the container, see [Container.WaitErrors].
+type waitErrorRecorder interface {
+	recordWaitError(err error)
+}
+
 // ContainerHook is a hook that is called after a container is created
 // It can be used to modify the state of the container after it is created,
 // using the different lifecycle hooks that are available:
@@ -284,45 +305,55 @@ var defaultPullHook = []DefinitionHook{
 			def.platform = platform
 		}
 
-		var shouldPullImage bool
+		// the definitions run together by RunAll share their identical pulls
+		key, shared := def.pullKey()
+		if !shared {
+			return pullImage(ctx, def, platform)
+		}
 
-		if def.alwaysPullImage {
-			shouldPullImage = true // If requested always attempt to pull image
-		} else {
-			img, err := def.dockerClient.ImageInspect(ctx, def.image)
-			if err != nil {
-				if !errdefs.IsNotFound(err) {
-					return err
-				}
-				shouldPullImage = true
-			}
-			if platform != nil && (img.Architecture != platform.Architecture || img.Os != platform.OS) {
-				shouldPullImage = true
+		return def.pulls.do(ctx, key, func() error {
+			return pullImage(ctx, def, platform)
+		})
+	},
+}
+
+// pullImage pulls the image of the definition if it is not present, if the platform is different,
+// or if it must always be pulled.
+func pullImage(ctx context.Context, def *Definition, platform *platforms.Platform) error {
+	var shouldPullImage bool
+
+	if def.alwaysPullImage {
+		shouldPullImage = true // If requested always attempt to pull image
+	} else {
+		img, err := def.dockerClient.ImageInspect(ctx, def.image)
+		if err != nil {
+			if !errdefs.IsNotFound(err) {
+				return err
 			}
+			shouldPullImage = true
+		}
+		if platform != nil && (img.Architecture != platform.Architecture || img.Os != platform.OS) {
+			shouldPullImage = true
 		}
+	}
 
-		if shouldPullImage {
-			pullOpts := []image.PullOption{image.WithPullClient(def.dockerClient)}
+	if shouldPullImage {
+		pullOpts := []image.PullOption{image.WithPullClient(def.dockerClient)}
 
-			// the caller can pass pull options to the definition to customize the pull behavior
-			pullOpts = append(pullOpts, def.pullOptions...)
+		// the caller can pass pull options to the definition to customize the pull behavior
+		pullOpts = append(pullOpts, def.pullOptions...)
 
-			// apply platform last
-			var pullOpt dockerclient.ImagePullOptions
-			if def.imagePlatform != "" {
-				p, err := platforms.Parse(def.imagePlatform)
-				if err != nil {
-					return fmt.Errorf("invalid platform %s: %w", def.imagePlatform, err)
-				}
-				pullOpt.Platforms = append(pullOpt.Platforms, p)
-			}
-			pullOpts = append(pullOpts, image.WithPullOptions(pullOpt))
+		// apply platform last
+		var pullOpt dockerclient.ImagePullOptions
+		if platform != nil {
+			pullOpt.Platforms = append(pullOpt.Platforms, *platform)
+		}
+		pullOpts = append(pullOpts, image.WithPullOptions(pullOpt))
 
-			if err := image.Pull(ctx, def.image, pullOpts...); err != nil {
-				return err
-			}
+		if err := image.Pull(ctx, def.image, pullOpts...); err != nil {
+			return err
 		}
+	}
 
-		return nil
-	},
+	return nil
 }
@@ -0,0 +1,303 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/moby/moby/api/types/mount"
+
+	"github.com/docker/go-sdk/volume"
+)
+
+// ErrInvalidMount is returned when a typed mount is missing required fields.
+var ErrInvalidMount = errors.New("invalid mount")
+
+// ContainerMount is a typed mount that will be added to the container's host config
+// before the container is created. The SDK provides implementations for the bind, volume,
+// tmpfs and image mount types: [BindMount], [VolumeMount], [TmpfsMount] and [ImageMount].
+type ContainerMount interface {
+	// Target returns the path inside the container where the mount is placed.
+	Target() string
+
+	// AsMount returns the Docker API representation of the mount.
+	AsMount() (mount.Mount, error)
+}
+
+// BindMount mounts a path of the host into the container.
+// It only works when the Docker daemon runs on the same host as the caller.
+type BindMount struct {
+	// HostPath the path on the host. It must be absolute.
+	HostPath string
+
+	// ContainerPath the path in the container.
+	ContainerPath string
+
+	// ReadOnly mounts the host path read-only.
+	ReadOnly bool
+
+	// Propagation the bind propagation mode, e.g. [mount.PropagationRPrivate].
+	// If empty, the daemon default is used.
+	Propagation mount.Propagation
+}
+
+// Target implements the [ContainerMount] interface.
+func (m BindMount) Target() string {
+	return m.ContainerPath
+}
+
+// AsMount implements the [ContainerMount] interface.
+func (m BindMount) AsMount() (mount.Mount, error) {
+	if m.HostPath == "" {
+		return mount.Mount{}, fmt.Errorf("%w: bind mount requires a host path", ErrInvalidMount)
+	}
+	if m.ContainerPath == "" {
+		return mount.Mount{}, fmt.Errorf("%w: bind mount requires a container path", ErrInvalidMount)
+	}
+
+	mnt := mount.Mount{
+		Type:     mount.TypeBind,
+		Source:   m.HostPath,
+		Target:   m.ContainerPath,
+		ReadOnly: m.ReadOnly,
+	}
+	if m.Propagation != "" {
+		mnt.BindOptions = &mount.BindOptions{Propagation: m.Propagation}
+	}
+
+	return mnt, nil
+}
+
+// VolumeMount mounts a Docker volume into the container.
+// The volume is identified either by [VolumeMount.Volume], typically created with [volume.New],
+// or by [VolumeMount.Name]. If both are empty, an anonymous volume is created by the daemon,
+// which is removed together with the container.
+type VolumeMount struct {
+	// Volume the volume to mount. It takes precedence over [VolumeMount.Name].
+	Volume *volume.Volume
+
+	// Name the name of the volume to mount. If the volume does not exist,
+	// the daemon creates it.
+	Name string
+
+	// ContainerPath the path in the container.
+	ContainerPath string
+
+	// ReadOnly mounts the volume read-only.
+	ReadOnly bool
+
+	// NoCopy prevents the daemon from populating an empty volume
+	// with the content of the image at the container path.
+	NoCopy bool
+
+	// Subpath the path inside the volume to mount, instead of its root.
+	Subpath string
+
+	// RemoveOnTerminate removes the volume when the container is terminated.
+	// It's meant to be used for volumes created by the SDK for a single container.
+	RemoveOnTerminate bool
+}
+
+// Target implements the [ContainerMount] interface.
+func (m VolumeMount) Target() string {
+	return m.ContainerPath
+}
+
+// AsMount implements the [ContainerMount] interface.
+func (m VolumeMount) AsMount() (mount.Mount, error) {
+	if m.ContainerPath == "" {
+		return mount.Mount{}, fmt.Errorf("%w: volume mount requires a container path", ErrInvalidMount)
+	}
+
+	mnt := mount.Mount{
+		Type:     mount.TypeVolume,
+		Source:   m.volumeName(),
+		Target:   m.ContainerPath,
+		ReadOnly: m.ReadOnly,
+	}
+	if m.NoCopy || m.Subpath != "" {
+		mnt.VolumeOptions = &mount.VolumeOptions{
+			NoCopy:  m.NoCopy,
+			Subpath: m.Subpath,
+		}
+	}
+
+	return mnt, nil
+}
+
+// volumeName returns the name of the volume, giving precedence to the typed volume.
+func (m VolumeMount) volumeName() string {
+	if m.Volume != nil && m.Volume.Volume != nil {
+		return m.Volume.Name
+	}
+
+	return m.Name
+}
+
+// TmpfsMount mounts a tmpfs filesystem into the container.
+type TmpfsMount struct {
+	// ContainerPath the path in the container.
+	ContainerPath string
+
+	// SizeBytes the size of the tmpfs, in bytes. Zero means unlimited.
+	SizeBytes int64
+
+	// Mode the file mode of the tmpfs root directory.
+	Mode os.FileMode
+}
+
+// Target implements the [ContainerMount] interface.
+func (m TmpfsMount) Target() string {
+	return m.ContainerPath
+}
+
+// AsMount implements the [ContainerMount] interface.
+func (m TmpfsMount) AsMount() (mount.Mount, error) {
+	if m.ContainerPath == "" {
+		return mount.Mount{}, fmt.Errorf("%w: tmpfs mount requires a container path", ErrInvalidMount)
+	}
+
+	mnt := mount.Mount{
+		Type:   mount.TypeTmpfs,
+		Target: m.ContainerPath,
+	}
+	if m.SizeBytes != 0 || m.Mode != 0 {
+		mnt.TmpfsOptions = &mount.TmpfsOptions{
+			SizeBytes: m.SizeBytes,
+			Mode:      m.Mode,
+		}
+	}
+
+	return mnt, nil
+}
+
+// ImageMount mounts the filesystem of an image into the container, read-only.
+// It requires a Docker daemon supporting image mounts (API v1.48+).
+type ImageMount struct {
+	// Image the image reference to mount.
+	Image string
+
+	// ContainerPath the path in the container.
+	ContainerPath string
+
+	// Subpath the path inside the image to mount, instead of its root.
+	Subpath string
+}
+
+// Target implements the [ContainerMount] interface.
+func (m ImageMount) Target() string {
+	return m.ContainerPath
+}
+
+// AsMount implements the [ContainerMount] interface.
+func (m ImageMount) AsMount() (mount.Mount, error) {
+	if m.Image == "" {
+		return mount.Mount{}, fmt.Errorf("%w: image mount requires an image", ErrInvalidMount)
+	}
+	if m.ContainerPath == "" {
+		return mount.Mount{}, fmt.Errorf("%w: image mount requires a container path", ErrInvalidMount)
+	}
+
+	mnt := mount.Mount{
+		Type:     mount.TypeImage,
+		Source:   m.Image,
+		Target:   m.ContainerPath,
+		ReadOnly: true,
+	}
+	if m.Subpath != "" {
+		mnt.ImageOptions = &mount.ImageOptions{Subpath: m.Subpath}
+	}
+
+	return mnt, nil
+}
+
+// WithMounts appends typed mounts to the container.
+// The mounts are added to the host config before any host config modifier is applied,
+// so modifiers can still inspect or override them.
+// Duplicate targets, across every kind of mount, are rejected when the definition is validated.
+func WithMounts(mounts ...ContainerMount) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		for _, m := range mounts {
+			if isNil(m) {
+				return fmt.Errorf("%w: mount is nil", ErrInvalidMount)
+			}
+		}
+
+		def.mounts = append(def.mounts, mounts...)
+		return nil
+	}
+}
+
+// dockerMounts converts the typed mounts into Docker API mounts.
+func dockerMounts(mounts []ContainerMount) ([]mount.Mount, error) {
+	result := make([]mount.Mount, 0, len(mounts))
+	for _, m := range mounts {
+		mnt, err := m.AsMount()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mnt)
+	}
+
+	return result, nil
+}
+
+// volumesToRemove returns the names of the volumes that must be removed
+// when the container is terminated.
+func volumesToRemove(mounts []ContainerMount) []string {
+	var names []string
+	for _, m := range mounts {
+		vm, ok := m.(VolumeMount)
+		if !ok || !vm.RemoveOnTerminate {
+			continue
+		}
+
+		if name := vm.volumeName(); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// parseBindTarget returns the container path of a bind in the
+// "source:target[:options]" format used by [container.HostConfig.Binds].
+// Windows drive letters, as in "C:\data:C:\data:ro", are not treated as separators.
+func parseBindTarget(bind string) (string, error) {
+	parts := strings.Split(bind, ":")
+
+	// source: a drive letter followed by an absolute path, as long as a target remains.
+	rest := parts[1:]
+	if len(parts) > 2 && isDriveLetter(parts[0]) && isAbsPathPart(parts[1]) && (isAbsPathPart(parts[2]) || isDriveLetter(parts[2])) {
+		rest = parts[2:]
+	}
+
+	if parts[0] == "" || len(rest) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidBindMount, bind)
+	}
+
+	target := rest[0]
+	rest = rest[1:]
+	if len(rest) > 0 && isDriveLetter(target) && isAbsPathPart(rest[0]) {
+		target += ":" + rest[0]
+		rest = rest[1:]
+	}
+
+	// only the options can remain
+	if target == "" || len(rest) > 1 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidBindMount, bind)
+	}
+
+	return target, nil
+}
+
+// isDriveLetter returns true if s is a single ASCII letter.
+func isDriveLetter(s string) bool {
+	return len(s) == 1 && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
+
+// isAbsPathPart returns true if s starts with a path separator.
+func isAbsPathPart(s string) bool {
+	return strings.HasPrefix(s, `\`) || strings.HasPrefix(s, "/")
+}
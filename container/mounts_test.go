@@ -0,0 +1,140 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/moby/moby/api/types/mount"
+	dockervolume "github.com/moby/moby/api/types/volume"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/volume"
+)
+
+func TestWithMounts(t *testing.T) {
+	t.Run("append", func(t *testing.T) {
+		def := Definition{}
+
+		require.NoError(t, WithMounts(BindMount{HostPath: "/host", ContainerPath: "/bind"}).Customize(&def))
+		require.NoError(t, WithMounts(TmpfsMount{ContainerPath: "/tmpfs"}).Customize(&def))
+
+		require.Len(t, def.Mounts(), 2)
+		require.Equal(t, "/bind", def.Mounts()[0].Target())
+		require.Equal(t, "/tmpfs", def.Mounts()[1].Target())
+	})
+
+	t.Run("nil-mount", func(t *testing.T) {
+		def := Definition{}
+
+		err := WithMounts(nil).Customize(&def)
+		require.ErrorIs(t, err, ErrInvalidMount)
+	})
+}
+
+func TestContainerMount_AsMount(t *testing.T) {
+	t.Run("bind", func(t *testing.T) {
+		m, err := BindMount{HostPath: "/host", ContainerPath: "/ctr", ReadOnly: true, Propagation: mount.PropagationRShared}.AsMount()
+		require.NoError(t, err)
+		require.Equal(t, mount.Mount{
+			Type:        mount.TypeBind,
+			Source:      "/host",
+			Target:      "/ctr",
+			ReadOnly:    true,
+			BindOptions: &mount.BindOptions{Propagation: mount.PropagationRShared},
+		}, m)
+	})
+
+	t.Run("volume/typed", func(t *testing.T) {
+		v := &volume.Volume{Volume: &dockervolume.Volume{Name: "typed"}}
+
+		m, err := VolumeMount{Volume: v, Name: "ignored", ContainerPath: "/data", NoCopy: true}.AsMount()
+		require.NoError(t, err)
+		require.Equal(t, mount.TypeVolume, m.Type)
+		require.Equal(t, "typed", m.Source)
+		require.Equal(t, &mount.VolumeOptions{NoCopy: true}, m.VolumeOptions)
+	})
+
+	t.Run("volume/anonymous", func(t *testing.T) {
+		m, err := VolumeMount{ContainerPath: "/data"}.AsMount()
+		require.NoError(t, err)
+		require.Empty(t, m.Source)
+		require.Nil(t, m.VolumeOptions)
+	})
+
+	t.Run("tmpfs", func(t *testing.T) {
+		m, err := TmpfsMount{ContainerPath: "/run", SizeBytes: 1024, Mode: 0o700}.AsMount()
+		require.NoError(t, err)
+		require.Equal(t, mount.TypeTmpfs, m.Type)
+		require.Empty(t, m.Source)
+		require.Equal(t, &mount.TmpfsOptions{SizeBytes: 1024, Mode: 0o700}, m.TmpfsOptions)
+	})
+
+	t.Run("image", func(t *testing.T) {
+		m, err := ImageMount{Image: "alpine:3", ContainerPath: "/img", Subpath: "etc"}.AsMount()
+		require.NoError(t, err)
+		require.Equal(t, mount.TypeImage, m.Type)
+		require.Equal(t, "alpine:3", m.Source)
+		require.True(t, m.ReadOnly)
+		require.Equal(t, &mount.ImageOptions{Subpath: "etc"}, m.ImageOptions)
+	})
+
+	t.Run("missing-fields", func(t *testing.T) {
+		for _, m := range []ContainerMount{
+			BindMount{ContainerPath: "/ctr"},
+			BindMount{HostPath: "/host"},
+			VolumeMount{Name: "vol"},
+			TmpfsMount{},
+			ImageMount{ContainerPath: "/img"},
+			ImageMount{Image: "alpine"},
+		} {
+			_, err := m.AsMount()
+			require.ErrorIs(t, err, ErrInvalidMount)
+		}
+	})
+}
+
+func TestVolumesToRemove(t *testing.T) {
+	v := &volume.Volume{Volume: &dockervolume.Volume{Name: "typed"}}
+
+	names := volumesToRemove([]ContainerMount{
+		VolumeMount{Volume: v, ContainerPath: "/a", RemoveOnTerminate: true},
+		VolumeMount{Name: "named", ContainerPath: "/b", RemoveOnTerminate: true},
+		VolumeMount{Name: "kept", ContainerPath: "/c"},
+		VolumeMount{ContainerPath: "/d", RemoveOnTerminate: true},
+		TmpfsMount{ContainerPath: "/e"},
+	})
+	require.Equal(t, []string{"typed", "named"}, names)
+}
+
+func TestParseBindTarget(t *testing.T) {
+	tests := []struct {
+		bind   string
+		target string
+		err    bool
+	}{
+		{bind: "/src:/dst", target: "/dst"},
+		{bind: "/src:/dst:ro", target: "/dst"},
+		{bind: "named:/dst:ro", target: "/dst"},
+		{bind: "v:/data:ro", target: "/data"},
+		{bind: "c:/data", target: "/data"},
+		{bind: `C:\src:C:\dst`, target: `C:\dst`},
+		{bind: `C:\src:C:\dst:ro`, target: `C:\dst`},
+		{bind: `C:\src:/dst`, target: "/dst"},
+		{bind: `/src:D:\dst`, target: `D:\dst`},
+		{bind: "foo", err: true},
+		{bind: ":/dst", err: true},
+		{bind: "/src:", err: true},
+		{bind: "/a:/b:ro:extra", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bind, func(t *testing.T) {
+			target, err := parseBindTarget(tt.bind)
+			if tt.err {
+				require.ErrorIs(t, err, ErrInvalidBindMount)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.target, target)
+		})
+	}
+}
@@ -376,6 +376,53 @@ func WithAdditionalWaitStrategyAndDeadline(deadline time.Duration, strategies ..
 	}
 }
 
+// WithHealthCheck sets the health check for a container, overriding the HEALTHCHECK
+// defined by the image. The test can be expressed in the Docker format, i.e. starting
+// with "CMD" or "CMD-SHELL", or as plain command arguments, which are run with "CMD".
+// Zero durations and retries inherit the daemon defaults.
+//
+// If no wait strategy is defined for the container, a [wait.ForHealthCheck] strategy is used,
+// failing as soon as the container is reported unhealthy, and including the output of the
+// last health checks in the error.
+func WithHealthCheck(test []string, interval, timeout time.Duration, retries int, startPeriod time.Duration) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if len(test) == 0 {
+			return errors.New("health check test must not be empty")
+		}
+		if interval < 0 || timeout < 0 || startPeriod < 0 || retries < 0 {
+			return errors.New("health check durations and retries must not be negative")
+		}
+
+		switch test[0] {
+		case "NONE", "CMD", "CMD-SHELL":
+		default:
+			test = append([]string{"CMD"}, test...)
+		}
+
+		def.healthCheck = &container.HealthConfig{
+			Test:        test,
+			Interval:    interval,
+			Timeout:     timeout,
+			Retries:     retries,
+			StartPeriod: startPeriod,
+		}
+
+		return nil
+	}
+}
+
+// healthCheckWaitStrategy returns the wait strategy used for a definition with a health check
+// and no explicit wait strategy. Its deadline covers the start period plus all the retries,
+// using the default 60 seconds as minimum.
+func healthCheckWaitStrategy(hc *container.HealthConfig) wait.Strategy {
+	deadline := hc.StartPeriod + time.Duration(hc.Retries+1)*(hc.Interval+hc.Timeout)
+	if deadline < 60*time.Second {
+		deadline = 60 * time.Second
+	}
+
+	return wait.ForHealthCheck().WithTimeout(deadline).WithFailOnUnhealthy()
+}
+
 // WithAlwaysPull will pull the image before starting the container.
 // Do not use this option in case the image is the result of a build
 // and not yet pushed to a registry. It will try to pull the image
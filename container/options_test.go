@@ -627,6 +627,46 @@ func TestWithWaitStrategy(t *testing.T) {
 	})
 }
 
+func TestWithHealthCheck(t *testing.T) {
+	t.Run("docker-format", func(t *testing.T) {
+		def := Definition{}
+
+		opt := WithHealthCheck([]string{"CMD-SHELL", "curl -f http://localhost"}, time.Second, 2*time.Second, 3, 5*time.Second)
+		require.NoError(t, opt.Customize(&def))
+		require.Equal(t, &container.HealthConfig{
+			Test:        []string{"CMD-SHELL", "curl -f http://localhost"},
+			Interval:    time.Second,
+			Timeout:     2 * time.Second,
+			Retries:     3,
+			StartPeriod: 5 * time.Second,
+		}, def.healthCheck)
+	})
+
+	t.Run("plain-command", func(t *testing.T) {
+		def := Definition{}
+
+		opt := WithHealthCheck([]string{"pg_isready", "-U", "postgres"}, 0, 0, 0, 0)
+		require.NoError(t, opt.Customize(&def))
+		require.Equal(t, []string{"CMD", "pg_isready", "-U", "postgres"}, def.healthCheck.Test)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		def := Definition{}
+
+		require.Error(t, WithHealthCheck(nil, 0, 0, 0, 0).Customize(&def))
+		require.Error(t, WithHealthCheck([]string{"true"}, -time.Second, 0, 0, 0).Customize(&def))
+		require.Error(t, WithHealthCheck([]string{"true"}, 0, 0, -1, 0).Customize(&def))
+	})
+
+	t.Run("wait-strategy-deadline", func(t *testing.T) {
+		short := healthCheckWaitStrategy(&container.HealthConfig{Interval: time.Second, Timeout: time.Second, Retries: 3})
+		require.Equal(t, 60*time.Second, *short.(*wait.HealthStrategy).Timeout())
+
+		long := healthCheckWaitStrategy(&container.HealthConfig{Interval: 10 * time.Second, Timeout: 5 * time.Second, Retries: 5, StartPeriod: time.Minute})
+		require.Equal(t, 150*time.Second, *long.(*wait.HealthStrategy).Timeout())
+	})
+}
+
 func TestWithValidateFuncs(t *testing.T) {
 	t.Run("add-zero", func(t *testing.T) {
 		def := Definition{}
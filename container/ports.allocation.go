@@ -0,0 +1,415 @@
+package container
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	dockerclient "github.com/moby/moby/client"
+)
+
+const (
+	// maxHostPortAttempts the maximum number of times the host ports of a container are
+	// picked again when they are already allocated on its first start.
+	maxHostPortAttempts = 5
+
+	// maxEphemeralPortAttempts the maximum number of ports the system is asked for,
+	// to get one not picked yet for the container.
+	maxEphemeralPortAttempts = 10
+
+	// remoteHostPortFrom, remoteHostPortTo the range of the host ports picked for a daemon
+	// running on another host, without a range set: the dynamic ports, as assigned by the IANA.
+	remoteHostPortFrom, remoteHostPortTo = 49152, 65535
+)
+
+// errPortConflict is a regular expression that matches the errors for a container start
+// binding a host port which is already in use, including the ones of Docker Desktop on Windows.
+var errPortConflict = regexp.MustCompile(`port is already allocated|address already in use|Only one usage of each socket address`)
+
+// errPortConflictHostPort is a regular expression that matches the host port in the errors
+// for a container start binding a host port which is already in use, e.g. "Bind for 0.0.0.0:32768 failed",
+// "listen tcp6 [::]:32768: bind" or "exposing port TCP 0.0.0.0:32768".
+var errPortConflictHostPort = regexp.MustCompile(`(?:Bind for |listen \w+ |exposing port \w+ )\S*:(\d+)`)
+
+// WithPinnedHostPorts picks the host ports of the published ports of the container up front,
+// before it's created, and pins them in its port bindings, so they don't change when the
+// container is restarted and [Container.MappedPort] stays stable. The ports explicitly bound
+// to a host port, e.g. "8080:80/tcp", are kept. When no port is exposed, the ports exposed by
+// the image are pinned.
+//
+// The host ports are free ports of the current host, or of the range set with [WithHostPortRange].
+// When the daemon runs on another host, they are picked at random, from the range or from the
+// dynamic ports 49152-65535, as the ports of the current host tell nothing about the ones of the daemon.
+// If one of them is already allocated when the container starts for the first time, e.g. because
+// another process took it meanwhile, they are picked again and the container is recreated, calling
+// its create hooks again. A conflict on a host port explicitly bound, or on the following starts,
+// where the pinned ports are kept, is returned as an error, as is a conflict whose error doesn't
+// name the host port.
+func WithPinnedHostPorts() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if def.hostPorts == nil {
+			def.hostPorts = &hostPortAllocator{}
+		}
+		return nil
+	}
+}
+
+// WithHostPortRange pins the host ports of the container, as [WithPinnedHostPorts] does,
+// picking them from the range [from, to], e.g. the ports allowed by a firewall.
+func WithHostPortRange(from uint16, to uint16) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if from == 0 || from > to {
+			return fmt.Errorf("invalid host port range %d-%d", from, to)
+		}
+
+		def.hostPorts = &hostPortAllocator{from: from, to: to}
+		return nil
+	}
+}
+
+// hostPortAllocator picks the host ports pinned in the port bindings of a container.
+// A nil allocator leaves the host ports to the daemon.
+type hostPortAllocator struct {
+	// from, to the range of the host ports, zero to ask the system for free ports.
+	from, to uint16
+
+	// picked the container ports whose host ports were picked, picked again on conflicts.
+	picked []network.Port
+
+	// hostPorts the host ports picked, the ones whose conflicts lead to picking them again.
+	hostPorts []uint16
+
+	// pinned is true once the container started with the picked ports, so they are kept.
+	pinned bool
+
+	// remote is true if the daemon runs on another host, so the host ports are not probed on the current one.
+	remote bool
+}
+
+// pinHostPorts picks the host ports of the published ports of the container, if enabled.
+// It must be called once the create hooks ran, as the port bindings are set by them.
+func (d *Definition) pinHostPorts(ctx context.Context, cfg *container.Config, hostConfig *container.HostConfig) error {
+	if d.hostPorts == nil || hostConfig.NetworkMode.IsHost() || hostConfig.NetworkMode.IsContainer() {
+		return nil
+	}
+
+	if hostConfig.PortBindings == nil {
+		hostConfig.PortBindings = network.PortMap{}
+	}
+
+	// the ports exposed by the image are published by the daemon otherwise
+	if hostConfig.PublishAllPorts {
+		img, err := d.dockerClient.ImageInspect(ctx, cfg.Image)
+		if err != nil {
+			return fmt.Errorf("image inspect: %w", err)
+		}
+
+		if img.Config != nil {
+			for p := range img.Config.ExposedPorts {
+				port, err := network.ParsePort(p)
+				if err != nil {
+					return fmt.Errorf("image exposed port %q: %w", p, err)
+				}
+				if _, ok := hostConfig.PortBindings[port]; !ok {
+					hostConfig.PortBindings[port] = []network.PortBinding{{}}
+				}
+			}
+		}
+	}
+
+	daemonHost, err := d.dockerClient.DaemonHostWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("daemon host: %w", err)
+	}
+	d.hostPorts.remote = !isLocalDaemonHost(daemonHost)
+
+	return d.hostPorts.pick(hostConfig.PortBindings)
+}
+
+// pick picks the host ports of the bindings which are not bound to a host port yet.
+// All the bindings of a container port, e.g. to IPv4 and IPv6 addresses, use the same host port.
+func (a *hostPortAllocator) pick(bindings network.PortMap) error {
+	used := usedHostPorts(bindings)
+
+	for _, port := range sortedPorts(bindings) {
+		pbs := bindings[port]
+		if !slices.ContainsFunc(pbs, func(pb network.PortBinding) bool { return pb.HostPort == "" || pb.HostPort == "0" }) {
+			continue
+		}
+
+		hostPort, err := a.free(port.Proto(), used)
+		if err != nil {
+			return fmt.Errorf("pick host port of %s: %w", port, err)
+		}
+		used[hostPort] = true
+
+		for i := range pbs {
+			if pbs[i].HostPort == "" || pbs[i].HostPort == "0" {
+				pbs[i].HostPort = strconv.Itoa(int(hostPort))
+			}
+		}
+		a.picked = append(a.picked, port)
+		a.hostPorts = append(a.hostPorts, hostPort)
+	}
+
+	return nil
+}
+
+// repick picks again the host ports picked by the allocator, avoiding the previous ones.
+func (a *hostPortAllocator) repick(bindings network.PortMap) error {
+	used := usedHostPorts(bindings)
+
+	hostPorts := make([]uint16, 0, len(a.picked))
+	for _, port := range a.picked {
+		hostPort, err := a.free(port.Proto(), used)
+		if err != nil {
+			return fmt.Errorf("pick host port of %s: %w", port, err)
+		}
+		used[hostPort] = true
+		hostPorts = append(hostPorts, hostPort)
+
+		pbs := bindings[port]
+		for i := range pbs {
+			pbs[i].HostPort = strconv.Itoa(int(hostPort))
+		}
+	}
+	a.hostPorts = hostPorts
+
+	return nil
+}
+
+// canRepick returns true if the picked host ports can be picked again after the start error,
+// i.e. the container never started with them, and the error is a conflict on one of them.
+// It returns an error wrapping the start error if it's a conflict which doesn't name the host port.
+func (a *hostPortAllocator) canRepick(err error) (bool, error) {
+	if a == nil || a.pinned || len(a.picked) == 0 || !isPortConflict(err) {
+		return false, nil
+	}
+
+	hostPort, ok := conflictingHostPort(err)
+	if !ok {
+		return false, fmt.Errorf("host port conflict on an unknown port, the picked host ports %v are not picked again: %w", a.hostPorts, err)
+	}
+
+	return slices.Contains(a.hostPorts, hostPort), nil
+}
+
+// pin records the container started with the picked host ports, so they are kept.
+func (a *hostPortAllocator) pin() {
+	if a != nil {
+		a.pinned = true
+	}
+}
+
+// free returns a host port which is free on the current host, for the protocol, and not used.
+// For a daemon running on another host, the port is only not used.
+func (a *hostPortAllocator) free(proto network.IPProtocol, used map[uint16]bool) (uint16, error) {
+	from, to := a.from, a.to
+	if a.remote && from == 0 {
+		from, to = remoteHostPortFrom, remoteHostPortTo
+	}
+
+	if from == 0 {
+		for range maxEphemeralPortAttempts {
+			port, err := ephemeralPort(proto)
+			if err != nil {
+				return 0, err
+			}
+			if !used[port] {
+				return port, nil
+			}
+		}
+		return 0, errors.New("no free host port")
+	}
+
+	// starting at a random port of the range, so concurrent containers don't pick the same ports
+	size := int(to) - int(from) + 1
+	offset := rand.IntN(size)
+	for i := range size {
+		port := from + uint16((offset+i)%size)
+		if !used[port] && (a.remote || isHostPortFree(proto, port)) {
+			return port, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no free host port in range %d-%d", from, to)
+}
+
+// ephemeralPort returns a free port of the current host, picked by the system.
+func ephemeralPort(proto network.IPProtocol) (uint16, error) {
+	if proto == network.UDP {
+		conn, err := net.ListenPacket("udp", ":0")
+		if err != nil {
+			return 0, fmt.Errorf("listen: %w", err)
+		}
+		defer conn.Close()
+
+		return uint16(conn.LocalAddr().(*net.UDPAddr).Port), nil
+	}
+
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, fmt.Errorf("listen: %w", err)
+	}
+	defer l.Close()
+
+	return uint16(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// isHostPortFree returns true if the port of the current host can be listened on.
+func isHostPortFree(proto network.IPProtocol, port uint16) bool {
+	addr := ":" + strconv.Itoa(int(port))
+
+	if proto == network.UDP {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	_ = l.Close()
+	return true
+}
+
+// usedHostPorts returns the host ports explicitly bound in the bindings.
+func usedHostPorts(bindings network.PortMap) map[uint16]bool {
+	used := map[uint16]bool{}
+	for _, pbs := range bindings {
+		for _, pb := range pbs {
+			if port, err := strconv.ParseUint(pb.HostPort, 10, 16); err == nil && port != 0 {
+				used[uint16(port)] = true
+			}
+		}
+	}
+
+	return used
+}
+
+// sortedPorts returns the ports of the bindings sorted by number and protocol,
+// so the host ports are picked in a deterministic order.
+func sortedPorts(bindings network.PortMap) []network.Port {
+	return slices.SortedFunc(maps.Keys(bindings), func(a, b network.Port) int {
+		return cmp.Or(
+			cmp.Compare(a.Num(), b.Num()),
+			cmp.Compare(a.Proto(), b.Proto()),
+		)
+	})
+}
+
+// isPortConflict returns true if the error is a container start failing because
+// a host port is already in use.
+func isPortConflict(err error) bool {
+	return err != nil && errPortConflict.MatchString(err.Error())
+}
+
+// conflictingHostPort returns the host port already in use a container start failed to bind,
+// if the error is such a conflict and it names the port.
+func conflictingHostPort(err error) (uint16, bool) {
+	if !isPortConflict(err) {
+		return 0, false
+	}
+
+	m := errPortConflictHostPort.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, false
+	}
+
+	port, errParse := strconv.ParseUint(m[1], 10, 16)
+	if errParse != nil {
+		return 0, false
+	}
+
+	return uint16(port), true
+}
+
+// containerStart starts the Docker container. On its first start, if its host ports picked
+// with [WithPinnedHostPorts] are already allocated, they are picked again and the container
+// is recreated, until it starts. Conflicts on other host ports are returned as is.
+func (c *Container) containerStart(ctx context.Context) error {
+	_, err := c.dockerClient.ContainerStart(ctx, c.ID(), dockerclient.ContainerStartOptions{})
+	for attempt := 1; attempt < maxHostPortAttempts; attempt++ {
+		repick, errConflict := c.hostPorts.canRepick(err)
+		if errConflict != nil {
+			return errConflict
+		}
+		if !repick {
+			break
+		}
+
+		c.logger.Info("Host port already allocated, picking the host ports again", "containerID", c.ShortID(), "error", err)
+
+		if errRepick := c.repickHostPorts(ctx); errRepick != nil {
+			return errors.Join(err, errRepick)
+		}
+
+		_, err = c.dockerClient.ContainerStart(ctx, c.ID(), dockerclient.ContainerStartOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	c.hostPorts.pin()
+
+	return nil
+}
+
+// repickHostPorts recreates the container with host ports picked again, as they are already
+// allocated. The caller must hold the lifecycle lock, with the container starting.
+func (c *Container) repickHostPorts(ctx context.Context) error {
+	inspect, err := c.Inspect(ctx)
+	if err != nil {
+		return fmt.Errorf("inspect: %w", err)
+	}
+
+	options := restoreCreateOptions(inspect.Container, inspect.Container.Image, c.ShortID())
+
+	// the container never started, so its port bindings are the requested ones
+	bindings := network.PortMap{}
+	if inspect.Container.HostConfig != nil {
+		for port, pbs := range inspect.Container.HostConfig.PortBindings {
+			bindings[port] = slices.Clone(pbs)
+		}
+	}
+	if err := c.hostPorts.repick(bindings); err != nil {
+		return err
+	}
+	options.HostConfig.PortBindings = bindings
+
+	_, err = c.dockerClient.ContainerRemove(ctx, c.ID(), dockerclient.ContainerRemoveOptions{
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("container remove: %w", err)
+	}
+
+	resp, err := c.dockerClient.ContainerCreate(ctx, options)
+	if err != nil {
+		// the container is gone
+		return errors.Join(fmt.Errorf("container create: %w", err), c.transition(StateRemoved))
+	}
+
+	c.replaceContainer(resp.ID)
+
+	if err := c.createdHook(ctx); err != nil {
+		return fmt.Errorf("created hook: %w", err)
+	}
+
+	return c.transition(StateStarting)
+}
@@ -0,0 +1,70 @@
+package container_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/moby/moby/api/types/network"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+)
+
+func TestRun_pinnedHostPorts(t *testing.T) {
+	ctx := context.Background()
+	port := network.MustParsePort("80/tcp")
+
+	t.Run("restart", func(t *testing.T) {
+		ctr, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithExposedPorts("80/tcp"),
+			container.WithPinnedHostPorts(),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		mappedPort, err := ctr.MappedPort(ctx, port)
+		require.NoError(t, err)
+
+		inspect, err := ctr.Inspect(ctx)
+		require.NoError(t, err)
+		require.Equal(t, strconv.Itoa(int(mappedPort.Num())), inspect.Container.HostConfig.PortBindings[port][0].HostPort)
+
+		require.NoError(t, ctr.Stop(ctx))
+		require.NoError(t, ctr.Start(ctx))
+
+		restartedPort, err := ctr.MappedPort(ctx, port)
+		require.NoError(t, err)
+		require.Equal(t, mappedPort, restartedPort)
+	})
+
+	t.Run("image-exposed-ports", func(t *testing.T) {
+		ctr, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithPinnedHostPorts(),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		inspect, err := ctr.Inspect(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, inspect.Container.HostConfig.PortBindings[port][0].HostPort)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		ctr, err := container.Run(ctx,
+			container.WithImage(nginxAlpineImage),
+			container.WithExposedPorts("80/tcp"),
+			container.WithHostPortRange(30000, 30100),
+		)
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+
+		mappedPort, err := ctr.MappedPort(ctx, port)
+		require.NoError(t, err)
+
+		require.GreaterOrEqual(t, mappedPort.Num(), uint16(30000))
+		require.LessOrEqual(t, mappedPort.Num(), uint16(30100))
+	})
+}
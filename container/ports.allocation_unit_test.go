@@ -0,0 +1,316 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPortAllocator_pick(t *testing.T) {
+	t.Run("ephemeral", func(t *testing.T) {
+		bindings := network.PortMap{
+			network.MustParsePort("80/tcp"):   {{HostIP: netip.MustParseAddr("0.0.0.0")}, {HostIP: netip.MustParseAddr("::")}},
+			network.MustParsePort("53/udp"):   {{HostPort: "0"}},
+			network.MustParsePort("8080/tcp"): {{HostPort: "9000"}},
+		}
+
+		a := &hostPortAllocator{}
+		require.NoError(t, a.pick(bindings))
+		require.Equal(t, []network.Port{network.MustParsePort("53/udp"), network.MustParsePort("80/tcp")}, a.picked)
+
+		http := bindings[network.MustParsePort("80/tcp")]
+		require.NotEmpty(t, http[0].HostPort)
+		require.NotEqual(t, "0", http[0].HostPort)
+		require.Equal(t, http[0].HostPort, http[1].HostPort)
+
+		require.NotEqual(t, "0", bindings[network.MustParsePort("53/udp")][0].HostPort)
+		require.Equal(t, "9000", bindings[network.MustParsePort("8080/tcp")][0].HostPort)
+		require.NotEqual(t, http[0].HostPort, bindings[network.MustParsePort("53/udp")][0].HostPort)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		port := freeTestPort(t)
+		bindings := network.PortMap{
+			network.MustParsePort("80/tcp"): {{}},
+		}
+
+		a := &hostPortAllocator{from: port, to: port}
+		require.NoError(t, a.pick(bindings))
+		require.Equal(t, strconv.Itoa(int(port)), bindings[network.MustParsePort("80/tcp")][0].HostPort)
+	})
+
+	t.Run("range-exhausted", func(t *testing.T) {
+		port := freeTestPort(t)
+		bindings := network.PortMap{
+			network.MustParsePort("80/tcp"):  {{}},
+			network.MustParsePort("443/tcp"): {{}},
+		}
+
+		a := &hostPortAllocator{from: port, to: port}
+		require.ErrorContains(t, a.pick(bindings), "no free host port in range")
+	})
+
+	t.Run("range-in-use", func(t *testing.T) {
+		l, err := net.Listen("tcp", ":0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+		port := uint16(l.Addr().(*net.TCPAddr).Port)
+
+		a := &hostPortAllocator{from: port, to: port}
+		require.Error(t, a.pick(network.PortMap{network.MustParsePort("80/tcp"): {{}}}))
+
+		// the ports of the current host are not probed for a daemon running on another host
+		bindings := network.PortMap{network.MustParsePort("80/tcp"): {{}}}
+		a = &hostPortAllocator{from: port, to: port, remote: true}
+		require.NoError(t, a.pick(bindings))
+		require.Equal(t, strconv.Itoa(int(port)), bindings[network.MustParsePort("80/tcp")][0].HostPort)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		bindings := network.PortMap{
+			network.MustParsePort("80/tcp"):  {{}},
+			network.MustParsePort("443/tcp"): {{}},
+		}
+
+		a := &hostPortAllocator{remote: true}
+		require.NoError(t, a.pick(bindings))
+		require.Len(t, a.hostPorts, 2)
+		require.NotEqual(t, a.hostPorts[0], a.hostPorts[1])
+		for _, port := range a.hostPorts {
+			require.GreaterOrEqual(t, port, uint16(remoteHostPortFrom))
+		}
+	})
+
+	t.Run("repick", func(t *testing.T) {
+		bindings := network.PortMap{
+			network.MustParsePort("80/tcp"): {{}},
+		}
+
+		a := &hostPortAllocator{}
+		require.NoError(t, a.pick(bindings))
+		previous := bindings[network.MustParsePort("80/tcp")][0].HostPort
+
+		require.NoError(t, a.repick(bindings))
+		current := bindings[network.MustParsePort("80/tcp")][0].HostPort
+		require.NotEqual(t, previous, current)
+
+		// the conflicts are matched against the host ports picked last
+		require.Len(t, a.hostPorts, 1)
+		require.Equal(t, current, strconv.Itoa(int(a.hostPorts[0])))
+	})
+}
+
+func TestDefinition_pinHostPorts(t *testing.T) {
+	for host, remote := range map[string]bool{
+		"localhost": false,
+		"127.0.0.1": false,
+		"10.0.0.2":  true,
+		"docker":    true,
+	} {
+		def := &Definition{dockerClient: &hostFakeClient{host: host}}
+		require.NoError(t, WithPinnedHostPorts()(def))
+
+		hostConfig := &container.HostConfig{PortBindings: network.PortMap{network.MustParsePort("80/tcp"): {{}}}}
+		require.NoError(t, def.pinHostPorts(context.Background(), &container.Config{}, hostConfig))
+		require.Equal(t, remote, def.hostPorts.remote, host)
+		require.NotEmpty(t, hostConfig.PortBindings[network.MustParsePort("80/tcp")][0].HostPort, host)
+	}
+}
+
+func TestWithHostPortRange(t *testing.T) {
+	def := &Definition{}
+	require.Error(t, WithHostPortRange(0, 100)(def))
+	require.Error(t, WithHostPortRange(200, 100)(def))
+	require.Nil(t, def.hostPorts)
+
+	require.NoError(t, WithHostPortRange(30000, 30010)(def))
+	require.Equal(t, &hostPortAllocator{from: 30000, to: 30010}, def.hostPorts)
+
+	// the range is kept
+	require.NoError(t, WithPinnedHostPorts()(def))
+	require.Equal(t, &hostPortAllocator{from: 30000, to: 30010}, def.hostPorts)
+}
+
+func TestIsPortConflict(t *testing.T) {
+	require.False(t, isPortConflict(nil))
+	require.False(t, isPortConflict(errors.New("no such container")))
+	require.True(t, isPortConflict(errors.New("Bind for 0.0.0.0:32768 failed: port is already allocated")))
+	require.True(t, isPortConflict(errors.New("listen tcp4 0.0.0.0:32768: bind: address already in use")))
+	require.True(t, isPortConflict(errors.New("listen tcp 0.0.0.0:8080: bind: Only one usage of each socket address (protocol/network address/port) is normally permitted.")))
+}
+
+func TestConflictingHostPort(t *testing.T) {
+	tests := []struct {
+		err  error
+		port uint16
+		ok   bool
+	}{
+		{err: errors.New("Bind for 0.0.0.0:32768 failed: port is already allocated"), port: 32768, ok: true},
+		{err: errors.New("Bind for [::]:8080 failed: port is already allocated"), port: 8080, ok: true},
+		{err: errors.New("driver failed programming external connectivity on endpoint pinned: Error starting userland proxy: listen tcp4 0.0.0.0:32769: bind: address already in use"), port: 32769, ok: true},
+		{err: errors.New("listen udp6 [::]:5353: bind: address already in use"), port: 5353, ok: true},
+		// Docker Desktop
+		{err: errors.New("Error response from daemon: Ports are not available: exposing port TCP 0.0.0.0:8080 -> 0.0.0.0:0: listen tcp 0.0.0.0:8080: bind: address already in use"), port: 8080, ok: true},
+		{err: errors.New("Error response from daemon: Ports are not available: exposing port TCP 0.0.0.0:8080 -> 0.0.0.0:0: listen tcp 0.0.0.0:8080: bind: Only one usage of each socket address (protocol/network address/port) is normally permitted."), port: 8080, ok: true},
+		// rootless
+		{err: errors.New("Error response from daemon: driver failed programming external connectivity on endpoint web (3c5b2e8f9a1d): Error starting userland proxy: error while calling PortManager.AddPort(): listen tcp4 0.0.0.0:8080: bind: address already in use"), port: 8080, ok: true},
+		{err: errors.New("Error response from daemon: driver failed programming external connectivity on endpoint web (3c5b2e8f9a1d): Error starting userland proxy: error while calling PortManager.AddPort(): listen udp6 [::]:5353: bind: address already in use"), port: 5353, ok: true},
+		{err: errors.New("port is already allocated")},
+		{err: errors.New("Bind for 0.0.0.0:32768 failed: no such container")},
+		{err: nil},
+	}
+
+	for _, tt := range tests {
+		port, ok := conflictingHostPort(tt.err)
+		require.Equal(t, tt.ok, ok, "error: %v", tt.err)
+		require.Equal(t, tt.port, port, "error: %v", tt.err)
+	}
+}
+
+func TestHostPortAllocator_canRepick(t *testing.T) {
+	a := &hostPortAllocator{picked: []network.Port{network.MustParsePort("80/tcp")}, hostPorts: []uint16{32768}}
+
+	repick, err := a.canRepick(errors.New("Bind for 0.0.0.0:32768 failed: port is already allocated"))
+	require.NoError(t, err)
+	require.True(t, repick)
+
+	// a conflict on another host port
+	repick, err = a.canRepick(errors.New("Bind for 0.0.0.0:8443 failed: port is already allocated"))
+	require.NoError(t, err)
+	require.False(t, repick)
+
+	repick, err = a.canRepick(errors.New("no such container"))
+	require.NoError(t, err)
+	require.False(t, repick)
+
+	// a conflict not naming the host port
+	cause := errors.New("driver failed programming external connectivity on endpoint web: port is already allocated")
+	repick, err = a.canRepick(cause)
+	require.ErrorIs(t, err, cause)
+	require.ErrorContains(t, err, "host port conflict on an unknown port")
+	require.False(t, repick)
+
+	// once pinned, the host ports are kept
+	a.pin()
+	repick, err = a.canRepick(cause)
+	require.NoError(t, err)
+	require.False(t, repick)
+}
+
+// portConflictFakeClient is a fake SDK client failing the first starts of a container
+// because the host port of its first binding is already allocated.
+type portConflictFakeClient struct {
+	*lifecycleFakeClient
+
+	mtx       sync.Mutex
+	conflicts int
+	bindings  network.PortMap
+	creates   []dockerclient.ContainerCreateOptions
+}
+
+func (f *portConflictFakeClient) ContainerStart(ctx context.Context, id string, options dockerclient.ContainerStartOptions) (dockerclient.ContainerStartResult, error) {
+	f.mtx.Lock()
+	if f.conflicts > 0 {
+		f.conflicts--
+		var hostPort string
+		for _, pbs := range f.bindings {
+			hostPort = pbs[0].HostPort
+		}
+		f.mtx.Unlock()
+		return dockerclient.ContainerStartResult{}, errors.New("Bind for 0.0.0.0:" + hostPort + " failed: port is already allocated")
+	}
+	f.mtx.Unlock()
+
+	return f.lifecycleFakeClient.ContainerStart(ctx, id, options)
+}
+
+func (f *portConflictFakeClient) ContainerInspect(ctx context.Context, id string, options dockerclient.ContainerInspectOptions) (dockerclient.ContainerInspectResult, error) {
+	result, err := f.lifecycleFakeClient.ContainerInspect(ctx, id, options)
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	result.Container.Name = "/pinned"
+	result.Container.HostConfig = &container.HostConfig{PortBindings: f.bindings}
+	return result, err
+}
+
+func (f *portConflictFakeClient) ContainerCreate(_ context.Context, options dockerclient.ContainerCreateOptions) (dockerclient.ContainerCreateResult, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.creates = append(f.creates, options)
+	f.bindings = options.HostConfig.PortBindings
+	return dockerclient.ContainerCreateResult{ID: "abcdef1234567890recreated"}, nil
+}
+
+func TestContainer_Start_pinnedHostPorts(t *testing.T) {
+	port := network.MustParsePort("80/tcp")
+
+	ctr, lifecycleFake := newLifecycleTestContainer(StateCreated)
+	fake := &portConflictFakeClient{
+		lifecycleFakeClient: lifecycleFake,
+		conflicts:           2,
+		bindings:            network.PortMap{port: {{HostPort: "32768"}}},
+	}
+	ctr.dockerClient = fake
+	ctr.hostPorts = &hostPortAllocator{picked: []network.Port{port}, hostPorts: []uint16{32768}}
+
+	require.NoError(t, ctr.Start(context.Background()))
+	require.Equal(t, StateRunning, ctr.LifecycleState())
+	require.Equal(t, "abcdef1234567890recreated", ctr.ID())
+	require.True(t, ctr.hostPorts.pinned)
+
+	// the container is recreated once per conflict, with other host ports
+	require.Len(t, fake.creates, 2)
+	for _, create := range fake.creates {
+		require.Equal(t, "pinned", create.Name)
+		require.NotEqual(t, "32768", create.HostConfig.PortBindings[port][0].HostPort)
+	}
+
+	// once pinned, the host ports are kept
+	require.NoError(t, ctr.Stop(context.Background()))
+	fake.conflicts = 1
+	require.ErrorContains(t, ctr.Start(context.Background()), "port is already allocated")
+	require.Len(t, fake.creates, 2)
+	require.Equal(t, StateStopped, ctr.LifecycleState())
+}
+
+func TestContainer_Start_explicitHostPortConflict(t *testing.T) {
+	picked := network.MustParsePort("80/tcp")
+	explicit := network.MustParsePort("443/tcp")
+
+	ctr, lifecycleFake := newLifecycleTestContainer(StateCreated)
+	fake := &portConflictFakeClient{
+		lifecycleFakeClient: lifecycleFake,
+		conflicts:           1,
+		// the host port explicitly bound is already allocated
+		bindings: network.PortMap{explicit: {{HostPort: "8443"}}},
+	}
+	ctr.dockerClient = fake
+	ctr.hostPorts = &hostPortAllocator{picked: []network.Port{picked}, hostPorts: []uint16{32768}}
+
+	require.ErrorContains(t, ctr.Start(context.Background()), "Bind for 0.0.0.0:8443 failed")
+	require.Empty(t, fake.creates)
+	require.Equal(t, "1234567890abcdefgh", ctr.ID())
+	require.False(t, ctr.hostPorts.pinned)
+}
+
+// freeTestPort returns a TCP port which is free on the current host.
+func freeTestPort(t *testing.T) uint16 {
+	t.Helper()
+
+	port, err := ephemeralPort(network.TCP)
+	require.NoError(t, err)
+
+	return port
+}
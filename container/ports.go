@@ -1,19 +1,216 @@
 package container
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"net"
+	"net/netip"
+	"slices"
 	"strconv"
 
 	"github.com/containerd/errdefs"
 	"github.com/moby/moby/api/types/network"
 )
 
+// AddressFamily is the address family of a host binding.
+type AddressFamily string
+
+const (
+	// IPv4 the IPv4 address family.
+	IPv4 AddressFamily = "ipv4"
+
+	// IPv6 the IPv6 address family.
+	IPv6 AddressFamily = "ipv6"
+)
+
+// HostBinding is a binding of a container port to a port of the host.
+type HostBinding struct {
+	// HostIP the host IP the port is bound to. It's unspecified, e.g. "0.0.0.0" or "::",
+	// when the port is bound to every address of the family.
+	HostIP netip.Addr
+
+	// HostPort the port of the host.
+	HostPort uint16
+
+	// Protocol the protocol of the port, e.g. "tcp" or "udp".
+	Protocol network.IPProtocol
+
+	// Family the address family of the binding.
+	Family AddressFamily
+}
+
+// PortBinding is a container port, with all of its host bindings.
+type PortBinding struct {
+	// Port the container port.
+	Port network.Port
+
+	// Bindings the host bindings of the port. It's empty for exposed ports
+	// which are not published to the host.
+	Bindings []HostBinding
+}
+
+// EndpointOption is an option to customize the endpoint returned by [Container.Endpoint]
+// and [Container.PortEndpoint].
+type EndpointOption func(*endpointOptions)
+
+// endpointOptions the options to build an endpoint.
+type endpointOptions struct {
+	family AddressFamily
+}
+
+// WithAddressFamily selects the host binding of the given address family,
+// instead of the first binding of the port. If the port has no binding
+// of that family, an error is returned.
+func WithAddressFamily(family AddressFamily) EndpointOption {
+	return func(o *endpointOptions) {
+		o.family = family
+	}
+}
+
+// PortBindings returns every container port, sorted by port number and protocol,
+// with all of its host bindings, including IPv6 bindings and bindings to specific host IPs.
+// When the container uses the host network, each exposed port is bound to itself.
+func (c *Container) PortBindings(ctx context.Context) ([]PortBinding, error) {
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("inspect: %w", err)
+	}
+
+	if inspect.Container.HostConfig != nil && inspect.Container.HostConfig.NetworkMode == "host" {
+		var exposed network.PortSet
+		if inspect.Container.Config != nil {
+			exposed = inspect.Container.Config.ExposedPorts
+		}
+
+		bindings := make([]PortBinding, 0, len(exposed))
+		for port := range exposed {
+			bindings = append(bindings, PortBinding{
+				Port: port,
+				Bindings: []HostBinding{
+					{HostIP: netip.IPv4Unspecified(), HostPort: port.Num(), Protocol: port.Proto(), Family: IPv4},
+				},
+			})
+		}
+		sortPortBindings(bindings)
+
+		return bindings, nil
+	}
+
+	if inspect.Container.NetworkSettings == nil {
+		return nil, nil
+	}
+
+	return portBindingsOf(inspect.Container.NetworkSettings.Ports)
+}
+
+// portBindingsOf converts the port map reported by the daemon into sorted port bindings.
+func portBindingsOf(ports network.PortMap) ([]PortBinding, error) {
+	bindings := make([]PortBinding, 0, len(ports))
+	for port, pbs := range ports {
+		pb := PortBinding{Port: port}
+		for _, b := range pbs {
+			hostPort, err := strconv.ParseUint(b.HostPort, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("parse host port %q of %s: %w", b.HostPort, port, err)
+			}
+
+			pb.Bindings = append(pb.Bindings, HostBinding{
+				HostIP:   b.HostIP,
+				HostPort: uint16(hostPort),
+				Protocol: port.Proto(),
+				Family:   familyOf(b.HostIP),
+			})
+		}
+		bindings = append(bindings, pb)
+	}
+	sortPortBindings(bindings)
+
+	return bindings, nil
+}
+
+// sortPortBindings sorts the port bindings by port number and protocol.
+func sortPortBindings(bindings []PortBinding) {
+	slices.SortFunc(bindings, func(a, b PortBinding) int {
+		return cmp.Or(
+			cmp.Compare(a.Port.Num(), b.Port.Num()),
+			cmp.Compare(a.Port.Proto(), b.Port.Proto()),
+		)
+	})
+}
+
+// familyOf returns the address family of a host IP. An invalid IP is considered IPv4,
+// as the daemon binds to every IPv4 address when no host IP is set.
+func familyOf(ip netip.Addr) AddressFamily {
+	if ip.Is6() && !ip.Is4In6() {
+		return IPv6
+	}
+
+	return IPv4
+}
+
+// PortEndpoints gets the proto://host:port strings of every host binding, for each published port.
+// Bindings to every address of a family use the host of the Docker daemon, or "::1" for IPv6
+// bindings when the daemon is local. Exposed ports which are not published are omitted.
+// It returns just host:port or [IPv6host]:port strings if proto is blank.
+func (c *Container) PortEndpoints(ctx context.Context, proto string) (map[network.Port][]string, error) {
+	host, err := c.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings, err := c.PortBindings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[network.Port][]string, len(bindings))
+	for _, pb := range bindings {
+		for _, b := range pb.Bindings {
+			endpoints[pb.Port] = append(endpoints[pb.Port], endpointOf(bindingHost(host, b), b.HostPort, proto))
+		}
+	}
+
+	return endpoints, nil
+}
+
+// bindingHost returns the host to reach a host binding, given the host of the Docker daemon.
+func bindingHost(daemonHost string, b HostBinding) string {
+	if b.HostIP.IsValid() && !b.HostIP.IsUnspecified() {
+		return b.HostIP.Unmap().String()
+	}
+
+	if b.Family == IPv6 && isLocalHost(daemonHost) {
+		return "::1"
+	}
+
+	return daemonHost
+}
+
+// isLocalHost returns true if host is the local host.
+func isLocalHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// endpointOf returns the proto://host:port string for a host and port, or host:port if proto is blank.
+func endpointOf(host string, port uint16, proto string) string {
+	hostPort := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	if proto == "" {
+		return hostPort
+	}
+
+	return proto + "://" + hostPort
+}
+
 // Endpoint gets proto://host:port string for the lowest numbered exposed port
 // Will return just host:port if proto is empty
-func (c *Container) Endpoint(ctx context.Context, proto string) (string, error) {
-	inspect, err := c.Inspect(ctx)
+func (c *Container) Endpoint(ctx context.Context, proto string, opts ...EndpointOption) (string, error) {
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return "", err
 	}
@@ -30,36 +227,69 @@ func (c *Container) Endpoint(ctx context.Context, proto string) (string, error)
 		}
 	}
 
-	return c.PortEndpoint(ctx, lowestPort, proto)
+	return c.PortEndpoint(ctx, lowestPort, proto, opts...)
 }
 
 // PortEndpoint gets proto://host:port string for the given exposed port
 // It returns proto://host:port or proto://[IPv6host]:port string for the given exposed port.
 // It returns just host:port or [IPv6host]:port if proto is blank.
+// Use [WithAddressFamily] to choose between the IPv4 and IPv6 bindings of the port.
 //
 // TODO(robmry) - remove proto and use port.Proto()
-func (c *Container) PortEndpoint(ctx context.Context, port network.Port, proto string) (string, error) {
+func (c *Container) PortEndpoint(ctx context.Context, port network.Port, proto string, opts ...EndpointOption) (string, error) {
 	host, err := c.Host(ctx)
 	if err != nil {
 		return "", err
 	}
 
+	var options endpointOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.family != "" {
+		b, err := c.familyBinding(ctx, port, options.family)
+		if err != nil {
+			return "", err
+		}
+
+		return endpointOf(bindingHost(host, b), b.HostPort, proto), nil
+	}
+
 	outerPort, err := c.MappedPort(ctx, port)
 	if err != nil {
 		return "", err
 	}
 
-	hostPort := net.JoinHostPort(host, strconv.Itoa(int(outerPort.Num())))
-	if proto == "" {
-		return hostPort, nil
+	return endpointOf(host, outerPort.Num(), proto), nil
+}
+
+// familyBinding returns the first host binding of the given address family for a container port.
+// A port without protocol matches any protocol.
+func (c *Container) familyBinding(ctx context.Context, port network.Port, family AddressFamily) (HostBinding, error) {
+	bindings, err := c.PortBindings(ctx)
+	if err != nil {
+		return HostBinding{}, err
+	}
+
+	for _, pb := range bindings {
+		if pb.Port.Num() != port.Num() || (port.Proto() != "" && pb.Port.Proto() != port.Proto()) {
+			continue
+		}
+
+		for _, b := range pb.Bindings {
+			if b.Family == family {
+				return b, nil
+			}
+		}
 	}
 
-	return proto + "://" + hostPort, nil
+	return HostBinding{}, errdefs.ErrNotFound.WithMessage(fmt.Sprintf("no %s binding for port %q", family, port))
 }
 
 // MappedPort gets externally mapped port for a container port
 func (c *Container) MappedPort(ctx context.Context, port network.Port) (network.Port, error) {
-	inspect, err := c.Inspect(ctx)
+	inspect, err := c.CachedInspect(ctx, inspectCacheMaxAge)
 	if err != nil {
 		return network.Port{}, fmt.Errorf("inspect: %w", err)
 	}
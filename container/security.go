@@ -0,0 +1,415 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/system"
+)
+
+// ErrUnsupportedByDaemon is returned when a resource limit or security option
+// is not supported by the Docker daemon the container is created on.
+var ErrUnsupportedByDaemon = errors.New("unsupported by the docker daemon")
+
+// Resources are the typed resource limits for a container.
+// Zero values are not applied, leaving the daemon defaults in place.
+type Resources struct {
+	// MemoryBytes the memory limit, in bytes.
+	MemoryBytes int64
+
+	// MemoryReservationBytes the memory soft limit, in bytes.
+	MemoryReservationBytes int64
+
+	// MemorySwapBytes the total memory limit (memory + swap), in bytes.
+	// Set it to -1 to enable unlimited swap.
+	MemorySwapBytes int64
+
+	// CPUs the number of CPUs, e.g. 1.5.
+	CPUs float64
+
+	// CPUShares the relative CPU weight versus other containers.
+	CPUShares int64
+
+	// CPUSetCPUs the CPUs in which to allow execution, e.g. "0-3" or "0,1".
+	CPUSetCPUs string
+
+	// PidsLimit the maximum number of processes. Set it to -1 for unlimited.
+	PidsLimit int64
+}
+
+// validate validates the [Resources].
+func (r Resources) validate() error {
+	if r.MemoryBytes < 0 || r.MemoryReservationBytes < 0 {
+		return errors.New("memory limits must not be negative")
+	}
+
+	if r.MemorySwapBytes > 0 && r.MemorySwapBytes < r.MemoryBytes {
+		return errors.New("memory swap limit must be greater than or equal to the memory limit")
+	}
+
+	if r.MemoryBytes > 0 && r.MemoryReservationBytes > r.MemoryBytes {
+		return errors.New("memory reservation must be lower than or equal to the memory limit")
+	}
+
+	if r.CPUs < 0 || math.IsNaN(r.CPUs) || math.IsInf(r.CPUs, 0) {
+		return fmt.Errorf("invalid number of CPUs: %v", r.CPUs)
+	}
+
+	if r.CPUShares < 0 {
+		return errors.New("CPU shares must not be negative")
+	}
+
+	return nil
+}
+
+// apply applies the non-zero resource limits to the host config.
+func (r Resources) apply(hostConfig *container.HostConfig) {
+	if r.MemoryBytes != 0 {
+		hostConfig.Memory = r.MemoryBytes
+	}
+	if r.MemoryReservationBytes != 0 {
+		hostConfig.MemoryReservation = r.MemoryReservationBytes
+	}
+	if r.MemorySwapBytes != 0 {
+		hostConfig.MemorySwap = r.MemorySwapBytes
+	}
+	if r.CPUs != 0 {
+		hostConfig.NanoCPUs = int64(r.CPUs * 1e9)
+	}
+	if r.CPUShares != 0 {
+		hostConfig.CPUShares = r.CPUShares
+	}
+	if r.CPUSetCPUs != "" {
+		hostConfig.CpusetCpus = r.CPUSetCPUs
+	}
+	if r.PidsLimit != 0 {
+		pids := r.PidsLimit
+		hostConfig.PidsLimit = &pids
+	}
+}
+
+// securityOptions holds the typed resource limits and security options of a definition.
+// They are applied to the host config before any host config modifier, so modifiers
+// are always able to override them.
+type securityOptions struct {
+	resources      *Resources
+	capAdd         []string
+	capDrop        []string
+	readOnlyRootfs bool
+	ulimits        []*container.Ulimit
+
+	// seccompProfile the content of the seccomp profile, or "unconfined".
+	seccompProfile  string
+	apparmorProfile string
+	noNewPrivileges bool
+	hostUserns      bool
+}
+
+// security returns the security options of the definition, initializing them if needed.
+func (d *Definition) security() *securityOptions {
+	if d.securityOpts == nil {
+		d.securityOpts = &securityOptions{}
+	}
+
+	return d.securityOpts
+}
+
+// WithResources sets the resource limits for a container.
+// Calling it multiple times merges the non-zero values, the last one winning.
+func WithResources(resources Resources) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if err := resources.validate(); err != nil {
+			return fmt.Errorf("resources: %w", err)
+		}
+
+		sec := def.security()
+		if sec.resources == nil {
+			sec.resources = &Resources{}
+		}
+
+		merged := *sec.resources
+		mergeResources(&merged, resources)
+
+		if err := merged.validate(); err != nil {
+			return fmt.Errorf("resources: %w", err)
+		}
+
+		sec.resources = &merged
+		return nil
+	}
+}
+
+// mergeResources copies the non-zero values of src into dst.
+func mergeResources(dst *Resources, src Resources) {
+	if src.MemoryBytes != 0 {
+		dst.MemoryBytes = src.MemoryBytes
+	}
+	if src.MemoryReservationBytes != 0 {
+		dst.MemoryReservationBytes = src.MemoryReservationBytes
+	}
+	if src.MemorySwapBytes != 0 {
+		dst.MemorySwapBytes = src.MemorySwapBytes
+	}
+	if src.CPUs != 0 {
+		dst.CPUs = src.CPUs
+	}
+	if src.CPUShares != 0 {
+		dst.CPUShares = src.CPUShares
+	}
+	if src.CPUSetCPUs != "" {
+		dst.CPUSetCPUs = src.CPUSetCPUs
+	}
+	if src.PidsLimit != 0 {
+		dst.PidsLimit = src.PidsLimit
+	}
+}
+
+// WithCapabilities adds and drops Linux capabilities for a container, e.g. "NET_ADMIN" or "ALL".
+// The "CAP_" prefix is optional. It's cumulative: capabilities are appended to the existing ones,
+// and a capability cannot be both added and dropped.
+func WithCapabilities(add []string, drop []string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		sec := def.security()
+
+		capAdd := appendCapabilities(sec.capAdd, add)
+		capDrop := appendCapabilities(sec.capDrop, drop)
+
+		for _, c := range capAdd {
+			if c != "ALL" && slices.Contains(capDrop, c) {
+				return fmt.Errorf("capability %q is both added and dropped", c)
+			}
+		}
+
+		sec.capAdd = capAdd
+		sec.capDrop = capDrop
+		return nil
+	}
+}
+
+// appendCapabilities appends the normalized capabilities to caps, skipping duplicates.
+func appendCapabilities(caps []string, newCaps []string) []string {
+	for _, c := range newCaps {
+		c = strings.ToUpper(strings.TrimSpace(c))
+		if c == "" {
+			continue
+		}
+		if c != "ALL" && !strings.HasPrefix(c, "CAP_") {
+			c = "CAP_" + c
+		}
+		if slices.Contains(caps, c) {
+			continue
+		}
+		caps = append(caps, c)
+	}
+
+	return caps
+}
+
+// WithReadOnlyRootfs mounts the container's root filesystem as read-only.
+// Combine it with [TmpfsMount] for the paths the process needs to write to.
+func WithReadOnlyRootfs() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.security().readOnlyRootfs = true
+		return nil
+	}
+}
+
+// WithSeccompProfileFile applies the seccomp profile read from the given host file.
+// The file is read and validated when the option is applied, so the profile also
+// works with remote Docker daemons. Use "unconfined" to disable seccomp confinement.
+func WithSeccompProfileFile(path string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if path == "unconfined" {
+			def.security().seccompProfile = path
+			return nil
+		}
+
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read seccomp profile: %w", err)
+		}
+
+		if !json.Valid(bs) {
+			return fmt.Errorf("invalid seccomp profile %s: not a JSON document", path)
+		}
+
+		def.security().seccompProfile = string(bs)
+		return nil
+	}
+}
+
+// WithAppArmorProfile applies the given AppArmor profile, which must be loaded in the host,
+// or "unconfined" to disable AppArmor confinement.
+func WithAppArmorProfile(profile string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if profile == "" {
+			return errors.New("apparmor profile must not be empty")
+		}
+
+		def.security().apparmorProfile = profile
+		return nil
+	}
+}
+
+// WithNoNewPrivileges prevents the container processes from gaining new privileges,
+// e.g. through setuid binaries.
+func WithNoNewPrivileges() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.security().noNewPrivileges = true
+		return nil
+	}
+}
+
+// WithHostUsernsMode opts the container out of the daemon's user namespace remapping,
+// running it in the host user namespace. It's not supported by rootless daemons.
+func WithHostUsernsMode() CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		def.security().hostUserns = true
+		return nil
+	}
+}
+
+// WithUlimits sets the ulimits for a container. Ulimits with the same name
+// as an existing one replace it.
+func WithUlimits(ulimits ...*container.Ulimit) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		sec := def.security()
+
+		for _, u := range ulimits {
+			if u == nil || u.Name == "" {
+				return errors.New("ulimit name must not be empty")
+			}
+			if u.Soft > u.Hard && u.Hard >= 0 {
+				return fmt.Errorf("ulimit %q: soft limit %d is greater than hard limit %d", u.Name, u.Soft, u.Hard)
+			}
+
+			sec.ulimits = slices.DeleteFunc(sec.ulimits, func(existing *container.Ulimit) bool {
+				return existing.Name == u.Name
+			})
+			sec.ulimits = append(sec.ulimits, u)
+		}
+
+		return nil
+	}
+}
+
+// WithUser sets the user the container process runs as, in any of the forms
+// supported by Docker: "user", "user:group", "uid" or "uid:gid".
+func WithUser(user string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		if user == "" {
+			return errors.New("user must not be empty")
+		}
+
+		def.user = user
+		return nil
+	}
+}
+
+// apply applies the security options to the host config.
+// Capabilities and security options are appended, while ulimits replace
+// the existing ones with the same name.
+func (s *securityOptions) apply(hostConfig *container.HostConfig) {
+	if s.resources != nil {
+		s.resources.apply(hostConfig)
+	}
+
+	hostConfig.CapAdd = appendCapabilities(hostConfig.CapAdd, s.capAdd)
+	hostConfig.CapDrop = appendCapabilities(hostConfig.CapDrop, s.capDrop)
+
+	if s.readOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+	}
+
+	for _, u := range s.ulimits {
+		hostConfig.Ulimits = slices.DeleteFunc(hostConfig.Ulimits, func(existing *container.Ulimit) bool {
+			return existing.Name == u.Name
+		})
+		hostConfig.Ulimits = append(hostConfig.Ulimits, u)
+	}
+
+	if s.seccompProfile != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+s.seccompProfile)
+	}
+	if s.apparmorProfile != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "apparmor="+s.apparmorProfile)
+	}
+	if s.noNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges=true")
+	}
+
+	if s.hostUserns {
+		hostConfig.UsernsMode = "host"
+	}
+}
+
+// validateDaemon checks that the security options are supported by the daemon,
+// using the daemon's reported info.
+func (s *securityOptions) validateDaemon(info system.Info) error {
+	var errs []error
+
+	if r := s.resources; r != nil {
+		if (r.MemoryBytes != 0 || r.MemoryReservationBytes != 0) && !info.MemoryLimit {
+			errs = append(errs, fmt.Errorf("%w: memory limit (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+		if r.MemorySwapBytes > 0 && !info.SwapLimit {
+			errs = append(errs, fmt.Errorf("%w: swap limit (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+		if r.CPUs != 0 && (!info.CPUCfsPeriod || !info.CPUCfsQuota) {
+			errs = append(errs, fmt.Errorf("%w: CPU limit (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+		if r.CPUShares != 0 && !info.CPUShares {
+			errs = append(errs, fmt.Errorf("%w: CPU shares (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+		if r.CPUSetCPUs != "" && !info.CPUSet {
+			errs = append(errs, fmt.Errorf("%w: CPU set (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+		if r.PidsLimit != 0 && !info.PidsLimit {
+			errs = append(errs, fmt.Errorf("%w: pids limit (cgroup %s)", ErrUnsupportedByDaemon, cgroupDescription(info)))
+		}
+	}
+
+	if s.seccompProfile != "" && s.seccompProfile != "unconfined" && !hasSecurityOption(info, "seccomp") {
+		errs = append(errs, fmt.Errorf("%w: seccomp profiles", ErrUnsupportedByDaemon))
+	}
+
+	if s.apparmorProfile != "" && s.apparmorProfile != "unconfined" && !hasSecurityOption(info, "apparmor") {
+		errs = append(errs, fmt.Errorf("%w: apparmor profiles", ErrUnsupportedByDaemon))
+	}
+
+	if s.hostUserns && hasSecurityOption(info, "rootless") {
+		errs = append(errs, fmt.Errorf("%w: host userns mode on a rootless daemon", ErrUnsupportedByDaemon))
+	}
+
+	return errors.Join(errs...)
+}
+
+// cgroupDescription returns a human readable description of the daemon's cgroup setup.
+func cgroupDescription(info system.Info) string {
+	version := info.CgroupVersion
+	if version == "" {
+		version = "unknown"
+	}
+
+	return fmt.Sprintf("version %s, driver %s", version, info.CgroupDriver)
+}
+
+// hasSecurityOption returns true if the daemon reports the given security option,
+// in the "name=<option>[,key=value]" format.
+func hasSecurityOption(info system.Info, name string) bool {
+	for _, opt := range info.SecurityOptions {
+		for _, kv := range strings.Split(opt, ",") {
+			if kv == "name="+name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
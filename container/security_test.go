@@ -0,0 +1,181 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResources(t *testing.T) {
+	t.Run("merge", func(t *testing.T) {
+		def := Definition{}
+
+		require.NoError(t, WithResources(Resources{MemoryBytes: 512 * 1024 * 1024, CPUs: 1})(&def))
+		require.NoError(t, WithResources(Resources{CPUs: 0.5, PidsLimit: 100})(&def))
+
+		hc := container.HostConfig{}
+		def.securityOpts.apply(&hc)
+
+		require.Equal(t, int64(512*1024*1024), hc.Memory)
+		require.Equal(t, int64(500_000_000), hc.NanoCPUs)
+		require.NotNil(t, hc.PidsLimit)
+		require.Equal(t, int64(100), *hc.PidsLimit)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		def := Definition{}
+
+		require.Error(t, WithResources(Resources{MemoryBytes: -1})(&def))
+		require.Error(t, WithResources(Resources{CPUs: -1})(&def))
+		require.Error(t, WithResources(Resources{MemoryBytes: 1024, MemorySwapBytes: 512})(&def))
+		require.Error(t, WithResources(Resources{MemoryBytes: 1024, MemoryReservationBytes: 2048})(&def))
+	})
+
+	t.Run("invalid-after-merge", func(t *testing.T) {
+		def := Definition{}
+
+		require.NoError(t, WithResources(Resources{MemorySwapBytes: 1024})(&def))
+		require.Error(t, WithResources(Resources{MemoryBytes: 2048})(&def))
+	})
+}
+
+func TestWithCapabilities(t *testing.T) {
+	t.Run("normalize-and-dedupe", func(t *testing.T) {
+		def := Definition{}
+
+		require.NoError(t, WithCapabilities([]string{"net_admin", "CAP_NET_ADMIN"}, []string{"ALL"})(&def))
+		require.NoError(t, WithCapabilities([]string{"SYS_TIME"}, nil)(&def))
+
+		require.Equal(t, []string{"CAP_NET_ADMIN", "CAP_SYS_TIME"}, def.securityOpts.capAdd)
+		require.Equal(t, []string{"ALL"}, def.securityOpts.capDrop)
+	})
+
+	t.Run("add-and-drop", func(t *testing.T) {
+		def := Definition{}
+
+		require.NoError(t, WithCapabilities([]string{"NET_ADMIN"}, nil)(&def))
+		require.Error(t, WithCapabilities(nil, []string{"CAP_NET_ADMIN"})(&def))
+	})
+}
+
+func TestWithUlimits(t *testing.T) {
+	def := Definition{}
+
+	require.NoError(t, WithUlimits(&container.Ulimit{Name: "nofile", Soft: 1024, Hard: 2048})(&def))
+	require.NoError(t, WithUlimits(&container.Ulimit{Name: "nofile", Soft: 4096, Hard: 4096}, &container.Ulimit{Name: "nproc", Soft: 10, Hard: 10})(&def))
+
+	hc := container.HostConfig{}
+	hc.Ulimits = []*container.Ulimit{{Name: "nofile", Soft: 1, Hard: 1}, {Name: "core", Soft: 0, Hard: 0}}
+	def.securityOpts.apply(&hc)
+
+	require.Equal(t, []*container.Ulimit{
+		{Name: "core", Soft: 0, Hard: 0},
+		{Name: "nofile", Soft: 4096, Hard: 4096},
+		{Name: "nproc", Soft: 10, Hard: 10},
+	}, hc.Ulimits)
+
+	require.Error(t, WithUlimits(&container.Ulimit{Name: "nofile", Soft: 10, Hard: 1})(&def))
+	require.Error(t, WithUlimits(nil)(&def))
+}
+
+func TestWithSeccompProfileFile(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		profile := filepath.Join(t.TempDir(), "seccomp.json")
+		require.NoError(t, os.WriteFile(profile, []byte(`{"defaultAction":"SCMP_ACT_ALLOW"}`), 0o600))
+
+		def := Definition{}
+		require.NoError(t, WithSeccompProfileFile(profile)(&def))
+
+		hc := container.HostConfig{}
+		def.securityOpts.apply(&hc)
+		require.Equal(t, []string{`seccomp={"defaultAction":"SCMP_ACT_ALLOW"}`}, hc.SecurityOpt)
+	})
+
+	t.Run("invalid-json", func(t *testing.T) {
+		profile := filepath.Join(t.TempDir(), "seccomp.json")
+		require.NoError(t, os.WriteFile(profile, []byte(`not json`), 0o600))
+
+		def := Definition{}
+		require.Error(t, WithSeccompProfileFile(profile)(&def))
+	})
+
+	t.Run("missing-file", func(t *testing.T) {
+		def := Definition{}
+		require.Error(t, WithSeccompProfileFile(filepath.Join(t.TempDir(), "missing.json"))(&def))
+	})
+}
+
+func TestSecurityOptions_apply(t *testing.T) {
+	def := Definition{}
+
+	require.NoError(t, WithReadOnlyRootfs()(&def))
+	require.NoError(t, WithNoNewPrivileges()(&def))
+	require.NoError(t, WithAppArmorProfile("docker-default")(&def))
+	require.NoError(t, WithHostUsernsMode()(&def))
+	require.NoError(t, WithCapabilities(nil, []string{"ALL"})(&def))
+
+	hc := container.HostConfig{
+		SecurityOpt: []string{"label=disable"},
+		CapDrop:     []string{"ALL"},
+	}
+	def.securityOpts.apply(&hc)
+
+	require.True(t, hc.ReadonlyRootfs)
+	require.Equal(t, container.UsernsMode("host"), hc.UsernsMode)
+	require.Equal(t, []string{"label=disable", "apparmor=docker-default", "no-new-privileges=true"}, hc.SecurityOpt)
+	require.Equal(t, []string{"ALL"}, hc.CapDrop)
+}
+
+func TestSecurityOptions_validateDaemon(t *testing.T) {
+	sec := &securityOptions{
+		resources:       &Resources{MemoryBytes: 1024, CPUs: 1, PidsLimit: 10},
+		seccompProfile:  "{}",
+		apparmorProfile: "docker-default",
+		hostUserns:      true,
+	}
+
+	t.Run("supported", func(t *testing.T) {
+		info := system.Info{
+			MemoryLimit:     true,
+			CPUCfsPeriod:    true,
+			CPUCfsQuota:     true,
+			PidsLimit:       true,
+			SecurityOptions: []string{"name=apparmor", "name=seccomp,profile=builtin", "name=cgroupns"},
+		}
+		require.NoError(t, sec.validateDaemon(info))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		info := system.Info{
+			CgroupVersion:   "1",
+			CgroupDriver:    "none",
+			SecurityOptions: []string{"name=rootless"},
+		}
+		err := sec.validateDaemon(info)
+		require.ErrorIs(t, err, ErrUnsupportedByDaemon)
+		require.ErrorContains(t, err, "memory limit (cgroup version 1, driver none)")
+		require.ErrorContains(t, err, "CPU limit")
+		require.ErrorContains(t, err, "pids limit")
+		require.ErrorContains(t, err, "seccomp profiles")
+		require.ErrorContains(t, err, "apparmor profiles")
+		require.ErrorContains(t, err, "rootless")
+	})
+
+	t.Run("unconfined", func(t *testing.T) {
+		unconfined := &securityOptions{seccompProfile: "unconfined", apparmorProfile: "unconfined"}
+		require.NoError(t, unconfined.validateDaemon(system.Info{}))
+	})
+}
+
+func TestWithUser(t *testing.T) {
+	def := Definition{}
+
+	require.NoError(t, WithUser("1000:1000")(&def))
+	require.Equal(t, "1000:1000", def.user)
+
+	require.Error(t, WithUser("")(&def))
+}
@@ -1,6 +1,8 @@
 package container
 
 import (
+	"context"
+	"path/filepath"
 	"regexp"
 	"testing"
 
@@ -34,14 +36,48 @@ type unwrapErrs interface {
 // [Create](...) in a test to ensure the
 // container is pruned when the function ends.
 // If the container is nil, it's a no-op.
+//
+// If the test failed, the diagnostics of the container, see [Container.WriteDiagnostics],
+// are written before it's terminated, into a directory named after the test and the short ID
+// of the container, in the artifacts directory. See [DiagnosticsEnv] and [DiagnosticsDirEnv].
 func Cleanup(tb testing.TB, ctr TerminableContainer, options ...TerminateOption) {
 	tb.Helper()
 
 	tb.Cleanup(func() {
+		if tb.Failed() {
+			writeTestDiagnostics(tb, ctr)
+		}
 		noErrorOrIgnored(tb, Terminate(ctr, options...))
 	})
 }
 
+// writeTestDiagnostics writes the diagnostics of the container of a failed test,
+// logging where they are. Failing to write them doesn't fail the test further.
+func writeTestDiagnostics(tb testing.TB, ctr TerminableContainer) {
+	tb.Helper()
+
+	writer, ok := ctr.(DiagnosticsWriter)
+	if !ok || isNil(ctr) {
+		return
+	}
+
+	root, ok := diagnosticsRoot()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), diagnosticsTimeout)
+	defer cancel()
+
+	dir := filepath.Join(root, diagnosticsTestDir(tb.Name()), writer.ShortID())
+	if err := writer.WriteDiagnostics(ctx, dir); err != nil {
+		tb.Logf("diagnostics of container %s partially written to %s: %v", writer.ShortID(), dir, err)
+		return
+	}
+
+	tb.Logf("diagnostics of container %s written to %s", writer.ShortID(), dir)
+}
+
 // isCleanupSafe checks if an error is cleanup safe.
 func isCleanupSafe(err error) bool {
 	if err == nil {
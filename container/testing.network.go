@@ -0,0 +1,237 @@
+package container
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+	dockerclient "github.com/moby/moby/client"
+
+	"github.com/docker/go-sdk/client"
+	"github.com/docker/go-sdk/network"
+)
+
+const (
+	// LabelTestNetwork is the label of the networks created by [WithTestNetwork], holding the name of the test.
+	LabelTestNetwork = client.LabelBase + ".test"
+
+	// testNetworkPrefix the prefix of the names of the test networks.
+	testNetworkPrefix = "sdk-test-"
+
+	// testNetworkNameMaxLen the maximum length of the test name in the name of a test network.
+	testNetworkNameMaxLen = 40
+
+	// testNetworkRemoveGrace the duration the removal of a test network is retried while it
+	// has active endpoints, before disconnecting them.
+	testNetworkRemoveGrace = 10 * time.Second
+
+	// testNetworkRemoveInterval the interval between two attempts to remove a test network.
+	testNetworkRemoveInterval = 200 * time.Millisecond
+
+	// testNetworkCreateTimeout the maximum duration of the creation of a test network.
+	testNetworkCreateTimeout = time.Minute
+
+	// testNetworkCleanupTimeout the maximum duration of the cleanup of a test network.
+	testNetworkCleanupTimeout = time.Minute
+)
+
+// errActiveEndpoints is a regular expression that matches the error for a network
+// removal while containers are still attached to it.
+var errActiveEndpoints = regexp.MustCompile(`has active endpoints`)
+
+var (
+	// testNetworksMtx guards the test networks.
+	testNetworksMtx sync.Mutex
+
+	// testNetworks the isolated networks of the running tests.
+	testNetworks = map[testing.TB]*testNetwork{}
+)
+
+// testNetwork is the isolated network of a test, and the containers attached to it.
+type testNetwork struct {
+	mtx sync.Mutex
+
+	// nw the network, nil until it's created.
+	nw *network.Network
+
+	// ownClient is true if the client of the network was created for it, so it's closed with it.
+	ownClient bool
+
+	// containers the containers created with the network, terminated before it's removed.
+	containers []*Container
+}
+
+// WithTestNetwork attaches the container to the isolated network of the test, with the given
+// network-scoped aliases. The network is created the first time the test uses it, with a unique
+// name derived from the name of the test and labelled with [LabelTestNetwork], so parallel tests
+// using the same aliases don't collide. Each subtest has its own network.
+//
+// The network is removed when the test ends, once the containers attached to it with
+// WithTestNetwork are terminated. While the daemon releases their endpoints, the removal
+// is retried, and the containers still attached after a grace period are disconnected.
+func WithTestNetwork(tb testing.TB, aliases ...string) CustomizeDefinitionOption {
+	return func(def *Definition) error {
+		tn, err := testNetworkOf(tb, def.dockerClient)
+		if err != nil {
+			return fmt.Errorf("test network: %w", err)
+		}
+
+		def.onCreated = append(def.onCreated, tn.track)
+
+		return WithNetwork(aliases, tn.nw)(def)
+	}
+}
+
+// testNetworkOf returns the network of the test, creating it with the given client if needed,
+// or with a new client if nil.
+func testNetworkOf(tb testing.TB, cli client.SDKClient) (*testNetwork, error) {
+	testNetworksMtx.Lock()
+	tn, ok := testNetworks[tb]
+	if !ok {
+		tn = &testNetwork{}
+		testNetworks[tb] = tn
+	}
+	testNetworksMtx.Unlock()
+
+	tn.mtx.Lock()
+	defer tn.mtx.Unlock()
+
+	if tn.nw != nil {
+		return tn, nil
+	}
+
+	opts := []network.Option{
+		network.WithName(testNetworkName(tb.Name())),
+		network.WithLabels(map[string]string{LabelTestNetwork: tb.Name()}),
+	}
+	if cli != nil {
+		opts = append(opts, network.WithClient(cli))
+	}
+
+	// the other containers of the test wait for the network, so its creation is bounded
+	ctx, cancel := context.WithTimeout(tb.Context(), testNetworkCreateTimeout)
+	defer cancel()
+
+	nw, err := network.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	tn.nw = nw
+	tn.ownClient = cli == nil
+
+	tb.Cleanup(func() {
+		testNetworksMtx.Lock()
+		delete(testNetworks, tb)
+		testNetworksMtx.Unlock()
+
+		noErrorOrIgnored(tb, tn.cleanup())
+	})
+
+	return tn, nil
+}
+
+// track records a container attached to the network, to terminate it before removing the network.
+func (tn *testNetwork) track(ctr *Container) {
+	tn.mtx.Lock()
+	defer tn.mtx.Unlock()
+
+	tn.containers = append(tn.containers, ctr)
+}
+
+// cleanup terminates the containers attached to the network, then removes it.
+func (tn *testNetwork) cleanup() error {
+	ctx, cancel := context.WithTimeout(context.Background(), testNetworkCleanupTimeout)
+	defer cancel()
+
+	tn.mtx.Lock()
+	containers := tn.containers
+	tn.containers = nil
+	tn.mtx.Unlock()
+
+	var errs []error
+	// terminating is a no-op for the containers already terminated by the test
+	for _, ctr := range containers {
+		if err := ctr.Terminate(ctx); !isCleanupSafe(err) {
+			errs = append(errs, fmt.Errorf("terminate container %s: %w", ctr.ShortID(), err))
+		}
+	}
+
+	cli := tn.nw.Client()
+	errs = append(errs, removeTestNetwork(ctx, cli, tn.nw.ID(), testNetworkRemoveGrace))
+
+	if tn.ownClient {
+		errs = append(errs, cli.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// removeTestNetwork removes a network, retrying while it has active endpoints, as the daemon
+// releases the endpoints of the removed containers asynchronously. Once grace elapsed, the
+// containers still attached are disconnected.
+func removeTestNetwork(ctx context.Context, cli client.SDKClient, id string, grace time.Duration) error {
+	deadline := time.Now().Add(grace)
+
+	for {
+		_, err := cli.NetworkRemove(ctx, id, dockerclient.NetworkRemoveOptions{})
+		if err == nil || errdefs.IsNotFound(err) {
+			return nil
+		}
+		if !errActiveEndpoints.MatchString(err.Error()) {
+			return fmt.Errorf("network remove: %w", err)
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(fmt.Errorf("network remove: %w", err), ctx.Err())
+		case <-time.After(testNetworkRemoveInterval):
+		}
+	}
+
+	inspect, err := cli.NetworkInspect(ctx, id, dockerclient.NetworkInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("network inspect: %w", err)
+	}
+
+	for containerID := range inspect.Network.Containers {
+		_, err := cli.NetworkDisconnect(ctx, id, dockerclient.NetworkDisconnectOptions{Container: containerID, Force: true})
+		if err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("network disconnect %s: %w", containerID, err)
+		}
+	}
+
+	if _, err := cli.NetworkRemove(ctx, id, dockerclient.NetworkRemoveOptions{}); err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("network remove: %w", err)
+	}
+
+	return nil
+}
+
+// testNetworkName returns a unique network name for a test, derived from its name.
+func testNetworkName(testName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, testName)
+
+	if len(name) > testNetworkNameMaxLen {
+		name = name[:testNetworkNameMaxLen]
+	}
+
+	return testNetworkPrefix + name + "-" + strings.ToLower(rand.Text()[:8])
+}
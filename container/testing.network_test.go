@@ -0,0 +1,81 @@
+package container_test
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/containerd/errdefs"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/client"
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/exec"
+)
+
+func TestWithTestNetwork(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		networks []string
+	)
+
+	t.Run("parallel", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				ctx := context.Background()
+
+				// both tests use the same alias
+				web, err := container.Run(ctx,
+					container.WithImage(nginxAlpineImage),
+					container.WithTestNetwork(t, "web"),
+				)
+				container.Cleanup(t, web)
+				require.NoError(t, err)
+
+				ctr, err := container.Run(ctx,
+					container.WithImage(alpineLatest),
+					container.WithCmd("sleep", "300"),
+					container.WithTestNetwork(t),
+				)
+				container.Cleanup(t, ctr)
+				require.NoError(t, err)
+
+				nws, err := ctr.Networks(ctx)
+				require.NoError(t, err)
+				require.Len(t, nws, 1)
+
+				webNws, err := web.Networks(ctx)
+				require.NoError(t, err)
+				require.Equal(t, nws, webNws)
+
+				code, r, err := ctr.Exec(ctx, []string{"wget", "-qO-", "http://web"}, exec.Multiplexed())
+				require.NoError(t, err)
+				require.Zero(t, code)
+				out, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Contains(t, string(out), "Welcome to nginx!")
+
+				mtx.Lock()
+				networks = append(networks, nws[0])
+				mtx.Unlock()
+			})
+		}
+	})
+
+	require.Len(t, networks, 2)
+	require.NotEqual(t, networks[0], networks[1])
+
+	cli, err := client.New(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// the networks are removed with their tests
+	for _, nw := range networks {
+		_, err := cli.NetworkInspect(context.Background(), nw, dockerclient.NetworkInspectOptions{})
+		require.True(t, errdefs.IsNotFound(err), "network %s: %v", nw, err)
+	}
+}
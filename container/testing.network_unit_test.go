@@ -0,0 +1,103 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/moby/moby/api/types/network"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	sdkclient "github.com/docker/go-sdk/client"
+)
+
+// networkRemoveFakeClient fails to remove the network with the given errors, in order.
+type networkRemoveFakeClient struct {
+	sdkclient.SDKClient
+
+	removeErrs   []error
+	removes      int
+	attached     []string
+	disconnected []string
+}
+
+func (f *networkRemoveFakeClient) NetworkRemove(_ context.Context, _ string, _ dockerclient.NetworkRemoveOptions) (dockerclient.NetworkRemoveResult, error) {
+	f.removes++
+	if len(f.removeErrs) == 0 {
+		return dockerclient.NetworkRemoveResult{}, nil
+	}
+
+	err := f.removeErrs[0]
+	f.removeErrs = f.removeErrs[1:]
+	return dockerclient.NetworkRemoveResult{}, err
+}
+
+func (f *networkRemoveFakeClient) NetworkInspect(_ context.Context, _ string, _ dockerclient.NetworkInspectOptions) (dockerclient.NetworkInspectResult, error) {
+	containers := map[string]network.EndpointResource{}
+	for _, id := range f.attached {
+		containers[id] = network.EndpointResource{}
+	}
+	return dockerclient.NetworkInspectResult{Network: network.Inspect{Containers: containers}}, nil
+}
+
+func (f *networkRemoveFakeClient) NetworkDisconnect(_ context.Context, _ string, options dockerclient.NetworkDisconnectOptions) (dockerclient.NetworkDisconnectResult, error) {
+	if !options.Force {
+		return dockerclient.NetworkDisconnectResult{}, errors.New("disconnect is not forced")
+	}
+	f.disconnected = append(f.disconnected, options.Container)
+	// once disconnected, the network can be removed
+	f.removeErrs = nil
+	return dockerclient.NetworkDisconnectResult{}, nil
+}
+
+func TestRemoveTestNetwork(t *testing.T) {
+	errActive := errors.New("error while removing network: network sdk-test-1234 has active endpoints")
+
+	t.Run("removed", func(t *testing.T) {
+		fake := &networkRemoveFakeClient{}
+		require.NoError(t, removeTestNetwork(context.Background(), fake, "1234", time.Minute))
+		require.Equal(t, 1, fake.removes)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		fake := &networkRemoveFakeClient{removeErrs: []error{errdefs.ErrNotFound}}
+		require.NoError(t, removeTestNetwork(context.Background(), fake, "1234", time.Minute))
+	})
+
+	t.Run("active-endpoints-released", func(t *testing.T) {
+		fake := &networkRemoveFakeClient{removeErrs: []error{errActive, errActive}}
+		require.NoError(t, removeTestNetwork(context.Background(), fake, "1234", time.Minute))
+		require.Equal(t, 3, fake.removes)
+		require.Empty(t, fake.disconnected)
+	})
+
+	t.Run("active-endpoints-disconnected", func(t *testing.T) {
+		fake := &networkRemoveFakeClient{
+			removeErrs: []error{errActive, errActive, errActive, errActive},
+			attached:   []string{"abcdef"},
+		}
+		require.NoError(t, removeTestNetwork(context.Background(), fake, "1234", 0))
+		require.Equal(t, []string{"abcdef"}, fake.disconnected)
+		require.Equal(t, 2, fake.removes)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		fake := &networkRemoveFakeClient{removeErrs: []error{errors.New("daemon unreachable")}}
+		err := removeTestNetwork(context.Background(), fake, "1234", time.Minute)
+		require.EqualError(t, err, "network remove: daemon unreachable")
+	})
+}
+
+func TestTestNetworkName(t *testing.T) {
+	name := testNetworkName("TestRun/with alias:web")
+	require.True(t, strings.HasPrefix(name, "sdk-test-TestRun-with-alias-web-"), name)
+	require.Len(t, name, len("sdk-test-TestRun-with-alias-web-")+8)
+	require.NotEqual(t, name, testNetworkName("TestRun/with alias:web"))
+
+	long := testNetworkName(strings.Repeat("a", 100))
+	require.Len(t, long, len(testNetworkPrefix)+testNetworkNameMaxLen+1+8)
+}
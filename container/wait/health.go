@@ -2,6 +2,8 @@ package wait
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/moby/moby/api/types/container"
@@ -20,6 +22,34 @@ type HealthStrategy struct {
 
 	// additional properties
 	PollInterval time.Duration
+
+	// failOnUnhealthy makes the strategy fail as soon as the container is reported unhealthy,
+	// instead of waiting for it to recover until the timeout.
+	failOnUnhealthy bool
+}
+
+// UnhealthyError is returned when the container is reported unhealthy.
+// It carries the results of the last health checks run by the Docker daemon.
+type UnhealthyError struct {
+	// FailingStreak the number of consecutive failed health checks.
+	FailingStreak int
+
+	// Log the last health check results, oldest first.
+	Log []*container.HealthcheckResult
+}
+
+// Error implements the error interface, including the output of the last health checks.
+func (e *UnhealthyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "container is unhealthy (failing streak: %d)", e.FailingStreak)
+	for _, r := range e.Log {
+		if r == nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  health check at %s exited with code %d: %s", r.Start.Format(time.RFC3339), r.ExitCode, strings.TrimSpace(r.Output))
+	}
+
+	return sb.String()
 }
 
 // NewHealthStrategy constructs with polling interval of 100 milliseconds and startup timeout of 60 seconds by default
@@ -45,6 +75,14 @@ func (ws *HealthStrategy) WithPollInterval(pollInterval time.Duration) *HealthSt
 	return ws
 }
 
+// WithFailOnUnhealthy makes the strategy fail as soon as the container is reported unhealthy,
+// returning an [UnhealthyError] with the health check log. By default, the strategy keeps
+// waiting for the container to recover until the timeout.
+func (ws *HealthStrategy) WithFailOnUnhealthy() *HealthStrategy {
+	ws.failOnUnhealthy = true
+	return ws
+}
+
 // ForHealthCheck is the default construction for the fluid interface.
 //
 // For Example:
@@ -75,9 +113,16 @@ func (ws *HealthStrategy) WaitUntilReady(ctx context.Context, target StrategyTar
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	// lastUnhealthy keeps the health of the last check reporting the container as unhealthy,
+	// so that its log is surfaced if the timeout is reached.
+	var lastUnhealthy *UnhealthyError
+
 	for {
 		select {
 		case <-ctx.Done():
+			if lastUnhealthy != nil {
+				return fmt.Errorf("%w: %w", ctx.Err(), lastUnhealthy)
+			}
 			return ctx.Err()
 		default:
 			state, err := target.State(ctx)
@@ -87,6 +132,15 @@ func (ws *HealthStrategy) WaitUntilReady(ctx context.Context, target StrategyTar
 			if err := checkState(state); err != nil {
 				return err
 			}
+			if state.Health != nil && state.Health.Status == container.Unhealthy {
+				lastUnhealthy = &UnhealthyError{
+					FailingStreak: state.Health.FailingStreak,
+					Log:           state.Health.Log,
+				}
+				if ws.failOnUnhealthy {
+					return lastUnhealthy
+				}
+			}
 			if state.Health == nil || state.Health.Status != container.Healthy {
 				time.Sleep(ws.PollInterval)
 				continue
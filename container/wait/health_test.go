@@ -182,3 +182,55 @@ func TestWaitForHealthFailsDueToUnexpectedContainerStatus(t *testing.T) {
 	require.Error(t, err)
 	require.EqualError(t, err, "unexpected container status \"dead\"")
 }
+
+func TestWaitForHealthTimesOutForUnhealthyWithLog(t *testing.T) {
+	target := &healthStrategyTarget{
+		state: &container.State{
+			Running: true,
+			Health: &container.Health{
+				Status:        container.Unhealthy,
+				FailingStreak: 3,
+				Log: []*container.HealthcheckResult{
+					{ExitCode: 1, Output: "connection refused\n"},
+				},
+			},
+		},
+	}
+	wg := NewHealthStrategy().WithTimeout(100 * time.Millisecond)
+	err := wg.WaitUntilReady(context.Background(), target)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var unhealthyErr *UnhealthyError
+	require.ErrorAs(t, err, &unhealthyErr)
+	require.Equal(t, 3, unhealthyErr.FailingStreak)
+	require.ErrorContains(t, err, "exited with code 1: connection refused")
+}
+
+func TestWaitForHealthFailsOnUnhealthy(t *testing.T) {
+	target := &healthStrategyTarget{
+		state: &container.State{
+			Running: true,
+			Health: &container.Health{
+				Status:        container.Unhealthy,
+				FailingStreak: 2,
+				Log: []*container.HealthcheckResult{
+					{ExitCode: 1, Output: "first failure"},
+					{ExitCode: 1, Output: "second failure"},
+				},
+			},
+		},
+	}
+	wg := NewHealthStrategy().
+		WithTimeout(5 * time.Second).
+		WithFailOnUnhealthy()
+
+	err := wg.WaitUntilReady(context.Background(), target)
+	require.NotErrorIs(t, err, context.DeadlineExceeded)
+
+	var unhealthyErr *UnhealthyError
+	require.ErrorAs(t, err, &unhealthyErr)
+	require.Len(t, unhealthyErr.Log, 2)
+	require.ErrorContains(t, err, "container is unhealthy (failing streak: 2)")
+	require.ErrorContains(t, err, "second failure")
+}
@@ -140,7 +140,7 @@ func (hp *HostPortStrategy) String() string {
 // can be checked against an empty string.
 func (hp *HostPortStrategy) detectInternalPort(ctx context.Context, target StrategyTarget) (network.Port, error) {
 	var internalPort network.Port
-	inspect, err := target.Inspect(ctx)
+	inspect, err := inspectTarget(ctx, target, hp.PollInterval)
 	if err != nil {
 		return internalPort, fmt.Errorf("inspect: %w", err)
 	}
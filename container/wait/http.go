@@ -212,7 +212,7 @@ func (ws *HTTPStrategy) WaitUntilReady(ctx context.Context, target StrategyTarge
 			return err
 		}
 
-		inspect, err := target.Inspect(ctx)
+		inspect, err := inspectTarget(ctx, target, ws.PollInterval)
 		if err != nil {
 			return err
 		}
@@ -36,6 +36,24 @@ type StrategyTarget interface {
 	Logger() *slog.Logger
 }
 
+// CachedInspector is an optional interface for a [StrategyTarget] able to serve
+// inspect results from a cache. Strategies reading data that does not change on
+// every poll, such as port bindings, use it to avoid a round-trip to the daemon.
+type CachedInspector interface {
+	// CachedInspect returns an inspect result not older than maxAge.
+	CachedInspect(ctx context.Context, maxAge time.Duration) (dockerclient.ContainerInspectResult, error)
+}
+
+// inspectTarget returns the inspect result of the target, reusing a cached result
+// not older than maxAge if the target implements [CachedInspector].
+func inspectTarget(ctx context.Context, target StrategyTarget, maxAge time.Duration) (dockerclient.ContainerInspectResult, error) {
+	if cached, ok := target.(CachedInspector); ok {
+		return cached.CachedInspect(ctx, maxAge)
+	}
+
+	return target.Inspect(ctx)
+}
+
 func checkTarget(ctx context.Context, target StrategyTarget) error {
 	state, err := target.State(ctx)
 	if err != nil {
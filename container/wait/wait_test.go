@@ -5,10 +5,13 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"testing"
+	"time"
 
 	"github.com/moby/moby/api/types/container"
 	"github.com/moby/moby/api/types/network"
 	"github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
 
 	"github.com/docker/go-sdk/container/exec"
 )
@@ -58,3 +61,48 @@ func (st *MockStrategyTarget) CopyFromContainer(ctx context.Context, filePath st
 func (st *MockStrategyTarget) Logger() *slog.Logger {
 	return st.LoggerImpl()
 }
+
+// cachedMockStrategyTarget is a [MockStrategyTarget] implementing [CachedInspector].
+type cachedMockStrategyTarget struct {
+	MockStrategyTarget
+	CachedInspectImpl func(context.Context, time.Duration) (client.ContainerInspectResult, error)
+}
+
+func (st *cachedMockStrategyTarget) CachedInspect(ctx context.Context, maxAge time.Duration) (client.ContainerInspectResult, error) {
+	return st.CachedInspectImpl(ctx, maxAge)
+}
+
+func TestInspectTarget(t *testing.T) {
+	t.Run("no-cache", func(t *testing.T) {
+		var inspected bool
+		target := &MockStrategyTarget{
+			InspectImpl: func(_ context.Context) (client.ContainerInspectResult, error) {
+				inspected = true
+				return client.ContainerInspectResult{}, nil
+			},
+		}
+
+		_, err := inspectTarget(context.Background(), target, time.Second)
+		require.NoError(t, err)
+		require.True(t, inspected)
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		var maxAge time.Duration
+		target := &cachedMockStrategyTarget{
+			MockStrategyTarget: MockStrategyTarget{
+				InspectImpl: func(_ context.Context) (client.ContainerInspectResult, error) {
+					return client.ContainerInspectResult{}, errors.New("inspect must not be called")
+				},
+			},
+			CachedInspectImpl: func(_ context.Context, d time.Duration) (client.ContainerInspectResult, error) {
+				maxAge = d
+				return client.ContainerInspectResult{}, nil
+			},
+		}
+
+		_, err := inspectTarget(context.Background(), target, 100*time.Millisecond)
+		require.NoError(t, err)
+		require.Equal(t, 100*time.Millisecond, maxAge)
+	})
+}
@@ -0,0 +1,114 @@
+// Package modules provides the building blocks of reusable service containers,
+// such as a database, a cache or a message broker, defined on top of [container.Run].
+//
+// A module implements [Module], describing the default image, configuration and
+// readiness of the service, and how to connect to it. [Run] runs a module,
+// applying the options of the caller on top of its defaults, and returns a
+// [Container] exposing its typed [ConnectionInfo].
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docker/go-sdk/client"
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+// LabelModule is the label of the containers run by [Run], holding the name of the module.
+const LabelModule = client.LabelBase + ".module"
+
+// ConnectionInfo is the information to connect to the service of a module,
+// e.g. the URL of a database and its credentials. Modules define their own type,
+// holding the fields their clients need.
+type ConnectionInfo interface {
+	// ConnectionString returns the string to connect to the service, e.g. a DSN or a URL.
+	ConnectionString() string
+}
+
+// Module is a reusable service container, whose connection information is of type C.
+type Module[C ConnectionInfo] interface {
+	// Name returns the name of the module, e.g. "postgres".
+	Name() string
+
+	// DefaultImage returns the image the module runs by default, pinned to a tag or a digest.
+	DefaultImage() string
+
+	// DefaultCustomizers returns the customizers applied to the container before the ones
+	// passed to [Run], e.g. exposing the ports of the service and configuring it.
+	DefaultCustomizers() []container.ContainerCustomizer
+
+	// WaitStrategy returns the strategy waiting for the service to be ready.
+	WaitStrategy() wait.Strategy
+
+	// ConnectionInfo returns the information to connect to the service running in the container.
+	ConnectionInfo(ctx context.Context, ctr *container.Container) (C, error)
+}
+
+// Container is a container running a [Module].
+type Container[C ConnectionInfo] struct {
+	*container.Container
+
+	module Module[C]
+}
+
+// Module returns the module the container runs.
+func (c *Container[C]) Module() Module[C] {
+	return c.module
+}
+
+// ConnectionInfo returns the information to connect to the service running in the container.
+func (c *Container[C]) ConnectionInfo(ctx context.Context) (C, error) {
+	return c.module.ConnectionInfo(ctx, c.Container)
+}
+
+// Run runs the module, applying, in order, its default image, customizers and wait strategy,
+// then the given options, so they override the defaults of the module. E.g. [container.WithImage]
+// replaces the default image, [container.WithWaitStrategy] replaces the wait strategy, and
+// [container.WithAdditionalWaitStrategy] adds a condition to it. The [Option]s of the module
+// are ignored, as they are applied by the module when it's created, see [ApplyOptions].
+//
+// As with [container.Run], the container is returned with the error if it was created,
+// so it can be cleaned up.
+func Run[C ConnectionInfo](ctx context.Context, m Module[C], opts ...container.ContainerCustomizer) (*Container[C], error) {
+	customizers, err := customizers(m, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr, err := container.Run(ctx, customizers...)
+	if ctr == nil {
+		return nil, fmt.Errorf("run %s: %w", m.Name(), err)
+	}
+
+	c := &Container[C]{Container: ctr, module: m}
+	if err != nil {
+		return c, fmt.Errorf("run %s: %w", m.Name(), err)
+	}
+
+	return c, nil
+}
+
+// customizers returns the customizers running the module, with the given options.
+func customizers[C ConnectionInfo](m Module[C], opts []container.ContainerCustomizer) ([]container.ContainerCustomizer, error) {
+	if m.Name() == "" {
+		return nil, errors.New("module name is required")
+	}
+
+	defaults := m.DefaultCustomizers()
+
+	customizers := make([]container.ContainerCustomizer, 0, len(defaults)+len(opts)+3)
+	customizers = append(customizers,
+		container.WithImage(m.DefaultImage()),
+		container.WithLabels(map[string]string{LabelModule: m.Name()}),
+	)
+	customizers = append(customizers, defaults...)
+	if strategy := m.WaitStrategy(); strategy != nil {
+		customizers = append(customizers, container.WithWaitStrategy(strategy))
+	}
+	customizers = append(customizers, opts...)
+
+	return customizers, nil
+}
@@ -0,0 +1,77 @@
+package modules_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/moby/moby/api/types/network"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+	"github.com/docker/go-sdk/modules/modulestest"
+)
+
+// nginxInfo is the connection information of the nginx module.
+type nginxInfo struct {
+	URL string
+}
+
+func (i nginxInfo) ConnectionString() string {
+	return i.URL
+}
+
+// nginxModule is a minimal module serving HTTP, as a module package would define it.
+type nginxModule struct{}
+
+func (nginxModule) Name() string {
+	return "nginx"
+}
+
+func (nginxModule) DefaultImage() string {
+	return "nginx:1.27-alpine"
+}
+
+func (nginxModule) DefaultCustomizers() []container.ContainerCustomizer {
+	return []container.ContainerCustomizer{
+		container.WithExposedPorts("80/tcp"),
+	}
+}
+
+func (nginxModule) WaitStrategy() wait.Strategy {
+	return wait.ForListeningPort(network.MustParsePort("80/tcp"))
+}
+
+func (nginxModule) ConnectionInfo(ctx context.Context, ctr *container.Container) (nginxInfo, error) {
+	endpoint, err := ctr.PortEndpoint(ctx, network.MustParsePort("80/tcp"), "http")
+	if err != nil {
+		return nginxInfo{}, fmt.Errorf("port endpoint: %w", err)
+	}
+
+	return nginxInfo{URL: endpoint}, nil
+}
+
+func TestConformance(t *testing.T) {
+	modulestest.Conformance(t, modulestest.Suite[nginxInfo]{
+		Module: nginxModule{},
+		Ping: func(ctx context.Context, info nginxInfo) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.URL, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			}
+
+			return nil
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package modules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/container/wait"
+)
+
+type testInfo string
+
+func (i testInfo) ConnectionString() string {
+	return string(i)
+}
+
+type testModule struct {
+	name string
+}
+
+func (m testModule) Name() string {
+	return m.name
+}
+
+func (m testModule) DefaultImage() string {
+	return "nginx:1.27-alpine"
+}
+
+func (m testModule) DefaultCustomizers() []container.ContainerCustomizer {
+	return []container.ContainerCustomizer{
+		container.WithExposedPorts("80/tcp"),
+		container.WithLabels(map[string]string{"tier": "default"}),
+	}
+}
+
+func (m testModule) WaitStrategy() wait.Strategy {
+	return wait.ForLog("start worker processes")
+}
+
+func (m testModule) ConnectionInfo(_ context.Context, _ *container.Container) (testInfo, error) {
+	return "http://localhost", nil
+}
+
+func TestCustomizers(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		customizers, err := customizers[testInfo](testModule{name: "web"}, nil)
+		require.NoError(t, err)
+
+		var def container.Definition
+		for _, opt := range customizers {
+			require.NoError(t, opt.Customize(&def))
+		}
+		require.Equal(t, "nginx:1.27-alpine", def.Image())
+		require.Equal(t, map[string]string{LabelModule: "web", "tier": "default"}, def.Labels())
+	})
+
+	t.Run("options-override-defaults", func(t *testing.T) {
+		customizers, err := customizers[testInfo](testModule{name: "web"}, []container.ContainerCustomizer{
+			container.WithImage("nginx:1.26-alpine"),
+			container.WithLabels(map[string]string{"tier": "custom"}),
+		})
+		require.NoError(t, err)
+
+		var def container.Definition
+		for _, opt := range customizers {
+			require.NoError(t, opt.Customize(&def))
+		}
+		require.Equal(t, "nginx:1.26-alpine", def.Image())
+		require.Equal(t, map[string]string{LabelModule: "web", "tier": "custom"}, def.Labels())
+	})
+
+	t.Run("no-name", func(t *testing.T) {
+		_, err := customizers[testInfo](testModule{}, nil)
+		require.EqualError(t, err, "module name is required")
+	})
+}
+
+func TestRun_noName(t *testing.T) {
+	ctr, err := Run[testInfo](context.Background(), testModule{})
+	require.Nil(t, ctr)
+	require.EqualError(t, err, "module name is required")
+}
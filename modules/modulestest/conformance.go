@@ -0,0 +1,123 @@
+// Package modulestest provides a conformance test harness for the modules built
+// with [modules.Module], so they behave consistently.
+package modulestest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+	"github.com/docker/go-sdk/modules"
+)
+
+// labelConformance is the label the harness adds to the container, checking
+// the options of the caller are applied.
+const labelConformance = "com.docker.sdk.modulestest"
+
+// Suite describes the module under test.
+type Suite[C modules.ConnectionInfo] struct {
+	// Module the module under test, with its default settings.
+	Module modules.Module[C]
+
+	// Run runs the module with the given options, as the users of the module do,
+	// e.g. the Run function of the module package.
+	// Default: [modules.Run] with Module.
+	Run func(ctx context.Context, opts ...container.ContainerCustomizer) (*modules.Container[C], error)
+
+	// Ping checks the service is reachable with the connection information, e.g. connecting
+	// with the client of the service and running a query. Optional.
+	Ping func(ctx context.Context, info C) error
+}
+
+// Conformance runs the conformance tests of a module, as subtests of t:
+//   - "definition" checks the module has a name, a pinned default image and a wait strategy,
+//     and that its default customizers apply, without setting the image.
+//   - "run" runs the module, checking it's running and ready, labelled with its name,
+//     that the options passed to Run are applied, and that the connection information
+//     is available and, with Ping, valid.
+//   - "terminate" checks the container is removed when terminated.
+//
+// The "run" and "terminate" subtests need a Docker daemon.
+func Conformance[C modules.ConnectionInfo](t *testing.T, s Suite[C]) {
+	t.Helper()
+
+	require.NotNil(t, s.Module, "the module is required")
+
+	run := s.Run
+	if run == nil {
+		run = func(ctx context.Context, opts ...container.ContainerCustomizer) (*modules.Container[C], error) {
+			return modules.Run(ctx, s.Module, opts...)
+		}
+	}
+
+	t.Run("definition", func(t *testing.T) {
+		m := s.Module
+
+		require.NotEmpty(t, m.Name(), "the module must have a name")
+		require.Equal(t, strings.ToLower(m.Name()), m.Name(), "the name of the module must be lowercase")
+		require.True(t, isPinned(m.DefaultImage()), "the default image %q must be pinned to a tag, other than latest, or a digest", m.DefaultImage())
+		require.NotNil(t, m.WaitStrategy(), "the module must wait for the service to be ready")
+
+		var def container.Definition
+		for _, opt := range m.DefaultCustomizers() {
+			require.NoError(t, opt.Customize(&def), "the default customizers must apply")
+		}
+		require.Empty(t, def.Image(), "the default customizers must not set the image, use DefaultImage")
+	})
+
+	var ctr *modules.Container[C]
+
+	t.Run("run", func(t *testing.T) {
+		ctx := context.Background()
+
+		var err error
+		ctr, err = run(ctx, container.WithLabels(map[string]string{labelConformance: "true"}))
+		container.Cleanup(t, ctr)
+		require.NoError(t, err)
+		require.NotNil(t, ctr)
+
+		require.True(t, ctr.IsRunning(), "the container must be running")
+
+		inspect, err := ctr.Inspect(ctx)
+		require.NoError(t, err)
+		require.Equal(t, s.Module.Name(), inspect.Container.Config.Labels[modules.LabelModule], "the container must be labelled with the name of the module")
+		require.Equal(t, "true", inspect.Container.Config.Labels[labelConformance], "the options passed to Run must be applied")
+
+		info, err := ctr.ConnectionInfo(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, info.ConnectionString(), "the connection string must not be empty")
+
+		again, err := ctr.ConnectionInfo(ctx)
+		require.NoError(t, err)
+		require.Equal(t, info.ConnectionString(), again.ConnectionString(), "the connection information must be stable")
+
+		if s.Ping != nil {
+			require.NoError(t, s.Ping(ctx, info), "the service must be reachable with the connection information")
+		}
+	})
+
+	t.Run("terminate", func(t *testing.T) {
+		if ctr == nil {
+			t.Skip("the module did not run")
+		}
+
+		require.NoError(t, ctr.Terminate(context.Background()))
+		require.Equal(t, container.StateRemoved, ctr.LifecycleState())
+	})
+}
+
+// isPinned returns true if the image reference has a digest, or a tag other than latest.
+func isPinned(ref string) bool {
+	if strings.Contains(ref, "@") {
+		return true
+	}
+
+	// the registry host can have a port, so the tag is looked up in the last path component
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	_, tag, ok := strings.Cut(name, ":")
+
+	return ok && tag != "" && tag != "latest"
+}
@@ -0,0 +1,22 @@
+package modulestest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPinned(t *testing.T) {
+	for ref, pinned := range map[string]bool{
+		"postgres:16-alpine":                    true,
+		"docker.io/library/redis:7.4":           true,
+		"localhost:5000/kafka:3.8":              true,
+		"alpine@sha256:0123456789abcdef":        true,
+		"postgres":                              false,
+		"postgres:latest":                       false,
+		"localhost:5000/kafka":                  false,
+		"registry.example.com:443/team/service": false,
+	} {
+		require.Equal(t, pinned, isPinned(ref), ref)
+	}
+}
@@ -0,0 +1,39 @@
+package modules
+
+import (
+	"fmt"
+
+	"github.com/docker/go-sdk/container"
+)
+
+// Option is an option of a module, configuring its settings of type S, e.g. the name
+// of a database or its password. An Option is a [container.ContainerCustomizer], leaving
+// the container definition untouched, so the options of a module and of the container
+// are passed together to the function creating the module:
+//
+//	pg, err := postgres.Run(ctx,
+//		postgres.WithDatabase("orders"),
+//		container.WithEnv(map[string]string{"TZ": "UTC"}),
+//	)
+type Option[S any] func(*S) error
+
+// Customize implements [container.ContainerCustomizer]. It's a no-op, as the option
+// is applied to the settings of the module by [ApplyOptions].
+func (o Option[S]) Customize(_ *container.Definition) error {
+	return nil
+}
+
+// ApplyOptions applies, in order, the [Option]s of type Option[S] among opts to the settings,
+// ignoring the other customizers. Modules call it with their default settings before running,
+// so their customizers and connection information reflect the options.
+func ApplyOptions[S any](settings *S, opts []container.ContainerCustomizer) error {
+	for _, opt := range opts {
+		if o, ok := opt.(Option[S]); ok {
+			if err := o(settings); err != nil {
+				return fmt.Errorf("apply option: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
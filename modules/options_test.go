@@ -0,0 +1,72 @@
+package modules
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/container"
+)
+
+type testSettings struct {
+	database string
+	users    []string
+}
+
+func withDatabase(name string) Option[testSettings] {
+	return func(s *testSettings) error {
+		s.database = name
+		return nil
+	}
+}
+
+func withUser(name string) Option[testSettings] {
+	return func(s *testSettings) error {
+		s.users = append(s.users, name)
+		return nil
+	}
+}
+
+func TestApplyOptions(t *testing.T) {
+	t.Run("in-order", func(t *testing.T) {
+		settings := testSettings{database: "test"}
+
+		err := ApplyOptions(&settings, []container.ContainerCustomizer{
+			withUser("alice"),
+			container.WithImage("postgres:16-alpine"),
+			withDatabase("orders"),
+			withUser("bob"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, testSettings{database: "orders", users: []string{"alice", "bob"}}, settings)
+	})
+
+	t.Run("other-settings", func(t *testing.T) {
+		settings := testSettings{database: "test"}
+
+		err := ApplyOptions(&settings, []container.ContainerCustomizer{
+			Option[struct{ name string }](func(s *struct{ name string }) error {
+				s.name = "other"
+				return nil
+			}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, testSettings{database: "test"}, settings)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		cause := errors.New("invalid database name")
+
+		err := ApplyOptions(&testSettings{}, []container.ContainerCustomizer{
+			Option[testSettings](func(_ *testSettings) error { return cause }),
+		})
+		require.ErrorIs(t, err, cause)
+	})
+}
+
+func TestOption_Customize(t *testing.T) {
+	var def container.Definition
+	require.NoError(t, withDatabase("orders").Customize(&def))
+	require.Equal(t, container.Definition{}, def)
+}
@@ -0,0 +1,10 @@
+package modules
+
+const (
+	version = "0.1.0-alpha001"
+)
+
+// Version returns the version of the modules package.
+func Version() string {
+	return version
+}
@@ -0,0 +1,364 @@
+// Package sdktest provides testing helpers for the code using the Docker Go SDK.
+package sdktest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dockerclient "github.com/moby/moby/client"
+
+	"github.com/docker/go-sdk/client"
+)
+
+const (
+	// defaultLeakTimeout the default maximum duration waiting for the leaked resources to be removed.
+	defaultLeakTimeout = 5 * time.Second
+
+	// leakPollInterval the interval between two checks of the leaked resources.
+	leakPollInterval = 200 * time.Millisecond
+)
+
+// ResourceKind is the kind of a Docker resource.
+type ResourceKind string
+
+const (
+	// ResourceContainer is a container, whatever its state.
+	ResourceContainer ResourceKind = "container"
+
+	// ResourceNetwork is a network.
+	ResourceNetwork ResourceKind = "network"
+
+	// ResourceVolume is a volume.
+	ResourceVolume ResourceKind = "volume"
+)
+
+// Resource is a Docker resource created by the SDK, labelled with [client.LabelBase].
+type Resource struct {
+	// Kind the kind of the resource.
+	Kind ResourceKind
+
+	// ID the ID of the resource, its name for volumes.
+	ID string
+
+	// Name the name of the resource.
+	Name string
+
+	// Image the image of the container, empty for other resources.
+	Image string
+
+	// Created the time the resource was created, zero if unknown.
+	Created time.Time
+}
+
+// String returns a description of the resource, e.g. for reporting it as leaked.
+func (r Resource) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(r.Kind) + " " + shortID(r.ID))
+	if r.Name != "" && r.Name != r.ID {
+		sb.WriteString(" (" + r.Name + ")")
+	}
+	if r.Image != "" {
+		sb.WriteString(" image=" + r.Image)
+	}
+	if !r.Created.IsZero() {
+		sb.WriteString(" created=" + r.Created.Format(time.RFC3339))
+	}
+
+	return sb.String()
+}
+
+// LeakError is the error reporting the resources created by the SDK which are still present.
+type LeakError struct {
+	// Resources the leaked resources.
+	Resources []Resource
+}
+
+// Error returns the error message, listing the leaked resources.
+func (e *LeakError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "found %d leaked Docker resources:", len(e.Resources))
+	for _, r := range e.Resources {
+		sb.WriteString("\n  - " + r.String())
+	}
+
+	return sb.String()
+}
+
+// LeakOption is a type that represents an option for checking the leaked resources.
+type LeakOption func(*leakOptions)
+
+// leakOptions holds the options for checking the leaked resources.
+type leakOptions struct {
+	client  client.SDKClient
+	timeout time.Duration
+	ignores []func(Resource) bool
+}
+
+// WithClient returns a LeakOption that sets the client listing the resources.
+// Default: a new client, see [client.New].
+func WithClient(c client.SDKClient) LeakOption {
+	return func(o *leakOptions) {
+		o.client = c
+	}
+}
+
+// WithTimeout returns a LeakOption that sets the maximum duration waiting for the resources
+// to be removed, e.g. the containers removed by the daemon once they exit, before reporting them.
+// Default: 5 seconds.
+func WithTimeout(timeout time.Duration) LeakOption {
+	return func(o *leakOptions) {
+		o.timeout = timeout
+	}
+}
+
+// IgnoreResources returns a LeakOption that ignores the resources for which ignore returns true,
+// e.g. the ones shared by the tests of a package and removed by another process.
+func IgnoreResources(ignore func(Resource) bool) LeakOption {
+	return func(o *leakOptions) {
+		o.ignores = append(o.ignores, ignore)
+	}
+}
+
+// testRunner is the test binary run by [VerifyNoLeaks], i.e. a [testing.M].
+type testRunner interface {
+	Run() int
+}
+
+// VerifyNoLeaks runs the tests of the package, then fails it if containers, networks or
+// volumes created by the SDK while they ran are still present, and exits. It's meant to be
+// called from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		sdktest.VerifyNoLeaks(m)
+//	}
+//
+// The resources are listed by the SDK labels, and compared with the ones present before the
+// tests ran, so the resources of other processes created meanwhile are reported too. The check
+// is skipped if the tests failed, or if the Docker daemon is not reachable before they run.
+func VerifyNoLeaks(m *testing.M, opts ...LeakOption) {
+	os.Exit(verifyNoLeaks(m, os.Stderr, opts...))
+}
+
+// verifyNoLeaks runs the tests, checking the leaked resources, and returns the exit code,
+// reporting the leaks to w.
+func verifyNoLeaks(m testRunner, w io.Writer, opts ...LeakOption) int {
+	ctx := context.Background()
+
+	checker, err := newLeakChecker(ctx, opts...)
+	if err != nil {
+		fmt.Fprintf(w, "sdktest: skipping the leak check: %v\n", err)
+		return m.Run()
+	}
+	defer checker.close()
+
+	code := m.Run()
+	if code != 0 {
+		return code
+	}
+
+	if err := checker.verify(ctx); err != nil {
+		fmt.Fprintf(w, "sdktest: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// NoLeaks fails the test if containers, networks or volumes created by the SDK during the test
+// are still present when it ends. It must be called at the beginning of the test, before the
+// cleanup functions of its resources are registered, so the check runs after them.
+//
+// The resources are compared with the ones present when NoLeaks is called, so it must not
+// be used by parallel tests, as the resources of the other tests would be reported.
+func NoLeaks(tb testing.TB, opts ...LeakOption) {
+	tb.Helper()
+
+	ctx := context.Background()
+
+	checker, err := newLeakChecker(ctx, opts...)
+	if err != nil {
+		tb.Fatalf("sdktest: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		defer checker.close()
+
+		if err := checker.verify(ctx); err != nil {
+			tb.Errorf("sdktest: %v", err)
+		}
+	})
+}
+
+// leakChecker compares the resources created by the SDK with a snapshot.
+type leakChecker struct {
+	options leakOptions
+
+	// ownClient is true if the client was created by the checker, which closes it.
+	ownClient bool
+
+	// snapshot the kinds and IDs of the resources present when the checker was created.
+	snapshot map[string]bool
+}
+
+// newLeakChecker returns a checker, taking a snapshot of the resources created by the SDK.
+func newLeakChecker(ctx context.Context, opts ...LeakOption) (*leakChecker, error) {
+	c := &leakChecker{
+		options: leakOptions{timeout: defaultLeakTimeout},
+	}
+	for _, opt := range opts {
+		opt(&c.options)
+	}
+
+	if c.options.client == nil {
+		cli, err := client.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("new client: %w", err)
+		}
+		c.options.client = cli
+		c.ownClient = true
+	}
+
+	resources, err := ListResources(ctx, c.options.client)
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("snapshot resources: %w", err)
+	}
+
+	c.snapshot = make(map[string]bool, len(resources))
+	for _, r := range resources {
+		c.snapshot[resourceKey(r)] = true
+	}
+
+	return c, nil
+}
+
+// leaks returns the resources created since the snapshot, which are not ignored.
+func (c *leakChecker) leaks(ctx context.Context) ([]Resource, error) {
+	resources, err := ListResources(ctx, c.options.client)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(resources, func(r Resource) bool {
+		if c.snapshot[resourceKey(r)] {
+			return true
+		}
+		return slices.ContainsFunc(c.options.ignores, func(ignore func(Resource) bool) bool {
+			return ignore(r)
+		})
+	}), nil
+}
+
+// verify returns a [*LeakError] if resources created since the snapshot are still present
+// once the timeout elapsed.
+func (c *leakChecker) verify(ctx context.Context) error {
+	deadline := time.Now().Add(c.options.timeout)
+
+	for {
+		leaks, err := c.leaks(ctx)
+		if err != nil {
+			return fmt.Errorf("list resources: %w", err)
+		}
+		if len(leaks) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return &LeakError{Resources: leaks}
+		}
+
+		time.Sleep(leakPollInterval)
+	}
+}
+
+// close closes the client if it was created by the checker.
+func (c *leakChecker) close() {
+	if c.ownClient {
+		_ = c.options.client.Close()
+	}
+}
+
+// ListResources returns the containers, whatever their state, networks and volumes created
+// by the SDK, i.e. labelled with [client.LabelBase], sorted by kind and creation time.
+func ListResources(ctx context.Context, cli client.SDKClient) ([]Resource, error) {
+	filters := make(dockerclient.Filters).Add("label", client.LabelBase+"=true")
+
+	var resources []Resource
+
+	containers, err := cli.ContainerList(ctx, dockerclient.ContainerListOptions{All: true, Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("container list: %w", err)
+	}
+	for _, c := range containers.Items {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		resources = append(resources, Resource{
+			Kind:    ResourceContainer,
+			ID:      c.ID,
+			Name:    name,
+			Image:   c.Image,
+			Created: time.Unix(c.Created, 0),
+		})
+	}
+
+	networks, err := cli.NetworkList(ctx, dockerclient.NetworkListOptions{Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("network list: %w", err)
+	}
+	for _, n := range networks.Items {
+		resources = append(resources, Resource{
+			Kind:    ResourceNetwork,
+			ID:      n.ID,
+			Name:    n.Name,
+			Created: n.Created,
+		})
+	}
+
+	volumes, err := cli.VolumeList(ctx, dockerclient.VolumeListOptions{Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("volume list: %w", err)
+	}
+	for _, v := range volumes.Items {
+		// the creation time is informative, an invalid one is left out
+		created, _ := time.Parse(time.RFC3339, v.CreatedAt)
+		resources = append(resources, Resource{
+			Kind:    ResourceVolume,
+			ID:      v.Name,
+			Name:    v.Name,
+			Created: created,
+		})
+	}
+
+	slices.SortStableFunc(resources, func(a, b Resource) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			a.Created.Compare(b.Created),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return resources, nil
+}
+
+// resourceKey returns the key identifying a resource in a snapshot.
+func resourceKey(r Resource) string {
+	return string(r.Kind) + "/" + r.ID
+}
+
+// shortID returns the first 12 characters of an ID, as displayed by the Docker CLI.
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
+}
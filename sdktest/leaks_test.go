@@ -0,0 +1,246 @@
+package sdktest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/api/types/volume"
+	dockerclient "github.com/moby/moby/client"
+	"github.com/stretchr/testify/require"
+
+	"github.com/docker/go-sdk/client"
+)
+
+var created = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+// leaksFakeClient lists the resources it holds, checking they are filtered by the SDK label.
+type leaksFakeClient struct {
+	client.SDKClient
+
+	containers []container.Summary
+	networks   []network.Summary
+	volumes    []volume.Volume
+	err        error
+
+	// onList is called when the containers are listed, if set.
+	onList func()
+}
+
+func (f *leaksFakeClient) checkFilters(filters dockerclient.Filters) error {
+	if !filters["label"][client.LabelBase+"=true"] {
+		return fmt.Errorf("unexpected filters: %v", filters)
+	}
+	return f.err
+}
+
+func (f *leaksFakeClient) ContainerList(_ context.Context, options dockerclient.ContainerListOptions) (dockerclient.ContainerListResult, error) {
+	if !options.All {
+		return dockerclient.ContainerListResult{}, errors.New("stopped containers are not listed")
+	}
+	if f.onList != nil {
+		f.onList()
+	}
+	return dockerclient.ContainerListResult{Items: f.containers}, f.checkFilters(options.Filters)
+}
+
+func (f *leaksFakeClient) NetworkList(_ context.Context, options dockerclient.NetworkListOptions) (dockerclient.NetworkListResult, error) {
+	return dockerclient.NetworkListResult{Items: f.networks}, f.checkFilters(options.Filters)
+}
+
+func (f *leaksFakeClient) VolumeList(_ context.Context, options dockerclient.VolumeListOptions) (dockerclient.VolumeListResult, error) {
+	return dockerclient.VolumeListResult{Items: f.volumes}, f.checkFilters(options.Filters)
+}
+
+func (f *leaksFakeClient) addContainer(id string, name string) {
+	f.containers = append(f.containers, container.Summary{
+		ID:      id,
+		Names:   []string{"/" + name},
+		Image:   "nginx:alpine",
+		Created: created.Unix(),
+	})
+}
+
+func (f *leaksFakeClient) addNetwork(id string, name string) {
+	f.networks = append(f.networks, network.Summary{Network: network.Network{ID: id, Name: name, Created: created}})
+}
+
+func (f *leaksFakeClient) addVolume(name string) {
+	f.volumes = append(f.volumes, volume.Volume{Name: name, CreatedAt: created.Format(time.RFC3339)})
+}
+
+// leaksRunner runs the tests of a package, calling run.
+type leaksRunner func() int
+
+func (r leaksRunner) Run() int {
+	return r()
+}
+
+func TestListResources(t *testing.T) {
+	fake := &leaksFakeClient{}
+	fake.addVolume("data")
+	fake.addNetwork("0123456789abcdef0123", "test-network")
+	fake.addContainer("fedcba98765432100123", "db")
+	fake.containers = append(fake.containers, container.Summary{ID: "aaaaaaaaaaaaaaaaaaaa", Image: "alpine", Created: created.Unix() - 60})
+
+	resources, err := ListResources(context.Background(), fake)
+	require.NoError(t, err)
+	require.Equal(t, []Resource{
+		{Kind: ResourceContainer, ID: "aaaaaaaaaaaaaaaaaaaa", Image: "alpine", Created: time.Unix(created.Unix()-60, 0)},
+		{Kind: ResourceContainer, ID: "fedcba98765432100123", Name: "db", Image: "nginx:alpine", Created: time.Unix(created.Unix(), 0)},
+		{Kind: ResourceNetwork, ID: "0123456789abcdef0123", Name: "test-network", Created: created},
+		{Kind: ResourceVolume, ID: "data", Name: "data", Created: created},
+	}, resources)
+
+	t.Run("error", func(t *testing.T) {
+		_, err := ListResources(context.Background(), &leaksFakeClient{err: errors.New("daemon unreachable")})
+		require.EqualError(t, err, "container list: daemon unreachable")
+	})
+}
+
+func TestLeakError(t *testing.T) {
+	err := &LeakError{Resources: []Resource{
+		{Kind: ResourceContainer, ID: "fedcba98765432100123", Name: "db", Image: "nginx:alpine", Created: created},
+		{Kind: ResourceVolume, ID: "data", Name: "data"},
+	}}
+
+	require.EqualError(t, err, "found 2 leaked Docker resources:\n"+
+		"  - container fedcba987654 (db) image=nginx:alpine created=2026-10-19T09:30:00Z\n"+
+		"  - volume data")
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	t.Run("no-leaks", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+		fake.addNetwork("0123456789abcdef0123", "existing")
+
+		var out bytes.Buffer
+		code := verifyNoLeaks(leaksRunner(func() int {
+			fake.addContainer("fedcba98765432100123", "db")
+			fake.containers = nil
+			return 0
+		}), &out, WithClient(fake), WithTimeout(0))
+
+		require.Equal(t, 0, code)
+		require.Empty(t, out.String())
+	})
+
+	t.Run("leaks", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+		fake.addNetwork("0123456789abcdef0123", "existing")
+
+		var out bytes.Buffer
+		code := verifyNoLeaks(leaksRunner(func() int {
+			fake.addContainer("fedcba98765432100123", "db")
+			fake.addVolume("data")
+			return 0
+		}), &out, WithClient(fake), WithTimeout(0))
+
+		require.Equal(t, 1, code)
+		require.Contains(t, out.String(), "sdktest: found 2 leaked Docker resources:")
+		require.Contains(t, out.String(), "container fedcba987654 (db) image=nginx:alpine")
+		require.Contains(t, out.String(), "volume data")
+		require.NotContains(t, out.String(), "existing")
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+
+		var out bytes.Buffer
+		code := verifyNoLeaks(leaksRunner(func() int {
+			fake.addVolume("shared-cache")
+			return 0
+		}), &out, WithClient(fake), WithTimeout(0), IgnoreResources(func(r Resource) bool {
+			return r.Kind == ResourceVolume && r.Name == "shared-cache"
+		}))
+
+		require.Equal(t, 0, code)
+		require.Empty(t, out.String())
+	})
+
+	t.Run("failed-tests", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+
+		var out bytes.Buffer
+		code := verifyNoLeaks(leaksRunner(func() int {
+			fake.addVolume("data")
+			return 2
+		}), &out, WithClient(fake), WithTimeout(0))
+
+		require.Equal(t, 2, code)
+		require.Empty(t, out.String())
+	})
+
+	t.Run("unreachable-daemon", func(t *testing.T) {
+		fake := &leaksFakeClient{err: errors.New("daemon unreachable")}
+
+		var ran bool
+		var out bytes.Buffer
+		code := verifyNoLeaks(leaksRunner(func() int {
+			ran = true
+			return 0
+		}), &out, WithClient(fake))
+
+		require.True(t, ran)
+		require.Equal(t, 0, code)
+		require.Equal(t, "sdktest: skipping the leak check: snapshot resources: container list: daemon unreachable\n", out.String())
+	})
+}
+
+// leaksTB is a test recording its cleanups and errors.
+type leaksTB struct {
+	testing.TB
+
+	cleanups []func()
+	errors   []string
+}
+
+func (tb *leaksTB) Helper()          {}
+func (tb *leaksTB) Cleanup(f func()) { tb.cleanups = append(tb.cleanups, f) }
+func (tb *leaksTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNoLeaks(t *testing.T) {
+	t.Run("leaks", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+		fake.addContainer("0123456789abcdef0123", "existing")
+
+		tb := &leaksTB{TB: t}
+		NoLeaks(tb, WithClient(fake), WithTimeout(0))
+		require.Len(t, tb.cleanups, 1)
+
+		fake.addNetwork("fedcba98765432100123", "test-network")
+		tb.cleanups[0]()
+
+		require.Len(t, tb.errors, 1)
+		require.Contains(t, tb.errors[0], "network fedcba987654 (test-network)")
+		require.NotContains(t, tb.errors[0], "existing")
+	})
+
+	t.Run("removed-within-timeout", func(t *testing.T) {
+		fake := &leaksFakeClient{}
+
+		tb := &leaksTB{TB: t}
+		NoLeaks(tb, WithClient(fake), WithTimeout(time.Minute))
+
+		fake.addContainer("fedcba98765432100123", "auto-removed")
+		var lists int
+		fake.onList = func() {
+			// removed by the daemon after the first check
+			if lists++; lists > 1 {
+				fake.containers = nil
+			}
+		}
+		tb.cleanups[0]()
+
+		require.Equal(t, 2, lists)
+
+		require.Empty(t, tb.errors)
+	})
+}
@@ -0,0 +1,10 @@
+package sdktest
+
+const (
+	version = "0.1.0-alpha001"
+)
+
+// Version returns the version of the sdktest package.
+func Version() string {
+	return version
+}